@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/achilleas-k/gg13/internal/config"
+	"github.com/achilleas-k/gg13/internal/keymap"
+	"github.com/spf13/cobra"
+)
+
+func mkkeymapcmd() *cobra.Command {
+	keymapCmd := &cobra.Command{
+		Use:   "keymap",
+		Short: "Show what the G13 keys are bound to",
+	}
+
+	renderCmd := &cobra.Command{
+		Use:                   "render [--format png|svg] <config> <output>",
+		Short:                 "Draw the key layout with the bindings of a config",
+		Long:                  "Draw the key layout with the bindings of a config as a PNG image or an SVG drawing. The format is taken from the extension of the output file unless it's given.",
+		Args:                  cobra.ExactArgs(2),
+		RunE:                  keymapRender,
+		DisableFlagsInUseLine: true,
+	}
+	renderCmd.Flags().String("format", "", "format of the output: png or svg")
+
+	keymapCmd.AddCommand(renderCmd)
+	return keymapCmd
+}
+
+func keymapRender(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	cfgPath, outPath := args[0], args[1]
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(outPath), "."))
+	}
+	if format != "png" && format != "svg" {
+		return fmt.Errorf("unknown output format %q: must be png or svg", format)
+	}
+
+	g13cfg, err := config.NewFromFile(cfgPath)
+	if err != nil {
+		return err
+	}
+	labels := g13cfg.GetKeyLabels()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed creating output file: %w", err)
+	}
+	if format == "svg" {
+		err = keymap.WriteSVG(out, labels)
+	} else {
+		err = png.Encode(out, keymap.Render(labels))
+	}
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("failed writing output file: %w", err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"strconv"
+
+	"github.com/achilleas-k/gg13/internal/config"
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdpage"
+	"github.com/achilleas-k/gg13/internal/lcdpreview"
+	"github.com/achilleas-k/gg13/internal/mpris"
+	"github.com/spf13/cobra"
+)
+
+func mklcdcmd() *cobra.Command {
+	lcdCmd := &cobra.Command{
+		Use:   "lcd",
+		Short: "Show what the LCD displays without the device",
+	}
+
+	previewCmd := &cobra.Command{
+		Use:                   "preview [--page <page>] [--style halfblock|braille] <config>",
+		Short:                 "Draw the LCD content of a config in the terminal",
+		Args:                  cobra.ExactArgs(1),
+		RunE:                  lcdPreview,
+		DisableFlagsInUseLine: true,
+	}
+	previewCmd.Flags().String("page", "", "page to draw, by number (starting at 1) or name (default the first page)")
+	previewCmd.Flags().String("style", "halfblock", "characters to draw with: halfblock or braille")
+
+	exportCmd := &cobra.Command{
+		Use:                   "export [--page <page>] [--scale <n>] <config> <output.png>",
+		Short:                 "Write the LCD content of a config to a PNG image",
+		Args:                  cobra.ExactArgs(2),
+		RunE:                  lcdExport,
+		DisableFlagsInUseLine: true,
+	}
+	exportCmd.Flags().String("page", "", "page to export, by number (starting at 1) or name (default the first page)")
+	exportCmd.Flags().Int("scale", 1, "size of each LCD pixel in the image, from 1 to 32")
+
+	lcdCmd.AddCommand(previewCmd, exportCmd)
+	return lcdCmd
+}
+
+// lcdContent returns the image that the LCD shows for the selected page of
+// the config at cfgPath, as decoded from the data sent to the device. Pages
+// that change over time are shown as they are when they start.
+func lcdContent(cfgPath, pageName string) (image.Image, error) {
+	g13cfg, err := config.NewFromFile(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	var media *mpris.Client
+	if g13cfg.UsesMedia() {
+		settings := g13cfg.GetMedia()
+		media = mpris.NewClient(settings.Address, settings.Player)
+		defer media.Close()
+	}
+	pages, err := g13cfg.GetPages(media)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.Image(blankImage())
+	if len(pages) > 0 {
+		page, err := selectPage(pages, pageName)
+		if err != nil {
+			return nil, err
+		}
+		stop := make(chan struct{})
+		if frame, ok := <-page.Play(stop); ok {
+			img = frame
+		}
+		close(stop)
+	} else if pageName != "" {
+		return nil, fmt.Errorf("page %q not found: no pages configured", pageName)
+	}
+	return device.LCDPreview(img), nil
+}
+
+// selectPage returns the page with the given number, starting at 1, or name.
+// An empty name selects the first page.
+func selectPage(pages []lcdpage.Page, name string) (lcdpage.Page, error) {
+	if name == "" {
+		return pages[0], nil
+	}
+	for _, page := range pages {
+		if page.Name() == name {
+			return page, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(pages) {
+		return pages[n-1], nil
+	}
+	return nil, fmt.Errorf("page %q not found: must be a page name or a number between 1 and %d", name, len(pages))
+}
+
+// blankImage returns a white image the size of the LCD, which turns all its
+// pixels off.
+func blankImage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return img
+}
+
+func lcdPreview(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	pageName, _ := cmd.Flags().GetString("page")
+	styleName, _ := cmd.Flags().GetString("style")
+	style, err := lcdpreview.ParseStyle(styleName)
+	if err != nil {
+		return err
+	}
+
+	img, err := lcdContent(args[0], pageName)
+	if err != nil {
+		return err
+	}
+	fmt.Print(lcdpreview.Text(img, style))
+	return nil
+}
+
+func lcdExport(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	pageName, _ := cmd.Flags().GetString("page")
+	scale, _ := cmd.Flags().GetInt("scale")
+	// checked before the output file is created, so it isn't left empty
+	if err := lcdpreview.CheckScale(scale); err != nil {
+		return err
+	}
+
+	img, err := lcdContent(args[0], pageName)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(args[1])
+	if err != nil {
+		return fmt.Errorf("failed creating image file: %w", err)
+	}
+	if err := lcdpreview.WritePNG(out, img, scale); err != nil {
+		out.Close()
+		return fmt.Errorf("failed writing image file: %w", err)
+	}
+	return out.Close()
+}
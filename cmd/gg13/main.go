@@ -1,18 +1,209 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"image"
+	"image/draw"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/achilleas-k/gg13/internal/backlight"
 	"github.com/achilleas-k/gg13/internal/config"
 	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/hostleds"
 	"github.com/achilleas-k/gg13/internal/joystick"
 	"github.com/achilleas-k/gg13/internal/keyboard"
+	"github.com/achilleas-k/gg13/internal/lcdcompositor"
+	"github.com/achilleas-k/gg13/internal/lcdpage"
+	"github.com/achilleas-k/gg13/internal/lcdsocket"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/achilleas-k/gg13/internal/menu"
+	"github.com/achilleas-k/gg13/internal/mouse"
+	"github.com/achilleas-k/gg13/internal/mpris"
+	"github.com/achilleas-k/gg13/internal/notify"
+	"github.com/achilleas-k/gg13/internal/radial"
+	"github.com/achilleas-k/gg13/internal/stick"
+	"github.com/achilleas-k/gg13/internal/sysmon"
+	"github.com/achilleas-k/gg13/internal/timers"
 	"github.com/spf13/cobra"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
+// Interval for updating outputs that depend on time rather than input
+// changes, like scrolling. The device only reports input when its state
+// changes, so a stick held in place would otherwise produce no output.
+const tickInterval = 20 * time.Millisecond
+
+// How long notices, like stick mode changes, are shown on the LCD.
+const noticeDuration = 1500 * time.Millisecond
+
+// Number of media actions waiting for the media player before further ones
+// are dropped.
+const mediaQueueSize = 8
+
+// Shortest time between two writes to the LCD, which caps its frame rate at
+// 30 frames per second.
+const lcdFrameInterval = time.Second / 30
+
+// Layers of the LCD, from the bottom to the top.
+const (
+	layerPage         = "page"
+	layerPushed       = "pushed"
+	layerTimers       = "timers"
+	layerHostLEDs     = "host-leds"
+	layerNotification = "notification"
+	layerNotice       = "notice"
+	layerRadial       = "radial"
+	layerMenu         = "menu"
+)
+
+var layerOrder = map[string]int{
+	layerPage:         0,
+	layerPushed:       1,
+	layerTimers:       2,
+	layerHostLEDs:     3,
+	layerNotification: 4,
+	layerNotice:       5,
+	layerRadial:       6,
+	layerMenu:         7,
+}
+
+// virtualDevices holds the virtual uinput devices that G13 input is
+// translated to.
+type virtualDevices struct {
+	keyboard keyboard.Keyboard
+	joystick joystick.Joystick
+	mouse    mouse.Mouse
+
+	// only created if the stick can be in absolute mode
+	absolute mouse.AbsolutePointer
+}
+
+func (vdevs *virtualDevices) Close() {
+	if vdevs.keyboard != nil {
+		if err := vdevs.keyboard.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing keyboard: %s\n", err)
+		}
+	}
+	if vdevs.joystick != nil {
+		if err := vdevs.joystick.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing joystick: %s\n", err)
+		}
+	}
+	if vdevs.mouse != nil {
+		if err := vdevs.mouse.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing mouse: %s\n", err)
+		}
+	}
+	if vdevs.absolute != nil {
+		if err := vdevs.absolute.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing absolute pointer: %s\n", err)
+		}
+	}
+}
+
+// readResult is a single read from the device.
+type readResult struct {
+	input uint64
+	err   error
+}
+
+// startReader reads input from the device in a goroutine and sends it to
+// the returned channel, until stop is closed.
+func startReader(dev device.Device, stop <-chan struct{}) <-chan readResult {
+	results := make(chan readResult)
+	go func() {
+		for {
+			input, err := dev.ReadInput()
+			select {
+			case results <- readResult{input: input, err: err}:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				// wait a bit before continuing to try to read
+				time.Sleep(500 * time.Millisecond)
+			}
+		}
+	}()
+	return results
+}
+
+// startMediaWorker runs media actions with a media player in a goroutine, so
+// that a slow player doesn't hold up input, and sends the errors of the ones
+// that fail to the returned channel until stop is closed. The client is closed
+// when the worker stops.
+func startMediaWorker(media *mpris.Client, actions <-chan config.Action, stop <-chan struct{}) <-chan error {
+	errs := make(chan error)
+	go func() {
+		defer func() {
+			if err := media.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error closing media player connection: %s\n", err)
+			}
+		}()
+		for {
+			var action config.Action
+			select {
+			case action = <-actions:
+			case <-stop:
+				return
+			}
+			err := runMediaAction(media, action)
+			if err == nil {
+				continue
+			}
+			select {
+			case errs <- err:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+// startMetricReader reads the statistic that the backlight follows in a
+// goroutine, once per interval, and sends its values to the returned channel
+// until stop is closed. A statistic that can't be read is reported once, until
+// it can be read again.
+func startMetricReader(metric config.BacklightMetric, stop <-chan struct{}) <-chan float64 {
+	values := make(chan float64)
+	go func() {
+		sampler := sysmon.NewSampler(metric.Root)
+		ticker := time.NewTicker(metric.Interval)
+		defer ticker.Stop()
+		missing := false
+		for {
+			sample, err := sampler.Sample(time.Now())
+			value, ok := sample.Value(metric.Metric, metric.Source)
+			if !ok && !missing {
+				fmt.Fprintf(os.Stderr, "error reading backlight metric %s: not available (%v)\n", metric.Metric, err)
+			}
+			missing = !ok
+			if ok {
+				select {
+				case values <- value:
+				case <-stop:
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return values
+}
+
 func mkcmd() *cobra.Command {
 	rootCmd := cobra.Command{
 		Use:                   "g13 <config>",
@@ -22,57 +213,876 @@ func mkcmd() *cobra.Command {
 		RunE:                  g13,
 		DisableFlagsInUseLine: true, // don't put [flags] at the end of the Use line
 	}
+	rootCmd.AddCommand(mklcdcmd(), mkkeymapcmd())
 
 	return &rootCmd
 }
 
-func setCleanupHandler(cleanup func()) {
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt)
-	go func() {
-		for sig := range signalChan {
-			if sig == os.Interrupt {
-				fmt.Println("Stopping...")
-				cleanup()
-				break
+// driver holds the state of a running driver: the device, the virtual
+// devices its input is translated to, and any state carried between inputs.
+type driver struct {
+	cfg     *config.G13Config
+	cfgPath string
+	dev     device.Device
+	vdevs   *virtualDevices
+
+	radial *radial.Tracker
+	filter *stick.Filter
+
+	// last input read from the device, before and after filtering
+	rawInput uint64
+	input    uint64
+
+	// layers of content drawn on the LCD
+	lcd *lcdcompositor.Compositor
+
+	// pages of the LCD, the images of the page being played (nil if none is
+	// playing), and the channel that stops playback when closed
+	pager    *lcdpage.Pager
+	frames   <-chan image.Image
+	stopPage chan struct{}
+
+	// content pushed to the LCD by other programs, and the server receiving
+	// it (nil if not enabled)
+	pushed   lcdsocket.Queue
+	lcdInput *lcdsocket.Server
+
+	// listener of desktop notifications (nil if not enabled or stopped)
+	notifier *notify.Listener
+
+	// listener of the lock LEDs of the host's keyboards (nil if not enabled
+	// or stopped)
+	hostLEDs *hostleds.Listener
+
+	// media player controlled by media actions and shown by media widgets
+	// (nil if the config doesn't use it). Actions are run in a goroutine from
+	// the queue of actions, which sends the errors of the actions that failed,
+	// until stopMedia is closed.
+	media        *mpris.Client
+	mediaActions chan<- config.Action
+	mediaErrors  <-chan error
+	stopMedia    chan struct{}
+
+	// stopwatches, countdowns, and cooldowns drawn over the page
+	timers *timers.Set
+
+	// effects of the backlight, like breathing and flashes for notifications
+	// and events
+	lights *backlight.Scheduler
+
+	// backlight colour following a statistic or pushed values (nil if the
+	// backlight doesn't follow one), and the values read for it (nil if
+	// they're pushed) until stopMetric is closed
+	gauge        *backlight.Gauge
+	metricValues <-chan float64
+	stopMetric   chan struct{}
+
+	// on-device menu (nil when closed)
+	menu *menu.Navigator
+
+	// input isn't translated to the virtual devices while paused
+	paused bool
+
+	// config of the profile to switch to, and its path (nil if not switching)
+	nextCfg     *config.G13Config
+	nextCfgPath string
+}
+
+func (d *driver) Close() {
+	d.unload()
+	d.dev.Close()
+	d.vdevs.Close()
+}
+
+// configure opens the virtual devices for a config, replacing the ones whose
+// settings differ from the previous config (nil to open all of them). Devices
+// with unchanged settings stay open, so switching profiles doesn't unplug them
+// from the programs using them.
+func (vdevs *virtualDevices) configure(prev, g13cfg *config.G13Config) error {
+	var err error
+	if prev == nil || prev.GetKeyboardName() != g13cfg.GetKeyboardName() {
+		if vdevs.keyboard != nil {
+			if err := vdevs.keyboard.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error closing keyboard: %s\n", err)
 			}
 		}
-		os.Exit(0)
-	}()
+		vdevs.keyboard, err = keyboard.New(g13cfg.GetKeyboardName())
+		if err != nil {
+			return fmt.Errorf("virtual keyboard initialisation failed: %w", err)
+		}
+	}
+
+	if prev == nil || !reflect.DeepEqual(prev.GetJoystickSettings(), g13cfg.GetJoystickSettings()) {
+		if vdevs.joystick != nil {
+			if err := vdevs.joystick.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error closing joystick: %s\n", err)
+			}
+		}
+		vdevs.joystick, err = joystick.New(g13cfg.GetJoystickSettings())
+		if err != nil {
+			return fmt.Errorf("virtual joystick initialisation failed: %w", err)
+		}
+	}
+
+	if prev == nil || prev.GetMouseName() != g13cfg.GetMouseName() {
+		if vdevs.mouse != nil {
+			if err := vdevs.mouse.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error closing mouse: %s\n", err)
+			}
+		}
+		vdevs.mouse, err = mouse.New(g13cfg.GetMouseName())
+		if err != nil {
+			return fmt.Errorf("virtual mouse initialisation failed: %w", err)
+		}
+	}
+
+	if !g13cfg.HasAbsoluteMode() && vdevs.absolute != nil {
+		if err := vdevs.absolute.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing absolute pointer: %s\n", err)
+		}
+		vdevs.absolute = nil
+	}
+	if g13cfg.HasAbsoluteMode() && vdevs.absolute == nil {
+		vdevs.absolute, err = mouse.NewAbsolute("g13-vabs")
+		if err != nil {
+			return fmt.Errorf("virtual absolute pointer initialisation failed: %w", err)
+		}
+	}
+	return nil
 }
 
-func initialise(g13cfg *config.G13Config) (device.Device, keyboard.Keyboard, joystick.Joystick, error) {
+// initialise opens the device and virtual devices for a config and loads it.
+func initialise(cfgPath string, g13cfg *config.G13Config) (*driver, error) {
 	dev, err := device.New()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("device initialisation failed: %w", err)
+		return nil, fmt.Errorf("device initialisation failed: %w", err)
 	}
-	setCleanupHandler(dev.Close)
 
-	vkb, err := keyboard.New("g13-vkb")
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("virtual keyboard initialisation failed: %w", err)
+	vdevs := &virtualDevices{}
+	if err := vdevs.configure(nil, g13cfg); err != nil {
+		dev.Close()
+		vdevs.Close()
+		return nil, err
+	}
+
+	d := &driver{dev: dev, vdevs: vdevs}
+	if err := d.load(cfgPath, g13cfg, nil); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// switchConfig replaces the running config with the one of another profile,
+// keeping the device and virtual devices open. Only the virtual devices whose
+// settings change are replaced. The backlight fades from the colour of the
+// previous profile if the new config sets a fade. If the profile fails to
+// start, the previous config is loaded again and the error is shown on the
+// LCD; an error is only returned if that fails too.
+func (d *driver) switchConfig(cfgPath string, g13cfg *config.G13Config) error {
+	prevCfg, prevCfgPath := d.cfg, d.cfgPath
+	prevBacklight := d.lights.Colour(time.Now())
+	err := d.reload(prevCfg, cfgPath, g13cfg, &prevBacklight)
+	if err == nil {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "error switching profile: %s\n", err)
+	if err := d.reload(g13cfg, prevCfgPath, prevCfg, nil); err != nil {
+		return fmt.Errorf("failed restoring the previous profile: %w", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(cfgPath), filepath.Ext(cfgPath))
+	d.showNotice("Profile error:", name)
+	return nil
+}
+
+// reload replaces the loaded config, current, with another one, keeping the
+// device and virtual devices open.
+func (d *driver) reload(current *config.G13Config, cfgPath string, g13cfg *config.G13Config, fadeFrom *[3]uint8) error {
+	d.unload()
+	if err := d.vdevs.configure(current, g13cfg); err != nil {
+		return err
+	}
+	// start from a clean state, except for the input: keys that are still
+	// held, like the one that switched profiles, aren't pressed again
+	*d = driver{dev: d.dev, vdevs: d.vdevs, rawInput: d.rawInput, input: d.input}
+	return d.load(cfgPath, g13cfg, fadeFrom)
+}
+
+// load sets up the state of a config: the backlight, the LCD, and the
+// listeners it enables. When switching profiles, the backlight fades from the
+// colour of the previous profile (fadeFrom, nil if not switching) if the
+// config sets a fade. Everything it starts is stopped by unload.
+func (d *driver) load(cfgPath string, g13cfg *config.G13Config, fadeFrom *[3]uint8) error {
+	now := time.Now()
+	effects := g13cfg.GetBacklightEffects()
+	base := effects.Effect
+	var gauge *backlight.Gauge
+	if effects.Metric != nil {
+		gauge = backlight.NewGauge(effects.Metric.Gradient)
+		base = gauge
+	}
+	lights := backlight.NewScheduler(base, effects.MinInterval, now)
+	if fadeFrom != nil && effects.Fade > 0 {
+		lights.Play(backlight.Fade{From: *fadeFrom, Duration: effects.Fade}, now)
+	}
+	colour, _ := lights.Next(now)
+	colour = backlight.Scale(colour, g13cfg.GetBrightness())
+	if err := d.dev.SetBacklightColour(colour[0], colour[1], colour[2]); err != nil {
+		return err
 	}
 
-	vjs, err := joystick.New("g13-vjs")
+	d.cfg = g13cfg
+	d.cfgPath = cfgPath
+	d.radial = radial.NewTracker(g13cfg.GetRadialMenus())
+	d.filter = stick.NewFilter(g13cfg.GetStickFilter())
+	d.lcd = lcdcompositor.New(device.LCDWidth, device.LCDHeight, lcdFrameInterval)
+	d.lights = lights
+	d.gauge = gauge
+	d.timers = timers.NewSet(g13cfg.GetTimers().Timers)
+
+	// clear what the previous config left on the LCD
+	d.lcd.Invalidate()
+
+	// one client, shared by media actions and widgets, is connected to the
+	// bus when the config uses the media player
+	if g13cfg.UsesMedia() {
+		media := g13cfg.GetMedia()
+		d.media = mpris.NewClient(media.Address, media.Player)
+		mediaActions := make(chan config.Action, mediaQueueSize)
+		d.mediaActions = mediaActions
+		d.stopMedia = make(chan struct{})
+		d.mediaErrors = startMediaWorker(d.media, mediaActions, d.stopMedia)
+	}
+
+	pages, err := g13cfg.GetPages(d.media)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("virtual joystick initialisation failed: %w", err)
+		return err
 	}
+	d.pager = lcdpage.NewPager(pages)
+	d.playPage()
 
-	backlight := g13cfg.GetBacklight()
-	if err := dev.SetBacklightColour(backlight[0], backlight[1], backlight[2]); err != nil {
-		return nil, nil, nil, err
+	if metric := effects.Metric; metric != nil && !metric.Pushed {
+		d.stopMetric = make(chan struct{})
+		d.metricValues = startMetricReader(*metric, d.stopMetric)
 	}
 
-	if g13cfg.GetImagePath() != "" {
-		lcdImg, err := g13cfg.GetImage()
+	if socket, fifo := g13cfg.GetLCDInput(); socket != "" || fifo != "" {
+		d.lcdInput = lcdsocket.NewServer()
+		if socket != "" {
+			err = d.lcdInput.ListenSocket(socket)
+		}
+		if err == nil && fifo != "" {
+			err = d.lcdInput.ListenFIFO(fifo)
+		}
 		if err != nil {
-			return nil, nil, nil, err
+			return fmt.Errorf("LCD input initialisation failed: %w", err)
 		}
-		if err := dev.SetLCD(lcdImg); err != nil {
-			return nil, nil, nil, err
+	}
+
+	if notifications, ok := g13cfg.GetNotifications(); ok {
+		d.notifier, err = notify.Listen(notifications.Address)
+		if err != nil {
+			return fmt.Errorf("notification listener initialisation failed: %w", err)
+		}
+	}
+
+	if leds, ok := g13cfg.GetHostLEDs(); ok {
+		d.hostLEDs, err = hostleds.Listen(leds.Devices)
+		if err != nil {
+			return fmt.Errorf("host LED listener initialisation failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// unload stops what load started for the running config, and turns off the
+// mode LEDs that it may have lit.
+func (d *driver) unload() {
+	d.stopPlayback()
+	if d.stopMetric != nil {
+		close(d.stopMetric)
+		d.stopMetric = nil
+	}
+	if d.lcdInput != nil {
+		if err := d.lcdInput.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing LCD input: %s\n", err)
+		}
+		d.lcdInput = nil
+	}
+	if d.notifier != nil {
+		if err := d.notifier.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing notification listener: %s\n", err)
+		}
+		d.notifier = nil
+	}
+	if d.hostLEDs != nil {
+		if err := d.hostLEDs.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing host LED listener: %s\n", err)
+		}
+		d.hostLEDs = nil
+		if err := d.dev.SetModeLEDs(0); err != nil {
+			fmt.Fprintf(os.Stderr, "error setting MR LED: %s\n", err)
+		}
+	}
+	if d.media != nil {
+		close(d.stopMedia)
+		// ends a call in progress, so the worker stops without waiting for
+		// the player
+		if err := d.media.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing media player connection: %s\n", err)
+		}
+		d.media = nil
+		d.mediaActions = nil
+		d.mediaErrors = nil
+	}
+}
+
+// playPage starts playing the current page of the LCD, stopping the page that
+// was playing before.
+func (d *driver) playPage() {
+	d.stopPlayback()
+	page := d.pager.Current()
+	if page == nil {
+		return
+	}
+	d.stopPage = make(chan struct{})
+	d.frames = page.Play(d.stopPage)
+}
+
+// stopPlayback stops playing the current page of the LCD.
+func (d *driver) stopPlayback() {
+	if d.stopPage != nil {
+		close(d.stopPage)
+		d.stopPage = nil
+	}
+	d.frames = nil
+}
+
+// handleFrame shows the latest image of the current page on the LCD, under
+// anything that is drawn over it.
+func (d *driver) handleFrame(frame image.Image) {
+	d.setLayer(layerPage, frame, time.Time{})
+	d.updateLCD(time.Now())
+}
+
+// setLayer shows an image in a layer of the LCD until the given time, or
+// until removed if it's zero. The LCD is drawn on the next call to updateLCD.
+func (d *driver) setLayer(name string, img image.Image, until time.Time) {
+	d.lcd.Set(name, layerOrder[name], img, until)
+}
+
+// updateLCD draws the layers of the LCD if they changed since they were last
+// drawn and the frame rate allows it. Changes that are held back are drawn on
+// a later tick.
+func (d *driver) updateLCD(now time.Time) {
+	frame, ok := d.lcd.Next(now)
+	if !ok {
+		return
+	}
+	if err := d.dev.SetLCD(frame); err != nil {
+		fmt.Fprintf(os.Stderr, "error drawing LCD: %s\n", err)
+		// try again on the next update
+		d.lcd.Invalidate()
+	}
+}
+
+// lcdInputResults returns the channel of content pushed to the LCD by other
+// programs (nil if not enabled).
+func (d *driver) lcdInputResults() <-chan lcdsocket.Result {
+	if d.lcdInput == nil {
+		return nil
+	}
+	return d.lcdInput.Results()
+}
+
+// handleLCDInput shows content pushed to the LCD by another program, under
+// anything that is drawn over it.
+func (d *driver) handleLCDInput(res lcdsocket.Result, now time.Time) {
+	if res.Err != nil {
+		fmt.Fprintf(os.Stderr, "LCD input error: %s\n", res.Err)
+		return
+	}
+	if value := res.Message.Metric; value != nil {
+		if metric := d.cfg.GetBacklightEffects().Metric; metric != nil && metric.Pushed {
+			d.setMetric(*value, now)
+		}
+		return
+	}
+	d.pushed.Handle(res.Message, now)
+	d.updatePushed()
+	d.updateLCD(now)
+}
+
+// updatePushed shows the current content pushed by other programs over the
+// page, or removes it if there is none.
+func (d *driver) updatePushed() {
+	if pushed := d.pushed.Current(); pushed != nil {
+		d.setLayer(layerPushed, pushed, time.Time{})
+	} else {
+		d.lcd.Remove(layerPushed)
+	}
+}
+
+// notifications returns the channel of desktop notifications (nil if not
+// enabled).
+func (d *driver) notifications() <-chan notify.Notification {
+	if d.notifier == nil {
+		return nil
+	}
+	return d.notifier.Notifications()
+}
+
+// handleNotification shows a desktop notification on the LCD, over the page
+// and pushed content, and flashes the backlight if configured. A closed
+// channel stops the listener.
+func (d *driver) handleNotification(n notify.Notification, ok bool, now time.Time) {
+	if !ok {
+		fmt.Fprintln(os.Stderr, "notification listener stopped: lost connection to the bus")
+		if err := d.notifier.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing notification listener: %s\n", err)
+		}
+		d.notifier = nil
+		return
+	}
+	settings, _ := d.cfg.GetNotifications()
+	if !settings.Filter.Allows(n.App) {
+		return
+	}
+	d.setLayer(layerNotification, n.Render(), now.Add(settings.Timeout))
+	d.updateLCD(now)
+
+	if settings.Flash != nil {
+		d.flashBacklight(backlight.Flash{Colour: *settings.Flash, Duration: settings.FlashDuration}, now)
+	}
+}
+
+// hostLEDStates returns the channel of the lock LED states of the host's
+// keyboards (nil if not enabled or stopped).
+func (d *driver) hostLEDStates() <-chan hostleds.State {
+	if d.hostLEDs == nil {
+		return nil
+	}
+	return d.hostLEDs.States()
+}
+
+// handleHostLEDs shows the lock LEDs of the host's keyboards on the backlight,
+// the MR LED, and the LCD, as configured. A closed channel stops the
+// listener.
+func (d *driver) handleHostLEDs(state hostleds.State, ok bool, now time.Time) {
+	if !ok {
+		fmt.Fprintln(os.Stderr, "host LED listener stopped: lost all keyboards")
+		if err := d.hostLEDs.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing host LED listener: %s\n", err)
+		}
+		d.hostLEDs = nil
+		return
+	}
+	settings, _ := d.cfg.GetHostLEDs()
+
+	if len(settings.Backlight) > 0 {
+		var override backlight.Effect
+		for _, lc := range settings.Backlight {
+			if state.On(lc.LED) {
+				override = backlight.Static(lc.Colour)
+				break
+			}
+		}
+		d.lights.SetOverride(override, now)
+		d.updateBacklight(now)
+	}
+
+	if settings.MR != nil {
+		var leds uint8
+		if state.On(*settings.MR) {
+			leds = device.LEDMR
+		}
+		if err := d.dev.SetModeLEDs(leds); err != nil {
+			fmt.Fprintf(os.Stderr, "error setting MR LED: %s\n", err)
+		}
+	}
+
+	if len(settings.LCD) > 0 {
+		if slices.ContainsFunc(settings.LCD, state.On) {
+			d.setLayer(layerHostLEDs, hostleds.Render(state, settings.LCD), time.Time{})
+		} else {
+			d.lcd.Remove(layerHostLEDs)
+		}
+		d.updateLCD(now)
+	}
+}
+
+// setMetric changes the value of the statistic that the backlight follows.
+func (d *driver) setMetric(value float64, now time.Time) {
+	d.gauge.Set(value)
+	d.updateBacklight(now)
+}
+
+// flashBacklight shows a flash over the backlight effect.
+func (d *driver) flashBacklight(flash backlight.Flash, now time.Time) {
+	d.lights.Play(flash, now)
+	d.updateBacklight(now)
+}
+
+// flashEvent flashes the backlight for an event if the config sets a flash
+// for it.
+func (d *driver) flashEvent(event string) {
+	if flash, ok := d.cfg.GetBacklightEffects().Flashes[event]; ok {
+		d.flashBacklight(flash, time.Now())
+	}
+}
+
+// setBacklight sets the colour of the backlight, replacing the effect and
+// ending any flash.
+func (d *driver) setBacklight(colour [3]uint8) {
+	now := time.Now()
+	d.lights.SetBase(backlight.Static(colour), now)
+	d.updateBacklight(now)
+}
+
+// updateBacklight sends the colour of the backlight effects, scaled by the
+// configured brightness, to the device when it changed, at most once per
+// minimum interval.
+func (d *driver) updateBacklight(now time.Time) {
+	colour, ok := d.lights.Next(now)
+	if !ok {
+		return
+	}
+	colour = backlight.Scale(colour, d.cfg.GetBrightness())
+	if err := d.dev.SetBacklightColour(colour[0], colour[1], colour[2]); err != nil {
+		fmt.Fprintf(os.Stderr, "error setting backlight: %s\n", err)
+	}
+}
+
+// handleRawInput filters the stick position of an input read from the device
+// and handles the result.
+func (d *driver) handleRawInput(raw uint64, now time.Time) {
+	d.rawInput = raw
+	d.handleInput(d.filter.Apply(raw, now))
+	d.updateLCD(now)
+}
+
+// handleInput translates a single (filtered) input to the virtual devices.
+func (d *driver) handleInput(input uint64) {
+	prevInput := d.input
+	d.input = input
+
+	if d.menu != nil {
+		d.handleMenuInput(prevInput, input)
+		return
+	}
+	if d.paused {
+		for _, action := range d.cfg.GetActions(prevInput, input) {
+			if action.Type == config.ActionMenu || action.Type == config.ActionPause {
+				d.runAction(action)
+			}
+		}
+		return
+	}
+
+	if changed, fired := d.radial.Update(input); changed {
+		d.updateRadial(fired)
+	}
+	if menu, _ := d.radial.Active(); menu != nil {
+		// the stick is used for selecting from the menu while it's open
+		input = device.CentreStick(input)
+	} else {
+		if cooldowns := d.cfg.GetCooldowns(prevInput, input); len(cooldowns) > 0 {
+			now := time.Now()
+			for _, cooldown := range cooldowns {
+				d.timers.StartCooldown(cooldown.Name, cooldown.Duration, now)
+			}
+			d.updateTimers(now)
+		}
+		for _, action := range d.cfg.GetActions(prevInput, input) {
+			d.runAction(action)
+		}
+		if d.menu != nil || d.paused || d.nextCfg != nil {
+			// outputs were released by the action
+			return
+		}
+	}
+
+	d.updateOutputs(input)
+}
+
+// updateOutputs sets the state of the virtual devices for an input.
+func (d *driver) updateOutputs(input uint64) {
+	for kbkey, isDown := range d.cfg.GetKeyStates(input) {
+		if isDown {
+			if err := d.vdevs.keyboard.KeyDown(kbkey); err != nil {
+				fmt.Fprintf(os.Stderr, "keyboard error pressing %d: %s\n", kbkey, err)
+			}
+		} else if err := d.vdevs.keyboard.KeyUp(kbkey); err != nil {
+			fmt.Fprintf(os.Stderr, "keyboard error releasing %d: %s\n", kbkey, err)
+		}
+	}
+
+	for button, isDown := range d.cfg.GetButtonStates(input) {
+		if isDown {
+			if err := d.vdevs.joystick.ButtonDown(button); err != nil {
+				fmt.Fprintf(os.Stderr, "joystick error pressing button %d: %s\n", button, err)
+			}
+		} else if err := d.vdevs.joystick.ButtonUp(button); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error releasing button %d: %s\n", button, err)
+		}
+	}
+
+	stickPos := d.cfg.GetStickPosition(input)
+	if stickPos != nil {
+		xOutput, yOutput := stickPos.UinputPosition()
+		if err := d.vdevs.joystick.StickPosition(xOutput, yOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error setting position %f %f", xOutput, yOutput)
+		}
+	}
+
+	if absX, absY, ok := d.cfg.GetAbsolutePosition(input); ok {
+		if err := d.vdevs.absolute.MoveTo(absX, absY); err != nil {
+			fmt.Fprintf(os.Stderr, "absolute pointer error moving to %f %f: %s\n", absX, absY, err)
+		}
+	}
+
+	if hatX, hatY, ok := d.cfg.GetHatPosition(input); ok {
+		if err := d.vdevs.joystick.HatPosition(hatX, hatY); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error setting hat position %d %d: %s\n", hatX, hatY, err)
+		}
+	}
+}
+
+// releaseOutputs returns the outputs of the virtual devices to their neutral
+// state: keys and buttons are released and the stick is centred.
+func (d *driver) releaseOutputs() {
+	neutral := device.CentreStick(0)
+	for kbkey := range d.cfg.GetKeyStates(neutral) {
+		if err := d.vdevs.keyboard.KeyUp(kbkey); err != nil {
+			fmt.Fprintf(os.Stderr, "keyboard error releasing %d: %s\n", kbkey, err)
+		}
+	}
+	for button := range d.cfg.GetButtonStates(neutral) {
+		if err := d.vdevs.joystick.ButtonUp(button); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error releasing button %d: %s\n", button, err)
+		}
+	}
+	switch d.cfg.GetStickMode() {
+	case config.StickModeJoystick:
+		if err := d.vdevs.joystick.StickPosition(0, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error recentering: %s\n", err)
+		}
+	case config.StickModeHat:
+		if err := d.vdevs.joystick.HatPosition(0, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error recentering hat: %s\n", err)
+		}
+	}
+}
+
+// updateRadial redraws the LCD after a radial menu state change and fires the
+// selected item when the menu is closed.
+func (d *driver) updateRadial(fired *radial.Item) {
+	if menu, selected := d.radial.Active(); menu != nil {
+		d.setLayer(layerRadial, menu.Render(selected), time.Time{})
+		return
+	}
+
+	d.lcd.Remove(layerRadial)
+	d.lcd.Remove(layerNotice)
+	if fired == nil {
+		return
+	}
+	for _, kbkey := range fired.Keys {
+		if err := d.vdevs.keyboard.KeyDown(kbkey); err != nil {
+			fmt.Fprintf(os.Stderr, "keyboard error pressing %d: %s\n", kbkey, err)
+		}
+	}
+	for idx := len(fired.Keys) - 1; idx >= 0; idx-- {
+		if err := d.vdevs.keyboard.KeyUp(fired.Keys[idx]); err != nil {
+			fmt.Fprintf(os.Stderr, "keyboard error releasing %d: %s\n", fired.Keys[idx], err)
+		}
+	}
+}
+
+// runAction performs an action bound to a G key.
+func (d *driver) runAction(action config.Action) {
+	switch action.Type {
+	case config.ActionStickMode:
+		d.setStickMode(action.NextStickMode(d.cfg.GetStickMode()))
+	case config.ActionPageNext:
+		if d.pager.Len() > 1 {
+			d.pager.Next()
+			d.playPage()
+			d.flashEvent(config.FlashPage)
+		}
+	case config.ActionPagePrevious:
+		if d.pager.Len() > 1 {
+			d.pager.Previous()
+			d.playPage()
+			d.flashEvent(config.FlashPage)
+		}
+	case config.ActionPageSelect:
+		if page, ok := d.pager.Current().(lcdpage.Selecter); ok {
+			d.stopPlayback()
+			page.Select()
+			d.playPage()
+		}
+	case config.ActionMenu:
+		if d.menu != nil {
+			d.closeMenu()
+		} else {
+			d.openMenu()
+		}
+	case config.ActionPause:
+		d.setPaused(!d.paused)
+	case config.ActionBacklight:
+		d.setBacklight(action.Colour)
+	case config.ActionProfile:
+		d.switchProfile(action.Profile)
+	case config.ActionMediaPlayPause, config.ActionMediaNext, config.ActionMediaPrevious, config.ActionMediaSeek:
+		d.queueMediaAction(action)
+	case config.ActionTimerStart, config.ActionTimerStop, config.ActionTimerToggle, config.ActionTimerReset:
+		d.runTimerAction(action, time.Now())
+	}
+}
+
+// runTimerAction starts, stops, or resets a timer.
+func (d *driver) runTimerAction(action config.Action, now time.Time) {
+	switch action.Type {
+	case config.ActionTimerStart:
+		d.timers.Start(action.Timer, now)
+	case config.ActionTimerStop:
+		d.timers.Stop(action.Timer, now)
+	case config.ActionTimerToggle:
+		d.timers.Toggle(action.Timer, now)
+	case config.ActionTimerReset:
+		d.timers.Reset(action.Timer)
+	}
+	d.updateTimers(now)
+}
+
+// updateTimers ends the countdowns that expired, flashing the backlight if
+// configured, and draws the active timers over the page.
+func (d *driver) updateTimers(now time.Time) {
+	if expired := d.timers.Expire(now); len(expired) > 0 {
+		if settings := d.cfg.GetTimers(); settings.Flash != nil {
+			d.flashBacklight(backlight.Flash{Colour: *settings.Flash, Duration: settings.FlashDuration}, now)
+		}
+	}
+	active := d.timers.Active(now)
+	if len(active) == 0 {
+		d.lcd.Remove(layerTimers)
+		return
+	}
+	d.setLayer(layerTimers, timers.Render(active), time.Time{})
+}
+
+// queueMediaAction queues a media action for the media worker. Actions are
+// dropped while the queue is full, which happens when the player doesn't
+// respond.
+func (d *driver) queueMediaAction(action config.Action) {
+	select {
+	case d.mediaActions <- action:
+	default:
+		fmt.Fprintln(os.Stderr, "media player busy: dropped media action")
+	}
+}
+
+// runMediaAction controls the media player.
+func runMediaAction(media *mpris.Client, action config.Action) error {
+	switch action.Type {
+	case config.ActionMediaPlayPause:
+		return media.PlayPause()
+	case config.ActionMediaNext:
+		return media.Next()
+	case config.ActionMediaPrevious:
+		return media.Previous()
+	case config.ActionMediaSeek:
+		return media.Seek(action.SeekOffset)
+	}
+	return nil
+}
+
+// handleMediaError shows the failure of a media action, like no player
+// running, on the LCD.
+func (d *driver) handleMediaError(err error, now time.Time) {
+	if errors.Is(err, mpris.ErrNoPlayer) {
+		d.showNotice("No media player")
+	} else {
+		fmt.Fprintf(os.Stderr, "media player error: %s\n", err)
+		d.showNotice("Media player error")
+	}
+	d.updateLCD(now)
+}
+
+// setStickMode switches the stick to a new mode, after returning any outputs
+// of the old mode to their neutral state, and shows the new mode on the LCD.
+func (d *driver) setStickMode(mode config.StickMode) {
+	switch d.cfg.GetStickMode() {
+	case config.StickModeJoystick:
+		if err := d.vdevs.joystick.StickPosition(0, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error recentering: %s\n", err)
+		}
+	case config.StickModeHat:
+		if err := d.vdevs.joystick.HatPosition(0, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "joystick error recentering hat: %s\n", err)
+		}
+	case config.StickModeKeys:
+		stickKeys := d.cfg.GetStickKeys()
+		for _, kbkey := range []int{stickKeys.Up, stickKeys.Down, stickKeys.Left, stickKeys.Right} {
+			if kbkey == 0 {
+				continue
+			}
+			if err := d.vdevs.keyboard.KeyUp(kbkey); err != nil {
+				fmt.Fprintf(os.Stderr, "keyboard error releasing %d: %s\n", kbkey, err)
+			}
+		}
+	}
+
+	d.cfg.SetStickMode(mode)
+	d.showNotice("Stick mode:", mode.String())
+	d.flashEvent(config.FlashStickMode)
+	if _, ok := d.pager.Current().(*lcdpage.Keymap); ok {
+		// restart the key binding page to show the new mode
+		d.playPage()
+	}
+}
+
+// showNotice shows a short message on the LCD, over the page and pushed
+// content, for a few seconds.
+func (d *driver) showNotice(lines ...string) {
+	d.setLayer(layerNotice, renderNotice(lines...), time.Now().Add(noticeDuration))
+}
+
+// handleTick updates the outputs that depend on elapsed time, based on the
+// last input read from the device.
+func (d *driver) handleTick(now time.Time, elapsed time.Duration) {
+	if d.cfg.GetStickFilter().Continuous() {
+		// the filtered position keeps changing while the device isn't
+		// reporting anything
+		if filtered := d.filter.Apply(d.rawInput, now); filtered != d.input {
+			d.handleInput(filtered)
+		}
+	}
+
+	if d.pushed.Expire(now) {
+		d.updatePushed()
+	}
+	d.updateTimers(now)
+	d.updateBacklight(now)
+	// draws notices that timed out and changes held back by the frame rate
+	d.updateLCD(now)
+
+	if menu, _ := d.radial.Active(); menu != nil || d.menu != nil || d.paused {
+		return
+	}
+
+	secs := float32(elapsed.Seconds())
+	if hSpeed, vSpeed, ok := d.cfg.GetScrollSpeed(d.input); ok && (hSpeed != 0 || vSpeed != 0) {
+		if err := d.vdevs.mouse.Scroll(hSpeed*secs, vSpeed*secs); err != nil {
+			fmt.Fprintf(os.Stderr, "mouse error scrolling: %s\n", err)
+		}
+	}
+	if xSpeed, ySpeed, ok := d.cfg.GetMouseSpeed(d.input); ok && (xSpeed != 0 || ySpeed != 0) {
+		if err := d.vdevs.mouse.Move(xSpeed*secs, ySpeed*secs); err != nil {
+			fmt.Fprintf(os.Stderr, "mouse error moving: %s\n", err)
 		}
 	}
-	return dev, vkb, vjs, nil
 }
 
 func g13(cmd *cobra.Command, args []string) error {
@@ -87,71 +1097,132 @@ func g13(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	dev, vkb, vjs, err := initialise(g13cfg)
+	d, err := initialise(configPath, g13cfg)
 	if err != nil {
 		return err
 	}
 
+	// stop on Ctrl-C, closing the devices on the way out, once the device is
+	// open: until then, Ctrl-C stops waiting for the device
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
 	defer func() {
-		dev.Close()
-		if err := vkb.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "error closing keyboard during shutdown: %s", err)
-		}
+		d.Close()
 	}()
 
+	stopReader := make(chan struct{})
+	inputs := startReader(d.dev, stopReader)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	lastTick := time.Now()
+
 	fmt.Println("Ready")
 	var consecutiveReadErrors uint8 = 0
 	for {
-		input, err := dev.ReadInput()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "e: %s (%d)\n", err, consecutiveReadErrors)
-			consecutiveReadErrors++
-			// wait a bit before continuing to try to read
-			time.Sleep(500 * time.Millisecond)
-
-			// TODO: shut down if consecutive errors reaches a limit
+		var input uint64
+		var now time.Time
+		select {
+		case <-interrupts:
+			fmt.Println("Stopping...")
+			return nil
+		case now := <-ticker.C:
+			d.handleTick(now, now.Sub(lastTick))
+			lastTick = now
 			continue
-		}
+		case res := <-d.lcdInputResults():
+			d.handleLCDInput(res, time.Now())
+			continue
+		case n, ok := <-d.notifications():
+			d.handleNotification(n, ok, time.Now())
+			continue
+		case state, ok := <-d.hostLEDStates():
+			d.handleHostLEDs(state, ok, time.Now())
+			continue
+		case err := <-d.mediaErrors:
+			d.handleMediaError(err, time.Now())
+			continue
+		case value := <-d.metricValues:
+			d.setMetric(value, time.Now())
+			continue
+		case frame, ok := <-d.frames:
+			if !ok {
+				// page finished playing; its last image stays on the LCD
+				d.frames = nil
+				continue
+			}
+			d.handleFrame(frame)
+			continue
+		case res := <-inputs:
+			if res.err != nil {
+				fmt.Fprintf(os.Stderr, "e: %s (%d)\n", res.err, consecutiveReadErrors)
+				consecutiveReadErrors++
 
-		consecutiveReadErrors = 0
-		for kbkey, isDown := range g13cfg.GetKeyStates(input) {
-			if isDown {
-				if err := vkb.KeyDown(kbkey); err != nil {
-					fmt.Fprintf(os.Stderr, "keyboard error pressing %d: %s\n", kbkey, err)
-				}
-			} else if err := vkb.KeyUp(kbkey); err != nil {
-				fmt.Fprintf(os.Stderr, "keyboard error releasing %d: %s\n", kbkey, err)
+				// TODO: shut down if consecutive errors reaches a limit
+				continue
 			}
+			input = res.input
+			now = time.Now()
 		}
 
-		stickPos := g13cfg.GetStickPosition(input)
-		if stickPos != nil {
-			xOutput, yOutput := stickPos.UinputPosition()
-			if err := vjs.StickPosition(xOutput, yOutput); err != nil {
-				fmt.Fprintf(os.Stderr, "joystick error setting position %f %f", xOutput, yOutput)
+		consecutiveReadErrors = 0
+		d.handleRawInput(input, now)
+
+		if d.nextCfg != nil {
+			fmt.Printf("Switching to profile %s\n", d.nextCfgPath)
+			if err := d.switchConfig(d.nextCfgPath, d.nextCfg); err != nil {
+				return err
 			}
+			// the profile that is running after the switch, which is the
+			// previous one if the new one failed to start
+			configPath, g13cfg = d.cfgPath, d.cfg
+			continue
 		}
 
 		if consecutiveReadErrors > 0 {
 			// device is back after read errors
 			fmt.Println("Reinitialising device")
-			dev.Close()
-			dev = nil
-			if err := vkb.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "error closing vkb: %s\n", err)
-			}
+			close(stopReader)
+			d.Close()
 			// After 3 consecutive read errors, try to reinitialise the device.
 			// This is primarily meant to handle device disconnections.
-			dev, vkb, vjs, err = initialise(g13cfg)
+			signal.Stop(interrupts)
+			d, err = initialise(configPath, g13cfg)
 			if err != nil {
 				return err
 			}
+			signal.Notify(interrupts, os.Interrupt)
+			stopReader = make(chan struct{})
+			inputs = startReader(d.dev, stopReader)
 			consecutiveReadErrors = 0
 			fmt.Println("Device restored")
 		}
 	}
 }
 
+// renderNotice draws a few lines of text, centred vertically, as an image for
+// the LCD.
+func renderNotice(lines ...string) image.Image {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	face := lcdtext.Builtin
+	lineHeight := face.Height
+	top := (device.LCDHeight - len(lines)*lineHeight) / 2
+	for idx, line := range lines {
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  image.Black,
+			Face: face,
+			Dot:  fixed.P(4, top+idx*lineHeight+face.Ascent),
+		}
+		drawer.DrawString(line)
+	}
+	return img
+}
+
 func main() {
 	cmd := mkcmd()
 	if err := cmd.Execute(); err != nil {
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/config"
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/menu"
+)
+
+// Distance from the edge of the stick range within which the stick moves
+// through the menu.
+const menuStickZone = 64
+
+// direction is a direction the stick is pushed towards.
+type direction uint8
+
+const (
+	dirNone direction = iota
+	dirUp
+	dirDown
+	dirLeft
+	dirRight
+)
+
+// Backlight colours offered by the menu, in addition to the configured one.
+var backlightPresets = []struct {
+	name   string
+	colour [3]uint8
+}{
+	{"Red", [3]uint8{255, 0, 0}},
+	{"Green", [3]uint8{0, 255, 0}},
+	{"Blue", [3]uint8{0, 0, 255}},
+	{"White", [3]uint8{255, 255, 255}},
+	{"Orange", [3]uint8{255, 96, 0}},
+	{"Purple", [3]uint8{128, 0, 255}},
+	{"Off", [3]uint8{0, 0, 0}},
+}
+
+// menuItems returns the items of the on-device menu. Chosen items hold the
+// [config.Action] they run, or nil to close the menu.
+func (d *driver) menuItems() []menu.Item {
+	profileItems := []menu.Item{}
+	profiles, err := config.FindProfiles(d.cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error finding profiles: %s\n", err)
+	}
+	current, _ := filepath.Abs(d.cfgPath)
+	for _, profile := range profiles {
+		label := "  " + profile.Name
+		if profile.Path == current {
+			label = "* " + profile.Name
+		}
+		profileItems = append(profileItems, menu.Item{
+			Label: label,
+			Value: config.Action{Type: config.ActionProfile, Profile: profile.Name},
+		})
+	}
+	if len(profileItems) == 0 {
+		profileItems = append(profileItems, menu.Item{Label: "No profiles"})
+	}
+
+	backlightItems := []menu.Item{{
+		Label: "Configured",
+		Value: config.Action{Type: config.ActionBacklight, Colour: d.cfg.GetBacklight()},
+	}}
+	for _, preset := range backlightPresets {
+		backlightItems = append(backlightItems, menu.Item{
+			Label: preset.name,
+			Value: config.Action{Type: config.ActionBacklight, Colour: preset.colour},
+		})
+	}
+
+	stickItems := []menu.Item{}
+	// modes without their settings loaded would leave the stick doing
+	// nothing, and the absolute pointer is only created when configured
+	for _, mode := range d.cfg.GetStickModes() {
+		label := "  " + mode.String()
+		if mode == d.cfg.GetStickMode() {
+			label = "* " + mode.String()
+		}
+		stickItems = append(stickItems, menu.Item{
+			Label: label,
+			Value: config.Action{Type: config.ActionStickMode, StickModes: []config.StickMode{mode}},
+		})
+	}
+
+	pauseLabel := "Pause mapping"
+	if d.paused {
+		pauseLabel = "Resume mapping"
+	}
+
+	return []menu.Item{
+		{Label: "Profile", Items: profileItems},
+		{Label: "Backlight", Items: backlightItems},
+		{Label: "Stick mode", Items: stickItems},
+		{Label: pauseLabel, Value: config.Action{Type: config.ActionPause}},
+		{Label: "Close"},
+	}
+}
+
+// openMenu opens the on-device menu, releasing any outputs that are held
+// since input goes to the menu while it's open.
+func (d *driver) openMenu() {
+	d.releaseOutputs()
+	d.lcd.Remove(layerNotice)
+	d.menu = menu.Open("Menu", d.menuItems())
+	d.drawMenu()
+}
+
+// closeMenu closes the on-device menu and restores the LCD.
+func (d *driver) closeMenu() {
+	d.menu = nil
+	d.lcd.Remove(layerMenu)
+}
+
+func (d *driver) drawMenu() {
+	d.setLayer(layerMenu, d.menu.Render(), time.Time{})
+}
+
+// handleMenuInput navigates the open menu: L1 goes back, L2 and L3 move up and
+// down, and L4 chooses the selected item. The stick moves up and down, enters
+// submenus when pushed right, and goes back when pushed left. The menu key
+// closes the menu.
+func (d *driver) handleMenuInput(prevInput, input uint64) {
+	pressed := input &^ prevInput
+	if menuKey, ok := d.cfg.MenuKey(); ok && pressed&menuKey.Uint64() != 0 {
+		d.closeMenu()
+		return
+	}
+
+	prevDir, dir := stickDirection(prevInput), stickDirection(input)
+	if dir == prevDir {
+		dir = dirNone
+	}
+
+	switch {
+	case pressed&device.L1.Uint64() != 0 || dir == dirLeft:
+		if !d.menu.Back() {
+			d.closeMenu()
+			return
+		}
+	case pressed&device.L2.Uint64() != 0 || dir == dirUp:
+		d.menu.Up()
+	case pressed&device.L3.Uint64() != 0 || dir == dirDown:
+		d.menu.Down()
+	case pressed&device.L4.Uint64() != 0 || dir == dirRight:
+		item := d.menu.Enter()
+		if item == nil {
+			// opened a submenu
+			break
+		}
+		d.closeMenu()
+		if action, ok := item.Value.(config.Action); ok {
+			d.runAction(action)
+		}
+		return
+	default:
+		return
+	}
+	d.drawMenu()
+}
+
+// stickDirection returns the direction the stick is pushed towards, or
+// dirNone if it's near the centre.
+func stickDirection(input uint64) direction {
+	x, y := device.StickPosition(input)
+	switch {
+	case y <= menuStickZone:
+		return dirUp
+	case y >= 255-menuStickZone:
+		return dirDown
+	case x <= menuStickZone:
+		return dirLeft
+	case x >= 255-menuStickZone:
+		return dirRight
+	}
+	return dirNone
+}
+
+// setPaused pauses or resumes the translation of G13 input to the virtual
+// devices. While paused, only menu and pause actions are run.
+func (d *driver) setPaused(paused bool) {
+	if paused {
+		d.releaseOutputs()
+	}
+	d.paused = paused
+	if paused {
+		d.showNotice("Mapping paused")
+	} else {
+		d.showNotice("Mapping resumed")
+	}
+	d.flashEvent(config.FlashPause)
+}
+
+// switchProfile loads the config of a profile, which replaces the running
+// config once the current input has been handled.
+func (d *driver) switchProfile(name string) {
+	path, err := config.ProfilePath(d.cfgPath, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error switching profile: %s\n", err)
+		return
+	}
+	cfg, err := config.NewFromFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error switching profile: %s\n", err)
+		d.showNotice("Profile error:", name)
+		return
+	}
+	d.releaseOutputs()
+	d.nextCfg = cfg
+	d.nextCfgPath = path
+}
@@ -0,0 +1,228 @@
+// Package animation loads animations for the G13 LCD, from animated GIFs or
+// directories of frames, and plays them back.
+package animation
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+)
+
+const (
+	// Frame rate used for directories of frames when none is given.
+	DefaultFPS = 10
+
+	// Delay used for GIF frames that don't specify one. Browsers treat very
+	// short delays the same way.
+	defaultGIFDelay = 100 * time.Millisecond
+
+	// GIF frames with delays shorter than this get the default delay.
+	minGIFDelay = 20 * time.Millisecond
+)
+
+// Frame is a single image of an animation and how long it's shown for.
+type Frame struct {
+	Image image.Image
+	Delay time.Duration
+}
+
+// Animation is a sequence of frames.
+type Animation struct {
+	Frames []Frame
+
+	// Number of times the animation is played. Zero loops forever.
+	Loops int
+}
+
+// SetFPS replaces the delay of every frame with the delay for the given frame
+// rate.
+func (a *Animation) SetFPS(fps float64) {
+	delay := time.Duration(float64(time.Second) / fps)
+	for idx := range a.Frames {
+		a.Frames[idx].Delay = delay
+	}
+}
+
+// Prepare fits every frame to the LCD and converts it to black and white, so
+// that frames can be drawn without any further processing during playback.
+func (a *Animation) Prepare(scale lcdimage.ScaleMode, conv lcdimage.Conversion) {
+	for idx, frame := range a.Frames {
+		a.Frames[idx].Image = conv.Apply(lcdimage.Fit(frame.Image, scale))
+	}
+}
+
+// Load reads an animation from path, which can be an animated GIF or a
+// directory of frames. Frames in a directory are played in the order of their
+// file names at [DefaultFPS] and loop forever.
+func Load(path string) (*Animation, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open animation %q: %w", path, err)
+	}
+	if info.IsDir() {
+		return LoadDir(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open animation %q: %w", path, err)
+	}
+	defer file.Close()
+	anim, err := DecodeGIF(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read animation %q: %w", path, err)
+	}
+	return anim, nil
+}
+
+// LoadDir reads every image in a directory as a frame of an animation, in the
+// order of their file names. Files that aren't images are skipped.
+func LoadDir(path string) (*Animation, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read animation directory %q: %w", path, err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+
+	anim := &Animation{}
+	for _, name := range names {
+		framePath := filepath.Join(path, name)
+		file, err := os.Open(framePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open animation frame %q: %w", framePath, err)
+		}
+		img, _, err := image.Decode(file)
+		file.Close()
+		if err == image.ErrFormat {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read animation frame %q: %w", framePath, err)
+		}
+		anim.Frames = append(anim.Frames, Frame{Image: img})
+	}
+	if len(anim.Frames) == 0 {
+		return nil, fmt.Errorf("animation directory %q contains no images", path)
+	}
+	anim.SetFPS(DefaultFPS)
+	return anim, nil
+}
+
+// DecodeGIF reads an animated GIF. Frames are composed according to their
+// disposal methods so that each frame of the result is a complete image.
+func DecodeGIF(r io.Reader) (*Animation, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, fmt.Errorf("gif contains no frames")
+	}
+
+	anim := &Animation{}
+	switch {
+	case g.LoopCount == 0:
+		// loop forever
+	case g.LoopCount < 0:
+		anim.Loops = 1
+	default:
+		// the loop count is the number of repetitions after the first time
+		anim.Loops = g.LoopCount + 1
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for idx, paletted := range g.Image {
+		var disposal byte
+		if idx < len(g.Disposal) {
+			disposal = g.Disposal[idx]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, paletted.Bounds(), paletted, paletted.Bounds().Min, draw.Over)
+
+		delay := defaultGIFDelay
+		if idx < len(g.Delay) {
+			// delays are in hundredths of a second
+			if d := time.Duration(g.Delay[idx]) * 10 * time.Millisecond; d >= minGIFDelay {
+				delay = d
+			}
+		}
+		anim.Frames = append(anim.Frames, Frame{Image: cloneRGBA(canvas), Delay: delay})
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, paletted.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return anim, nil
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := *img
+	clone.Pix = slices.Clone(img.Pix)
+	return &clone
+}
+
+// Play sends the frames of the animation to the returned channel, each after
+// the delay of the frame before it, until the animation ends or stop is
+// closed. The channel is closed when playback stops.
+func Play(anim *Animation, stop <-chan struct{}) <-chan image.Image {
+	frames := make(chan image.Image)
+	go func() {
+		defer close(frames)
+		if len(anim.Frames) == 0 {
+			return
+		}
+		if len(anim.Frames) == 1 {
+			// nothing to animate
+			select {
+			case frames <- anim.Frames[0].Image:
+			case <-stop:
+			}
+			return
+		}
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for loop := 0; anim.Loops == 0 || loop < anim.Loops; loop++ {
+			for _, frame := range anim.Frames {
+				select {
+				case <-timer.C:
+				case <-stop:
+					return
+				}
+				start := time.Now()
+				select {
+				case frames <- frame.Image:
+				case <-stop:
+					return
+				}
+				// the time spent waiting for the frame to be received counts
+				// towards its delay
+				timer.Reset(frame.Delay - time.Since(start))
+			}
+		}
+	}()
+	return frames
+}
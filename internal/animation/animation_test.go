@@ -0,0 +1,186 @@
+package animation_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/animation"
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/stretchr/testify/assert"
+)
+
+var palette = color.Palette{color.Transparent, color.Black, color.White}
+
+// encodeGIF encodes a 4x1 animated GIF where frame i draws a black pixel at
+// x=i, using the given disposal method for every frame.
+func encodeGIF(t *testing.T, nFrames int, disposal byte, loopCount int, delay int) []byte {
+	g := &gif.GIF{
+		LoopCount: loopCount,
+		Config:    image.Config{ColorModel: palette, Width: 4, Height: 1},
+	}
+	for idx := range nFrames {
+		frame := image.NewPaletted(image.Rect(idx, 0, idx+1, 1), palette)
+		frame.SetColorIndex(idx, 0, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, delay)
+		g.Disposal = append(g.Disposal, disposal)
+	}
+	buf := bytes.Buffer{}
+	assert.NoError(t, gif.EncodeAll(&buf, g))
+	return buf.Bytes()
+}
+
+// blackPixels returns the x coordinates of the black pixels in the first row
+// of img.
+func blackPixels(img image.Image) []int {
+	var xs []int
+	for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+		if _, _, _, a := img.At(x, 0).RGBA(); a == 0 {
+			continue
+		}
+		if r, _, _, _ := img.At(x, 0).RGBA(); r == 0 {
+			xs = append(xs, x)
+		}
+	}
+	return xs
+}
+
+func TestDecodeGIF(t *testing.T) {
+	t.Run("disposal-none", func(t *testing.T) {
+		assert := assert.New(t)
+		anim, err := animation.DecodeGIF(bytes.NewReader(encodeGIF(t, 3, gif.DisposalNone, 0, 5)))
+		assert.NoError(err)
+		assert.Len(anim.Frames, 3)
+		assert.Equal(0, anim.Loops)
+		assert.Equal([]int{0}, blackPixels(anim.Frames[0].Image))
+		assert.Equal([]int{0, 1}, blackPixels(anim.Frames[1].Image))
+		assert.Equal([]int{0, 1, 2}, blackPixels(anim.Frames[2].Image))
+		for _, frame := range anim.Frames {
+			assert.Equal(50*time.Millisecond, frame.Delay)
+		}
+	})
+
+	t.Run("disposal-background", func(t *testing.T) {
+		assert := assert.New(t)
+		anim, err := animation.DecodeGIF(bytes.NewReader(encodeGIF(t, 3, gif.DisposalBackground, 2, 0)))
+		assert.NoError(err)
+		assert.Equal(3, anim.Loops)
+		assert.Equal([]int{0}, blackPixels(anim.Frames[0].Image))
+		assert.Equal([]int{1}, blackPixels(anim.Frames[1].Image))
+		assert.Equal([]int{2}, blackPixels(anim.Frames[2].Image))
+		for _, frame := range anim.Frames {
+			// no delay set: the default is used
+			assert.Equal(100*time.Millisecond, frame.Delay)
+		}
+	})
+
+	t.Run("play-once", func(t *testing.T) {
+		assert := assert.New(t)
+		anim, err := animation.DecodeGIF(bytes.NewReader(encodeGIF(t, 2, gif.DisposalNone, -1, 10)))
+		assert.NoError(err)
+		assert.Equal(1, anim.Loops)
+	})
+
+	t.Run("not-a-gif", func(t *testing.T) {
+		_, err := animation.DecodeGIF(bytes.NewReader([]byte("P1 1 1 1")))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadDir(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	for idx, name := range []string{"b.png", "a.png", "c.png"} {
+		img := image.NewGray(image.Rect(0, 0, idx+1, 1))
+		fp, err := os.Create(filepath.Join(dir, name))
+		assert.NoError(err)
+		assert.NoError(png.Encode(fp, img))
+		assert.NoError(fp.Close())
+	}
+	assert.NoError(os.WriteFile(filepath.Join(dir, "README"), []byte("not an image"), 0o644))
+	assert.NoError(os.Mkdir(filepath.Join(dir, "subdir"), 0o755))
+
+	anim, err := animation.Load(dir)
+	assert.NoError(err)
+	assert.Len(anim.Frames, 3)
+	assert.Equal(0, anim.Loops)
+	// sorted by name
+	assert.Equal(2, anim.Frames[0].Image.Bounds().Dx())
+	assert.Equal(1, anim.Frames[1].Image.Bounds().Dx())
+	assert.Equal(3, anim.Frames[2].Image.Bounds().Dx())
+	for _, frame := range anim.Frames {
+		assert.Equal(time.Second/animation.DefaultFPS, frame.Delay)
+	}
+
+	anim.SetFPS(50)
+	assert.Equal(20*time.Millisecond, anim.Frames[0].Delay)
+
+	anim.Prepare(lcdimage.ScaleFit, lcdimage.DefaultConversion())
+	for _, frame := range anim.Frames {
+		assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), frame.Image.Bounds())
+	}
+
+	_, err = animation.Load(t.TempDir())
+	assert.ErrorContains(err, "contains no images")
+
+	_, err = animation.Load(filepath.Join(dir, "nope.gif"))
+	assert.ErrorContains(err, "failed to open animation")
+}
+
+// testAnimation returns an animation with n frames, each a distinct image,
+// with a short delay.
+func testAnimation(n int, loops int) *animation.Animation {
+	anim := &animation.Animation{Loops: loops}
+	for idx := range n {
+		anim.Frames = append(anim.Frames, animation.Frame{
+			Image: image.NewGray(image.Rect(0, 0, idx+1, 1)),
+			Delay: time.Millisecond,
+		})
+	}
+	return anim
+}
+
+func TestPlay(t *testing.T) {
+	t.Run("loops", func(t *testing.T) {
+		assert := assert.New(t)
+		var widths []int
+		for frame := range animation.Play(testAnimation(3, 2), make(chan struct{})) {
+			widths = append(widths, frame.Bounds().Dx())
+		}
+		assert.Equal([]int{1, 2, 3, 1, 2, 3}, widths)
+	})
+
+	t.Run("single-frame", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		for range animation.Play(testAnimation(1, 0), make(chan struct{})) {
+			count++
+		}
+		assert.Equal(1, count)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		assert := assert.New(t)
+		stop := make(chan struct{})
+		frames := animation.Play(testAnimation(3, 0), stop)
+		for range 10 {
+			<-frames
+		}
+		close(stop)
+		// drain any frame that was already being sent; the channel must be
+		// closed afterwards
+		for range frames {
+		}
+		_, ok := <-frames
+		assert.False(ok)
+	})
+}
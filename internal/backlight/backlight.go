@@ -0,0 +1,248 @@
+// Package backlight computes the colour of the backlight over time for
+// effects like breathing, colour cycling, fades, and flashes, and limits how
+// often the colour is sent to the device. It also reads colours written as
+// hex, CSS names, or HSV.
+package backlight
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultMinInterval is the shortest time between colour changes sent to the
+// device, so effects don't flood it with control transfers.
+const DefaultMinInterval = 50 * time.Millisecond
+
+// Effect is the colour of the backlight over time.
+type Effect interface {
+	// At returns the colour at a time since the effect started, given the
+	// colour of the effect under it, and whether the effect is still
+	// running. Effects that never finish ignore the colour under them.
+	At(elapsed time.Duration, under [3]uint8) ([3]uint8, bool)
+}
+
+// Static is a single colour.
+type Static [3]uint8
+
+func (s Static) At(time.Duration, [3]uint8) ([3]uint8, bool) {
+	return s, true
+}
+
+// Breathing fades a colour in and out.
+type Breathing struct {
+	Colour [3]uint8
+
+	// Time for the brightness to go from the highest to the lowest and back.
+	Period time.Duration
+
+	// Lowest brightness, from 0 to 1.
+	Min float64
+}
+
+func (b Breathing) At(elapsed time.Duration, _ [3]uint8) ([3]uint8, bool) {
+	if b.Period <= 0 {
+		return b.Colour, true
+	}
+	phase := 2 * math.Pi * float64(elapsed%b.Period) / float64(b.Period)
+	brightness := b.Min + (1-b.Min)*(1+math.Cos(phase))/2
+	return Scale(b.Colour, brightness), true
+}
+
+// Cycle fades through colours in order, starting over after the last one.
+type Cycle struct {
+	Colours [][3]uint8
+
+	// Time to fade from one colour to the next.
+	Period time.Duration
+}
+
+func (c Cycle) At(elapsed time.Duration, _ [3]uint8) ([3]uint8, bool) {
+	switch {
+	case len(c.Colours) == 0:
+		return [3]uint8{}, true
+	case len(c.Colours) == 1 || c.Period <= 0:
+		return c.Colours[0], true
+	}
+	step := int(elapsed / c.Period)
+	from := c.Colours[step%len(c.Colours)]
+	to := c.Colours[(step+1)%len(c.Colours)]
+	return Blend(from, to, float64(elapsed%c.Period)/float64(c.Period)), true
+}
+
+// Fade fades from a colour to the colour of the effect under it.
+type Fade struct {
+	From     [3]uint8
+	Duration time.Duration
+}
+
+func (f Fade) At(elapsed time.Duration, under [3]uint8) ([3]uint8, bool) {
+	if elapsed >= f.Duration {
+		return under, false
+	}
+	return Blend(f.From, under, float64(elapsed)/float64(f.Duration)), true
+}
+
+// Flash shows a colour for a while.
+type Flash struct {
+	Colour   [3]uint8
+	Duration time.Duration
+}
+
+func (f Flash) At(elapsed time.Duration, under [3]uint8) ([3]uint8, bool) {
+	if elapsed >= f.Duration {
+		return under, false
+	}
+	return f.Colour, true
+}
+
+// Stop is the colour of a gradient at a value.
+type Stop struct {
+	Value  float64
+	Colour [3]uint8
+}
+
+// Gradient blends the colours of stops, sorted by value. Values outside the
+// stops have the colour of the nearest one.
+type Gradient []Stop
+
+// At returns the colour of the gradient at a value.
+func (g Gradient) At(value float64) [3]uint8 {
+	if len(g) == 0 {
+		return [3]uint8{}
+	}
+	if value <= g[0].Value {
+		return g[0].Colour
+	}
+	for idx := 1; idx < len(g); idx++ {
+		from, to := g[idx-1], g[idx]
+		if value < to.Value {
+			return Blend(from.Colour, to.Colour, (value-from.Value)/(to.Value-from.Value))
+		}
+	}
+	return g[len(g)-1].Colour
+}
+
+// Gauge is the colour of a gradient at a value that changes over time, like a
+// system statistic.
+type Gauge struct {
+	gradient Gradient
+	value    float64
+}
+
+// NewGauge returns a gauge for a gradient, at the value of its first stop
+// until one is set.
+func NewGauge(gradient Gradient) *Gauge {
+	g := &Gauge{gradient: gradient}
+	if len(gradient) > 0 {
+		g.value = gradient[0].Value
+	}
+	return g
+}
+
+// Set changes the value of the gauge.
+func (g *Gauge) Set(value float64) {
+	g.value = value
+}
+
+func (g *Gauge) At(time.Duration, [3]uint8) ([3]uint8, bool) {
+	return g.gradient.At(g.value), true
+}
+
+// Blend mixes two colours: 0 is the first colour and 1 the second.
+func Blend(from, to [3]uint8, amount float64) [3]uint8 {
+	amount = min(1, max(0, amount))
+	var mixed [3]uint8
+	for idx := range mixed {
+		mixed[idx] = uint8(math.Round(float64(from[idx]) + (float64(to[idx])-float64(from[idx]))*amount))
+	}
+	return mixed
+}
+
+// playing is an effect and the time it started.
+type playing struct {
+	effect  Effect
+	started time.Time
+}
+
+// Scheduler runs a base effect, like a static colour or breathing, with
+// effects that finish, like flashes and fades, over it. It decides when the
+// colour needs to be sent to the device.
+type Scheduler struct {
+	minInterval time.Duration
+
+	base playing
+	// effect shown instead of the base while it's set, like a warning that
+	// lasts as long as a state (nil if not set)
+	override *playing
+	// finishing effects over the base, from the bottom to the top
+	over []playing
+
+	sent     bool
+	lastSent [3]uint8
+	sentAt   time.Time
+}
+
+// NewScheduler returns a scheduler for a base effect that sends colours at
+// most once per interval.
+func NewScheduler(base Effect, minInterval time.Duration, now time.Time) *Scheduler {
+	return &Scheduler{
+		minInterval: minInterval,
+		base:        playing{effect: base, started: now},
+	}
+}
+
+// SetBase replaces the base effect and ends the effects over it.
+func (s *Scheduler) SetBase(effect Effect, now time.Time) {
+	s.base = playing{effect: effect, started: now}
+	s.over = nil
+}
+
+// SetOverride shows an effect instead of the base effect, under the effects
+// played over it, until it's set to nil.
+func (s *Scheduler) SetOverride(effect Effect, now time.Time) {
+	if effect == nil {
+		s.override = nil
+		return
+	}
+	s.override = &playing{effect: effect, started: now}
+}
+
+// Play runs an effect over the base effect until it finishes. The most
+// recent effect is on top.
+func (s *Scheduler) Play(effect Effect, now time.Time) {
+	s.over = append(s.over, playing{effect: effect, started: now})
+}
+
+// Colour returns the colour of the backlight at a time, removing the effects
+// that finished.
+func (s *Scheduler) Colour(now time.Time) [3]uint8 {
+	colour, _ := s.base.effect.At(now.Sub(s.base.started), [3]uint8{})
+	if s.override != nil {
+		colour, _ = s.override.effect.At(now.Sub(s.override.started), colour)
+	}
+	running := s.over[:0]
+	for _, p := range s.over {
+		next, ok := p.effect.At(now.Sub(p.started), colour)
+		if !ok {
+			continue
+		}
+		colour = next
+		running = append(running, p)
+	}
+	s.over = running
+	return colour
+}
+
+// Next returns the colour to send to the device at a time. It returns false
+// if the colour hasn't changed since it was last sent, or if it was sent less
+// than the minimum interval ago.
+func (s *Scheduler) Next(now time.Time) ([3]uint8, bool) {
+	colour := s.Colour(now)
+	if s.sent && (colour == s.lastSent || now.Sub(s.sentAt) < s.minInterval) {
+		return colour, false
+	}
+	s.sent = true
+	s.lastSent = colour
+	s.sentAt = now
+	return colour, true
+}
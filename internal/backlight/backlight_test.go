@@ -0,0 +1,210 @@
+package backlight_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/backlight"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	red   = [3]uint8{255, 0, 0}
+	green = [3]uint8{0, 255, 0}
+	blue  = [3]uint8{0, 0, 255}
+)
+
+func TestBlend(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(red, backlight.Blend(red, blue, 0))
+	assert.Equal(blue, backlight.Blend(red, blue, 1))
+	assert.Equal([3]uint8{128, 0, 128}, backlight.Blend(red, blue, 0.5))
+	assert.Equal(blue, backlight.Blend(red, blue, 2))
+}
+
+func TestEffects(t *testing.T) {
+	t.Run("static", func(t *testing.T) {
+		assert := assert.New(t)
+		colour, running := backlight.Static(red).At(time.Hour, blue)
+		assert.Equal(red, colour)
+		assert.True(running)
+	})
+
+	t.Run("breathing", func(t *testing.T) {
+		assert := assert.New(t)
+		effect := backlight.Breathing{Colour: [3]uint8{200, 100, 0}, Period: 4 * time.Second, Min: 0.5}
+		colour, running := effect.At(0, blue)
+		assert.Equal([3]uint8{200, 100, 0}, colour)
+		assert.True(running)
+		colour, _ = effect.At(2*time.Second, blue)
+		assert.Equal([3]uint8{100, 50, 0}, colour)
+		colour, _ = effect.At(4*time.Second, blue)
+		assert.Equal([3]uint8{200, 100, 0}, colour)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		assert := assert.New(t)
+		effect := backlight.Cycle{Colours: [][3]uint8{red, green, blue}, Period: time.Second}
+		colour, running := effect.At(0, [3]uint8{})
+		assert.Equal(red, colour)
+		assert.True(running)
+		colour, _ = effect.At(1500*time.Millisecond, [3]uint8{})
+		assert.Equal([3]uint8{0, 128, 128}, colour)
+		// back to the first colour after the last
+		colour, _ = effect.At(2500*time.Millisecond, [3]uint8{})
+		assert.Equal([3]uint8{128, 0, 128}, colour)
+		colour, _ = effect.At(3*time.Second, [3]uint8{})
+		assert.Equal(red, colour)
+
+		colour, _ = backlight.Cycle{Colours: [][3]uint8{green}, Period: time.Second}.At(time.Hour, red)
+		assert.Equal(green, colour)
+	})
+
+	t.Run("fade", func(t *testing.T) {
+		assert := assert.New(t)
+		effect := backlight.Fade{From: red, Duration: time.Second}
+		colour, running := effect.At(500*time.Millisecond, blue)
+		assert.Equal([3]uint8{128, 0, 128}, colour)
+		assert.True(running)
+		colour, running = effect.At(time.Second, blue)
+		assert.Equal(blue, colour)
+		assert.False(running)
+	})
+
+	t.Run("flash", func(t *testing.T) {
+		assert := assert.New(t)
+		effect := backlight.Flash{Colour: red, Duration: time.Second}
+		colour, running := effect.At(999*time.Millisecond, blue)
+		assert.Equal(red, colour)
+		assert.True(running)
+		colour, running = effect.At(time.Second, blue)
+		assert.Equal(blue, colour)
+		assert.False(running)
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sched := backlight.NewScheduler(backlight.Static(blue), 50*time.Millisecond, start)
+
+	// the first colour is always sent, and then only changes
+	colour, ok := sched.Next(start)
+	assert.True(ok)
+	assert.Equal(blue, colour)
+	_, ok = sched.Next(start.Add(time.Second))
+	assert.False(ok)
+
+	// effects over the base are sent, limited to one colour per interval
+	now := start.Add(2 * time.Second)
+	sched.Play(backlight.Flash{Colour: red, Duration: 200 * time.Millisecond}, now)
+	colour, ok = sched.Next(now)
+	assert.True(ok)
+	assert.Equal(red, colour)
+	sched.Play(backlight.Flash{Colour: green, Duration: 100 * time.Millisecond}, now.Add(10*time.Millisecond))
+	_, ok = sched.Next(now.Add(20 * time.Millisecond))
+	assert.False(ok)
+	colour, ok = sched.Next(now.Add(60 * time.Millisecond))
+	assert.True(ok)
+	assert.Equal(green, colour)
+
+	// the top effect finished, then the one under it
+	assert.Equal(red, sched.Colour(now.Add(150*time.Millisecond)))
+	colour, ok = sched.Next(now.Add(250 * time.Millisecond))
+	assert.True(ok)
+	assert.Equal(blue, colour)
+
+	// a new base ends the effects over the old one
+	sched.Play(backlight.Flash{Colour: red, Duration: time.Second}, now)
+	sched.SetBase(backlight.Static(green), now.Add(300*time.Millisecond))
+	assert.Equal(green, sched.Colour(now.Add(300*time.Millisecond)))
+}
+
+func TestGradient(t *testing.T) {
+	assert := assert.New(t)
+	gradient := backlight.Gradient{{Value: 20, Colour: green}, {Value: 60, Colour: [3]uint8{255, 255, 0}}, {Value: 80, Colour: red}}
+	assert.Equal(green, gradient.At(0))
+	assert.Equal(green, gradient.At(20))
+	assert.Equal([3]uint8{128, 255, 0}, gradient.At(40))
+	assert.Equal([3]uint8{255, 255, 0}, gradient.At(60))
+	assert.Equal([3]uint8{255, 128, 0}, gradient.At(70))
+	assert.Equal(red, gradient.At(80))
+	assert.Equal(red, gradient.At(100))
+	assert.Equal([3]uint8{}, backlight.Gradient{}.At(50))
+
+	// a gauge starts at the first stop
+	gauge := backlight.NewGauge(gradient)
+	colour, running := gauge.At(0, blue)
+	assert.Equal(green, colour)
+	assert.True(running)
+	gauge.Set(70)
+	colour, _ = gauge.At(time.Hour, blue)
+	assert.Equal([3]uint8{255, 128, 0}, colour)
+}
+
+func TestSchedulerOverride(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sched := backlight.NewScheduler(backlight.Static(blue), 0, start)
+
+	// the override replaces the base, and flashes still show over it
+	sched.SetOverride(backlight.Static(red), start)
+	assert.Equal(red, sched.Colour(start))
+	sched.Play(backlight.Flash{Colour: green, Duration: time.Second}, start)
+	assert.Equal(green, sched.Colour(start))
+	assert.Equal(red, sched.Colour(start.Add(time.Second)))
+
+	// a new base stays under the override until it's cleared
+	sched.SetBase(backlight.Static(green), start.Add(time.Second))
+	assert.Equal(red, sched.Colour(start.Add(time.Second)))
+	sched.SetOverride(nil, start.Add(2*time.Second))
+	assert.Equal(green, sched.Colour(start.Add(2*time.Second)))
+}
+
+func TestParseColour(t *testing.T) {
+	type testCase struct {
+		text     string
+		expected [3]uint8
+	}
+	testCases := map[string]testCase{
+		"hex":       {text: "#17a3d1", expected: [3]uint8{0x17, 0xa3, 0xd1}},
+		"hex-upper": {text: "#17A3D1", expected: [3]uint8{0x17, 0xa3, 0xd1}},
+		"hex-short": {text: "#1ad", expected: [3]uint8{0x11, 0xaa, 0xdd}},
+		"name":      {text: "teal", expected: [3]uint8{0, 128, 128}},
+		"name-case": {text: "CornflowerBlue", expected: [3]uint8{100, 149, 237}},
+		"hsv":       {text: "hsv(120, 100%, 100%)", expected: green},
+		"hsv-plain": {text: "hsv(240,100,50)", expected: [3]uint8{0, 0, 128}},
+		"hsv-grey":  {text: "hsv(0, 0%, 50%)", expected: [3]uint8{128, 128, 128}},
+		"hsv-360":   {text: "hsv(360, 100%, 100%)", expected: red},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			colour, err := backlight.ParseColour(tc.text)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, colour)
+		})
+	}
+
+	errors := map[string]string{
+		"#12345":             `invalid colour "#12345": hex colours need 3 or 6 digits`,
+		"#12345g":            `invalid colour "#12345g": invalid hex digits "5g"`,
+		"bluish":             `invalid colour "bluish": unknown colour name`,
+		"hsv(10, 20%)":       `invalid colour "hsv(10, 20%)": HSV colours need a hue, saturation, and value`,
+		"hsv(400, 0, 0)":     `invalid colour "hsv(400, 0, 0)": hue 400 must be between 0 and 360`,
+		"hsv(0, 150%, 0)":    `invalid colour "hsv(0, 150%, 0)": saturation 150 must be between 0 and 100`,
+		"hsv(0, 0, -1)":      `invalid colour "hsv(0, 0, -1)": value -1 must be between 0 and 100`,
+		"hsv(a, 0%, 0%)":     `invalid colour "hsv(a, 0%, 0%)": invalid number "a"`,
+		"hsv(nan, 50%, 50%)": `invalid colour "hsv(nan, 50%, 50%)": invalid number "nan"`,
+		"hsv(0, inf%, 50%)":  `invalid colour "hsv(0, inf%, 50%)": invalid number "inf%"`,
+	}
+	for text, expected := range errors {
+		_, err := backlight.ParseColour(text)
+		assert.EqualError(t, err, expected, text)
+	}
+}
+
+func TestScale(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([3]uint8{100, 50, 0}, backlight.Scale([3]uint8{200, 100, 0}, 0.5))
+	assert.Equal([3]uint8{}, backlight.Scale(red, 0))
+}
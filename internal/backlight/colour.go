@@ -0,0 +1,117 @@
+package backlight
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// ParseColour reads a colour written as hex ("#17a3d1" or "#1ad"), a CSS
+// colour name ("teal"), or HSV ("hsv(195, 89%, 82%)", with the hue in degrees
+// and the saturation and value in percent).
+func ParseColour(s string) ([3]uint8, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	var colour [3]uint8
+	var err error
+	switch {
+	case strings.HasPrefix(text, "#"):
+		colour, err = parseHex(text[1:])
+	case strings.HasPrefix(text, "hsv(") && strings.HasSuffix(text, ")"):
+		colour, err = parseHSV(text[len("hsv(") : len(text)-1])
+	default:
+		named, ok := colornames.Map[text]
+		if !ok {
+			err = fmt.Errorf("unknown colour name")
+		}
+		colour = [3]uint8{named.R, named.G, named.B}
+	}
+	if err != nil {
+		return [3]uint8{}, fmt.Errorf("invalid colour %q: %w", s, err)
+	}
+	return colour, nil
+}
+
+func parseHex(digits string) ([3]uint8, error) {
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 {
+		return [3]uint8{}, fmt.Errorf("hex colours need 3 or 6 digits")
+	}
+	var colour [3]uint8
+	for idx := range colour {
+		value, err := strconv.ParseUint(digits[2*idx:2*idx+2], 16, 8)
+		if err != nil {
+			return [3]uint8{}, fmt.Errorf("invalid hex digits %q", digits[2*idx:2*idx+2])
+		}
+		colour[idx] = uint8(value)
+	}
+	return colour, nil
+}
+
+func parseHSV(args string) ([3]uint8, error) {
+	fields := strings.Split(args, ",")
+	if len(fields) != 3 {
+		return [3]uint8{}, fmt.Errorf("HSV colours need a hue, saturation, and value")
+	}
+	var values [3]float64
+	for idx, field := range fields {
+		field = strings.TrimSuffix(strings.TrimSpace(field), "%")
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return [3]uint8{}, fmt.Errorf("invalid number %q", strings.TrimSpace(fields[idx]))
+		}
+		values[idx] = value
+	}
+	hue, sat, val := values[0], values[1], values[2]
+	if hue < 0 || hue > 360 {
+		return [3]uint8{}, fmt.Errorf("hue %g must be between 0 and 360", hue)
+	}
+	if sat < 0 || sat > 100 {
+		return [3]uint8{}, fmt.Errorf("saturation %g must be between 0 and 100", sat)
+	}
+	if val < 0 || val > 100 {
+		return [3]uint8{}, fmt.Errorf("value %g must be between 0 and 100", val)
+	}
+	return HSV(hue, sat/100, val/100), nil
+}
+
+// HSV returns the colour of a hue in degrees, and a saturation and value from
+// 0 to 1.
+func HSV(hue, sat, val float64) [3]uint8 {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+	chroma := val * sat
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g = chroma, x
+	case hue < 120:
+		r, g = x, chroma
+	case hue < 180:
+		g, b = chroma, x
+	case hue < 240:
+		g, b = x, chroma
+	case hue < 300:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	m := val - chroma
+	return [3]uint8{
+		uint8(math.Round((r + m) * 255)),
+		uint8(math.Round((g + m) * 255)),
+		uint8(math.Round((b + m) * 255)),
+	}
+}
+
+// Scale multiplies the channels of a colour by a brightness from 0 to 1.
+func Scale(colour [3]uint8, brightness float64) [3]uint8 {
+	return Blend([3]uint8{}, colour, brightness)
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+)
+
+type ActionType uint8
+
+const (
+	ActionNone ActionType = iota
+	ActionStickMode
+	ActionPageNext
+	ActionPagePrevious
+	ActionPageSelect
+	ActionMenu
+	ActionPause
+	ActionBacklight
+	ActionProfile
+	ActionMediaPlayPause
+	ActionMediaNext
+	ActionMediaPrevious
+	ActionMediaSeek
+	ActionTimerStart
+	ActionTimerStop
+	ActionTimerToggle
+	ActionTimerReset
+)
+
+var pageActionTypes = map[string]ActionType{
+	"page-next":     ActionPageNext,
+	"page-previous": ActionPagePrevious,
+	"page-select":   ActionPageSelect,
+}
+
+var mediaActionTypes = map[string]ActionType{
+	"media-play-pause": ActionMediaPlayPause,
+	"media-next":       ActionMediaNext,
+	"media-previous":   ActionMediaPrevious,
+	"media-seek":       ActionMediaSeek,
+}
+
+var timerActionTypes = map[string]ActionType{
+	"timer-start":  ActionTimerStart,
+	"timer-stop":   ActionTimerStop,
+	"timer-toggle": ActionTimerToggle,
+	"timer-reset":  ActionTimerReset,
+}
+
+// Action is an operation of the driver itself, bound to a G key instead of a
+// keyboard key.
+type Action struct {
+	Type ActionType
+
+	// Stick modes for [ActionStickMode]: a single mode is set directly while
+	// multiple modes are cycled through in order.
+	StickModes []StickMode
+
+	// Backlight colour for [ActionBacklight].
+	Colour [3]uint8
+
+	// Name of the profile to switch to for [ActionProfile].
+	Profile string
+
+	// How far [ActionMediaSeek] moves the playback position, backwards if
+	// negative.
+	SeekOffset time.Duration
+
+	// Name of the timer controlled by [ActionTimerStart], [ActionTimerStop],
+	// [ActionTimerToggle], and [ActionTimerReset].
+	Timer string
+}
+
+// Key that opens the menu when no key is bound to a menu action and it isn't
+// mapped to anything else.
+const defaultMenuKey = device.BD
+
+// Default stick modes cycled through by a stick-mode action when none are
+// specified.
+var defaultStickModeCycle = []StickMode{
+	StickModeJoystick,
+	StickModeKeys,
+	StickModeMouse,
+	StickModeOff,
+}
+
+// NextStickMode returns the stick mode that a stick-mode action switches to
+// from the given mode.
+func (a Action) NextStickMode(current StickMode) StickMode {
+	for idx, mode := range a.StickModes {
+		if mode == current {
+			return a.StickModes[(idx+1)%len(a.StickModes)]
+		}
+	}
+	return a.StickModes[0]
+}
+
+type fileAction struct {
+	Type    string               `json:"type"`
+	Mode    string               `json:"mode"`
+	Modes   []string             `json:"modes"`
+	Colour  *backlightFileConfig `json:"colour"`
+	Profile string               `json:"profile"`
+	Offset  int64                `json:"offset_ms"`
+	Timer   string               `json:"timer"`
+}
+
+// GetActions returns the actions bound to keys that were pressed between the
+// previous and current input (from [device.ReadInput]).
+func (cfg *G13Config) GetActions(prevInput, input uint64) []Action {
+	pressed := input &^ prevInput
+	if pressed == 0 {
+		return nil
+	}
+
+	var actions []Action
+	for _, gkey := range device.AllKeys() {
+		if gkey.Uint64()&pressed == 0 {
+			continue
+		}
+		if action, ok := cfg.mapping.actions[gkey]; ok {
+			actions = append(actions, action)
+		} else if gkey == defaultMenuKey && cfg.usesDefaultMenuKey() {
+			actions = append(actions, Action{Type: ActionMenu})
+		}
+	}
+	return actions
+}
+
+// MenuKey returns the key that opens the menu: the first key bound to a menu
+// action or the default menu key if it's unbound. It returns false if the menu
+// can't be opened.
+func (cfg *G13Config) MenuKey() (device.KeyBit, bool) {
+	for _, gkey := range device.AllKeys() {
+		if action, ok := cfg.mapping.actions[gkey]; ok && action.Type == ActionMenu {
+			return gkey, true
+		}
+	}
+	if cfg.usesDefaultMenuKey() {
+		return defaultMenuKey, true
+	}
+	return 0, false
+}
+
+// usesDefaultMenuKey returns true if the default menu key opens the menu,
+// which is the case when no key is bound to a menu action and the default key
+// isn't mapped to anything else.
+func (cfg *G13Config) usesDefaultMenuKey() bool {
+	for _, action := range cfg.mapping.actions {
+		if action.Type == ActionMenu {
+			return false
+		}
+	}
+	if _, bound := cfg.mapping.actions[defaultMenuKey]; bound {
+		return false
+	}
+	if _, bound := cfg.mapping.keyMap[defaultMenuKey]; bound {
+		return false
+	}
+	if _, bound := cfg.mapping.buttonMap[defaultMenuKey]; bound {
+		return false
+	}
+	for _, menu := range cfg.mapping.radialMenus {
+		if menu.Trigger == defaultMenuKey {
+			return false
+		}
+	}
+	return true
+}
+
+func loadActions(fileActions map[string]fileAction, km keyMap, fileButtons map[string]string, cfgPath string) (map[device.KeyBit]Action, error) {
+	if len(fileActions) == 0 {
+		return nil, nil
+	}
+
+	actions := make(map[device.KeyBit]Action, len(fileActions))
+	for gKeyStr, fa := range fileActions {
+		gKey := device.KeyCode(gKeyStr)
+		if gKey == 0 {
+			return nil, fmt.Errorf("unknown G13 key name: %s", gKeyStr)
+		}
+		if _, mapped := km[gKey]; mapped {
+			return nil, fmt.Errorf("key %s bound to an action is also mapped to a keyboard key", gKeyStr)
+		}
+		if _, mapped := fileButtons[gKeyStr]; mapped {
+			return nil, fmt.Errorf("key %s bound to an action is also mapped to a joystick button", gKeyStr)
+		}
+
+		action, err := loadAction(fa, cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("action for key %s: %w", gKeyStr, err)
+		}
+		actions[gKey] = action
+	}
+	return actions, nil
+}
+
+// checkActionFields returns an error if options that don't belong to the type
+// of the action are set.
+func checkActionFields(fa fileAction) error {
+	if fa.Type != "stick-mode" && (fa.Mode != "" || len(fa.Modes) > 0) {
+		return fmt.Errorf("stick mode set for %s action", fa.Type)
+	}
+	if fa.Type != "backlight" && fa.Colour != nil {
+		return fmt.Errorf("colour set for %s action", fa.Type)
+	}
+	if fa.Type != "profile" && fa.Profile != "" {
+		return fmt.Errorf("profile set for %s action", fa.Type)
+	}
+	if fa.Type != "media-seek" && fa.Offset != 0 {
+		return fmt.Errorf("seek offset set for %s action", fa.Type)
+	}
+	if _, ok := timerActionTypes[fa.Type]; !ok && fa.Timer != "" {
+		return fmt.Errorf("timer set for %s action", fa.Type)
+	}
+	return nil
+}
+
+func loadAction(fa fileAction, cfgPath string) (Action, error) {
+	switch fa.Type {
+	case "":
+		return Action{}, fmt.Errorf("action type not set")
+	case "stick-mode", "page-next", "page-previous", "page-select", "menu", "pause", "backlight", "profile",
+		"media-play-pause", "media-next", "media-previous", "media-seek",
+		"timer-start", "timer-stop", "timer-toggle", "timer-reset":
+		if err := checkActionFields(fa); err != nil {
+			return Action{}, err
+		}
+	}
+
+	switch fa.Type {
+	case "stick-mode":
+		action := Action{Type: ActionStickMode}
+		if fa.Mode != "cycle" {
+			if len(fa.Modes) > 0 {
+				return Action{}, fmt.Errorf("stick modes to cycle through set for non-cycle mode %q", fa.Mode)
+			}
+			if fa.Mode == "" {
+				return Action{}, fmt.Errorf("stick mode not set")
+			}
+			mode, err := ParseStickMode(fa.Mode)
+			if err != nil {
+				return Action{}, err
+			}
+			action.StickModes = []StickMode{mode}
+			return action, nil
+		}
+
+		if len(fa.Modes) == 0 {
+			action.StickModes = defaultStickModeCycle
+			return action, nil
+		}
+		for _, name := range fa.Modes {
+			mode, err := ParseStickMode(name)
+			if err != nil {
+				return Action{}, err
+			}
+			action.StickModes = append(action.StickModes, mode)
+		}
+		return action, nil
+	case "page-next", "page-previous", "page-select":
+		return Action{Type: pageActionTypes[fa.Type]}, nil
+	case "menu":
+		return Action{Type: ActionMenu}, nil
+	case "pause":
+		return Action{Type: ActionPause}, nil
+	case "backlight":
+		if fa.Colour == nil {
+			return Action{}, fmt.Errorf("backlight colour not set")
+		}
+		colour, err := fa.Colour.colour()
+		if err != nil {
+			return Action{}, fmt.Errorf("backlight colour: %w", err)
+		}
+		return Action{Type: ActionBacklight, Colour: colour}, nil
+	case "profile":
+		if fa.Profile == "" {
+			return Action{}, fmt.Errorf("profile not set")
+		}
+		profilePath, err := ProfilePath(cfgPath, fa.Profile)
+		if err != nil {
+			return Action{}, err
+		}
+		if _, err := os.Stat(profilePath); err != nil {
+			return Action{}, fmt.Errorf("profile %q: %w", fa.Profile, err)
+		}
+		return Action{Type: ActionProfile, Profile: fa.Profile}, nil
+	case "media-play-pause", "media-next", "media-previous":
+		return Action{Type: mediaActionTypes[fa.Type]}, nil
+	case "media-seek":
+		if fa.Offset == 0 {
+			return Action{}, fmt.Errorf("seek offset not set")
+		}
+		return Action{Type: ActionMediaSeek, SeekOffset: time.Duration(fa.Offset) * time.Millisecond}, nil
+	case "timer-start", "timer-stop", "timer-toggle", "timer-reset":
+		if fa.Timer == "" {
+			return Action{}, fmt.Errorf("timer not set")
+		}
+		return Action{Type: timerActionTypes[fa.Type], Timer: fa.Timer}, nil
+	default:
+		return Action{}, fmt.Errorf("unknown action type: %s", fa.Type)
+	}
+}
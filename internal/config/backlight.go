@@ -0,0 +1,345 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/backlight"
+	"github.com/achilleas-k/gg13/internal/sysmon"
+)
+
+// DefaultMetricInterval is how often the statistic followed by the backlight
+// is read when no interval is set.
+const DefaultMetricInterval = time.Second
+
+// Metric name for backlight values pushed over the LCD input.
+const metricPushed = "pushed"
+
+// Events that can flash the backlight.
+const (
+	FlashStickMode = "stick-mode"
+	FlashPage      = "page"
+	FlashPause     = "pause"
+)
+
+// BacklightEffects configures how the backlight changes over time.
+type BacklightEffects struct {
+	// Effect run while the profile is loaded, which is the configured
+	// backlight colour if no effect is set. Backlight actions replace it with
+	// a static colour.
+	Effect backlight.Effect
+
+	// Statistic that the colour of the backlight follows instead of the
+	// effect (nil if it doesn't follow one).
+	Metric *BacklightMetric
+
+	// How long the backlight takes to fade from the colour of the previous
+	// profile when switching to the profile (0 to change at once).
+	Fade time.Duration
+
+	// Flashes shown when the events happen, by event name.
+	Flashes map[string]backlight.Flash
+
+	// Shortest time between colour changes sent to the device.
+	MinInterval time.Duration
+}
+
+// backlightFileConfig is a colour, written either as an object with red,
+// green, and blue from 0 to 255, or as a string read by
+// [backlight.ParseColour]. Strings are read by colour, so that errors can say
+// which setting the colour is for.
+type backlightFileConfig struct {
+	Red   uint8 `json:"red"`
+	Green uint8 `json:"green"`
+	Blue  uint8 `json:"blue"`
+
+	// the colour written as a string (nil if written as an object)
+	text *string
+}
+
+func (c *backlightFileConfig) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = backlightFileConfig{text: &text}
+		return nil
+	}
+
+	// an alias without this method, keeping unknown fields an error like
+	// the rest of the file
+	type rgb backlightFileConfig
+	var value rgb
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	*c = backlightFileConfig(value)
+	return nil
+}
+
+// BacklightMetric is a backlight colour that follows a system statistic, or
+// values pushed over the LCD input, through a gradient.
+type BacklightMetric struct {
+	// Statistic that is read, unless values are pushed.
+	Metric sysmon.Metric
+
+	// Temperature sensor or battery (empty for the first one).
+	Source string
+
+	// Values are pushed with the metric command of the LCD input instead of
+	// read.
+	Pushed bool
+
+	// How often the statistic is read.
+	Interval time.Duration
+
+	// Root of the /proc and /sys trees that the statistic is read from
+	// (empty for "/").
+	Root string
+
+	// Colours for values of the statistic, in its own units: percent for
+	// the CPU usage and battery, and degrees Celsius for temperatures.
+	Gradient backlight.Gradient
+}
+
+type fileBacklightEffects struct {
+	Effect        *fileBacklightEffect      `json:"effect"`
+	FadeMS        int64                     `json:"fade_ms"`
+	Flash         map[string]fileFlashEvent `json:"flash"`
+	MinIntervalMS int64                     `json:"min_interval_ms"`
+}
+
+type fileBacklightEffect struct {
+	Type          string                `json:"type"`
+	PeriodMS      int64                 `json:"period_ms"`
+	MinBrightness float64               `json:"min_brightness"`
+	Colours       []backlightFileConfig `json:"colours"`
+
+	Metric     string             `json:"metric"`
+	Source     string             `json:"source"`
+	IntervalMS int64              `json:"interval_ms"`
+	Root       string             `json:"root"`
+	Gradient   []fileGradientStop `json:"gradient"`
+}
+
+type fileGradientStop struct {
+	Value  float64              `json:"value"`
+	Colour *backlightFileConfig `json:"colour"`
+}
+
+type fileFlashEvent struct {
+	Colour     *backlightFileConfig `json:"colour"`
+	DurationMS int64                `json:"duration_ms"`
+}
+
+// GetBacklightEffects returns the backlight effect of the profile and the
+// flashes shown on events.
+func (cfg *G13Config) GetBacklightEffects() BacklightEffects {
+	eff := cfg.backlightEffects
+	if eff.Effect == nil {
+		eff.Effect = backlight.Static(cfg.backlight)
+	}
+	if eff.MinInterval == 0 {
+		eff.MinInterval = backlight.DefaultMinInterval
+	}
+	return eff
+}
+
+// GetBrightness returns the scale, from 0 to 1, of the brightness of every
+// backlight colour.
+func (cfg *G13Config) GetBrightness() float64 {
+	if cfg.brightness == 0 {
+		return 1
+	}
+	return cfg.brightness
+}
+
+// colour returns the colour, reading it if it's written as a string.
+func (c backlightFileConfig) colour() ([3]uint8, error) {
+	if c.text == nil {
+		return [3]uint8{c.Red, c.Green, c.Blue}, nil
+	}
+	return backlight.ParseColour(*c.text)
+}
+
+func loadBrightness(brightness *float64) (float64, error) {
+	if brightness == nil {
+		return 0, nil
+	}
+	if *brightness <= 0 || *brightness > 1 {
+		return 0, fmt.Errorf("invalid brightness %g: must be greater than 0 and at most 1", *brightness)
+	}
+	return *brightness, nil
+}
+
+func loadBacklightEffects(fileEff fileBacklightEffects, colour [3]uint8, cfgPath string) (BacklightEffects, error) {
+	if fileEff.FadeMS < 0 {
+		return BacklightEffects{}, fmt.Errorf("invalid backlight fade duration %d: must not be negative", fileEff.FadeMS)
+	}
+	if fileEff.MinIntervalMS < 0 {
+		return BacklightEffects{}, fmt.Errorf("invalid backlight minimum interval %d: must not be negative", fileEff.MinIntervalMS)
+	}
+
+	eff := BacklightEffects{
+		Fade:        time.Duration(fileEff.FadeMS) * time.Millisecond,
+		MinInterval: time.Duration(fileEff.MinIntervalMS) * time.Millisecond,
+	}
+
+	if fe := fileEff.Effect; fe != nil && fe.Type == "metric" {
+		metric, err := loadBacklightMetric(*fe, cfgPath)
+		if err != nil {
+			return BacklightEffects{}, fmt.Errorf("backlight effect: %w", err)
+		}
+		eff.Metric = metric
+	} else if fe != nil {
+		effect, err := loadBacklightEffect(*fe, colour)
+		if err != nil {
+			return BacklightEffects{}, fmt.Errorf("backlight effect: %w", err)
+		}
+		eff.Effect = effect
+	}
+
+	for event, ff := range fileEff.Flash {
+		switch event {
+		case FlashStickMode, FlashPage, FlashPause:
+		default:
+			return BacklightEffects{}, fmt.Errorf("unknown backlight flash event: %q", event)
+		}
+		if ff.Colour == nil {
+			return BacklightEffects{}, fmt.Errorf("backlight flash for %s: colour not set", event)
+		}
+		if ff.DurationMS < 0 {
+			return BacklightEffects{}, fmt.Errorf("backlight flash for %s: invalid duration %d: must not be negative", event, ff.DurationMS)
+		}
+		colour, err := ff.Colour.colour()
+		if err != nil {
+			return BacklightEffects{}, fmt.Errorf("backlight flash for %s: %w", event, err)
+		}
+		flash := backlight.Flash{
+			Colour:   colour,
+			Duration: DefaultFlashDuration,
+		}
+		if ff.DurationMS > 0 {
+			flash.Duration = time.Duration(ff.DurationMS) * time.Millisecond
+		}
+		if eff.Flashes == nil {
+			eff.Flashes = make(map[string]backlight.Flash, len(fileEff.Flash))
+		}
+		eff.Flashes[event] = flash
+	}
+	return eff, nil
+}
+
+// loadBacklightEffect reads the base effect of the backlight. Breathing uses
+// the configured backlight colour.
+func loadBacklightEffect(fe fileBacklightEffect, colour [3]uint8) (backlight.Effect, error) {
+	if fe.Metric != "" || fe.Source != "" || fe.IntervalMS != 0 || fe.Root != "" || len(fe.Gradient) != 0 {
+		return nil, fmt.Errorf("metric options set for %s effect", fe.Type)
+	}
+	period := time.Duration(fe.PeriodMS) * time.Millisecond
+	switch fe.Type {
+	case "static":
+		if fe.PeriodMS != 0 || fe.MinBrightness != 0 || len(fe.Colours) != 0 {
+			return nil, fmt.Errorf("options set for static effect")
+		}
+		return backlight.Static(colour), nil
+	case "breathing":
+		if fe.PeriodMS <= 0 {
+			return nil, fmt.Errorf("invalid period %d: must be positive", fe.PeriodMS)
+		}
+		if fe.MinBrightness < 0 || fe.MinBrightness > 1 {
+			return nil, fmt.Errorf("invalid minimum brightness %g: must be between 0 and 1", fe.MinBrightness)
+		}
+		if len(fe.Colours) != 0 {
+			return nil, fmt.Errorf("colours set for breathing effect")
+		}
+		return backlight.Breathing{Colour: colour, Period: period, Min: fe.MinBrightness}, nil
+	case "cycle":
+		if fe.PeriodMS <= 0 {
+			return nil, fmt.Errorf("invalid period %d: must be positive", fe.PeriodMS)
+		}
+		if len(fe.Colours) < 2 {
+			return nil, fmt.Errorf("colour cycle needs at least 2 colours")
+		}
+		if fe.MinBrightness != 0 {
+			return nil, fmt.Errorf("minimum brightness set for cycle effect")
+		}
+		colours := make([][3]uint8, 0, len(fe.Colours))
+		for idx, c := range fe.Colours {
+			colour, err := c.colour()
+			if err != nil {
+				return nil, fmt.Errorf("colour %d: %w", idx+1, err)
+			}
+			colours = append(colours, colour)
+		}
+		return backlight.Cycle{Colours: colours, Period: period}, nil
+	case "":
+		return nil, fmt.Errorf("type not set")
+	default:
+		return nil, fmt.Errorf("unknown type: %q", fe.Type)
+	}
+}
+
+// loadBacklightMetric reads a backlight colour that follows a statistic. The
+// root is relative to the config file.
+func loadBacklightMetric(fe fileBacklightEffect, cfgPath string) (*BacklightMetric, error) {
+	if fe.PeriodMS != 0 || fe.MinBrightness != 0 || len(fe.Colours) != 0 {
+		return nil, fmt.Errorf("options set for metric effect")
+	}
+	if fe.IntervalMS < 0 {
+		return nil, fmt.Errorf("invalid metric interval %d: must not be negative", fe.IntervalMS)
+	}
+
+	metric := &BacklightMetric{Source: fe.Source}
+	switch fe.Metric {
+	case "":
+		return nil, fmt.Errorf("metric not set")
+	case metricPushed:
+		if fe.Source != "" || fe.IntervalMS != 0 || fe.Root != "" {
+			return nil, fmt.Errorf("source, interval, and root set for pushed metric")
+		}
+		metric.Pushed = true
+	default:
+		var err error
+		if metric.Metric, err = sysmon.ParseMetric(fe.Metric); err != nil {
+			return nil, err
+		}
+		if metric.Metric == sysmon.MetricCPU && fe.Source != "" {
+			return nil, fmt.Errorf("source set for cpu metric")
+		}
+		metric.Interval = DefaultMetricInterval
+		if fe.IntervalMS > 0 {
+			metric.Interval = time.Duration(fe.IntervalMS) * time.Millisecond
+		}
+		if fe.Root != "" {
+			root, err := configRelativePath(cfgPath, fe.Root)
+			if err != nil {
+				return nil, err
+			}
+			metric.Root = root
+		}
+	}
+
+	if len(fe.Gradient) == 0 {
+		return nil, fmt.Errorf("metric gradient not set")
+	}
+	for idx, stop := range fe.Gradient {
+		if stop.Colour == nil {
+			return nil, fmt.Errorf("gradient stop %d: colour not set", idx+1)
+		}
+		if idx > 0 && stop.Value <= fe.Gradient[idx-1].Value {
+			return nil, fmt.Errorf("gradient stop %d: value %g must be greater than the value of the stop before it", idx+1, stop.Value)
+		}
+		colour, err := stop.Colour.colour()
+		if err != nil {
+			return nil, fmt.Errorf("gradient stop %d: %w", idx+1, err)
+		}
+		metric.Gradient = append(metric.Gradient, backlight.Stop{Value: stop.Value, Colour: colour})
+	}
+	return metric, nil
+}
@@ -9,10 +9,15 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/joystick"
 	"github.com/achilleas-k/gg13/internal/keyboard"
-	"golang.org/x/image/bmp"
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/achilleas-k/gg13/internal/radial"
+	"github.com/achilleas-k/gg13/internal/stick"
 )
 
 // G13Config maps G13 keys to uinput key codes.
@@ -22,8 +27,65 @@ type G13Config struct {
 	// backlight rgb
 	backlight [3]uint8
 
+	// scale of the brightness of every backlight colour (0 for full
+	// brightness)
+	brightness float64
+
+	// backlight effect and the flashes shown on events
+	backlightEffects BacklightEffects
+
+	// lock LEDs of the host's keyboards shown on the device (nil if not
+	// shown)
+	hostLEDs *HostLEDs
+
 	// path to image configured for the display
 	lcdImage string
+
+	// how the image is fitted to the display
+	lcdImageScale lcdimage.ScaleMode
+
+	// how the image is converted to black and white (nil for the default)
+	lcdImageConversion *lcdimage.Conversion
+
+	// animation played on the display (nil if none is configured)
+	animation *animationCfg
+
+	// text shown on the display (nil if none is configured)
+	text *textCfg
+
+	// pages shown on the display, switched between with page actions
+	pages []pageCfg
+
+	// socket and FIFO that other programs push content to the display
+	// through (empty if not enabled)
+	lcdInput lcdInputCfg
+
+	// how desktop notifications are shown (nil if they aren't)
+	notifications *Notifications
+
+	// media player shown by media widgets and controlled by media actions
+	media Media
+
+	// stopwatches and countdowns controlled by timer actions
+	timers Timers
+
+	// identity and capabilities of the virtual devices
+	virtualDevices virtualDevicesCfg
+}
+
+// Default names of the virtual devices.
+const (
+	DefaultKeyboardName = "g13-vkb"
+	DefaultMouseName    = "g13-vms"
+)
+
+type virtualDevicesCfg struct {
+	// names of the virtual keyboard and mouse (empty for the default)
+	keyboardName string
+	mouseName    string
+
+	// joystick settings (nil for the default preset)
+	joystick *joystick.Settings
 }
 
 type Mapping struct {
@@ -32,6 +94,18 @@ type Mapping struct {
 
 	// stick configuration and mapping
 	stick stickCfg
+
+	// radial menus opened by holding a trigger key
+	radialMenus []*radial.Menu
+
+	// actions bound to G keys
+	actions map[device.KeyBit]Action
+
+	// countdowns started when G keys are pressed
+	cooldowns map[device.KeyBit]Cooldown
+
+	// mapping from G keys to joystick buttons
+	buttonMap map[device.KeyBit]int
 }
 
 type keyMap map[device.KeyBit]int
@@ -43,11 +117,84 @@ const (
 	StickModeJoystick
 	StickModeKeys
 	StickModeMouse
+	StickModeScroll
+	StickModeHat
+	StickModeAbsolute
+)
+
+var stickModeNames = map[StickMode]string{
+	StickModeOff:      "off",
+	StickModeJoystick: "joystick",
+	StickModeKeys:     "keys",
+	StickModeMouse:    "mouse",
+	StickModeScroll:   "scroll",
+	StickModeHat:      "hat",
+	StickModeAbsolute: "absolute",
+}
+
+func (mode StickMode) String() string {
+	return stickModeNames[mode]
+}
+
+// ParseStickMode returns the [StickMode] with the given name. An empty name is
+// equivalent to "off".
+func ParseStickMode(name string) (StickMode, error) {
+	if name == "" {
+		return StickModeOff, nil
+	}
+	for mode, modeName := range stickModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return StickModeOff, fmt.Errorf("unknown stick mode: %s", name)
+}
+
+const (
+	// Distance from the centre (in either direction) that the stick must be
+	// moved before scrolling or pointer movement starts.
+	speedDeadzone = 32
+
+	// Default scrolling speed in wheel detents per second at full deflection.
+	defaultScrollSpeed = 10
+
+	// Default pointer speed in pixels per second at full deflection.
+	defaultMouseSpeed = 800
+
+	// Largest jitter that can be filtered out of the stick position. Anything
+	// larger would swallow deliberate movement.
+	maxStickJitter = 32
+
+	// Distance from the centre that the stick must be moved before a hat
+	// direction is activated.
+	hatDeadzone = 63
 )
 
 type stickCfg struct {
 	mode StickMode
 	keys StickKeys
+
+	// scrolling speed in detents per second at full deflection
+	scrollSpeed float32
+
+	// pointer speed in pixels per second at full deflection
+	mouseSpeed float32
+
+	// processing of the raw stick position before it's mapped
+	filter stick.Settings
+
+	// screen region covered by the stick in absolute mode (nil if the mode
+	// can't be reached)
+	absRegion *Region
+}
+
+// Region is a rectangular area of the screen in normalised coordinates, where
+// 0, 0 is the top left and 1, 1 the bottom right corner.
+type Region struct {
+	X      float32
+	Y      float32
+	Width  float32
+	Height float32
 }
 
 type StickKeys struct {
@@ -149,6 +296,200 @@ func (cfg *G13Config) GetStickPosition(input uint64) *StickPosition {
 	return &StickPosition{posX: x, posY: y}
 }
 
+// GetButtonStates returns the state of each mapped joystick button for the
+// given input (from [device.ReadInput]). The result maps a button code to a
+// state, true for down (pressed) and false for up (released).
+func (cfg *G13Config) GetButtonStates(input uint64) map[int]bool {
+	if len(cfg.mapping.buttonMap) == 0 {
+		return nil
+	}
+	buttons := make(map[int]bool, len(cfg.mapping.buttonMap))
+	for gkey, button := range cfg.mapping.buttonMap {
+		buttons[button] = buttons[button] || (gkey.Uint64()&input) != 0
+	}
+	return buttons
+}
+
+// GetScrollSpeed returns the horizontal and vertical scrolling speed, in wheel
+// detents per second, for the stick position in the given input. The speed is
+// proportional to the deflection of the stick outside the deadzone. Positive
+// values scroll right and up respectively. The last return value is false if
+// the stick is not in scroll mode.
+func (cfg *G13Config) GetScrollSpeed(input uint64) (float32, float32, bool) {
+	if cfg.mapping.stick.mode != StickModeScroll {
+		return 0, 0, false
+	}
+
+	x, y := device.StickPosition(input)
+	speed := cfg.mapping.stick.scrollSpeed
+	// the stick's y axis grows downwards but positive wheel movement scrolls
+	// up
+	return speed * deflection(x, speedDeadzone), -speed * deflection(y, speedDeadzone), true
+}
+
+// GetMouseSpeed returns the horizontal and vertical pointer speed, in pixels
+// per second, for the stick position in the given input. The speed is
+// proportional to the deflection of the stick outside the deadzone. The last
+// return value is false if the stick is not in mouse mode.
+func (cfg *G13Config) GetMouseSpeed(input uint64) (float32, float32, bool) {
+	if cfg.mapping.stick.mode != StickModeMouse {
+		return 0, 0, false
+	}
+
+	x, y := device.StickPosition(input)
+	speed := cfg.mapping.stick.mouseSpeed
+	return speed * deflection(x, speedDeadzone), speed * deflection(y, speedDeadzone), true
+}
+
+// GetAbsolutePosition returns the pointer position, in normalised screen
+// coordinates, for the stick position in the given input. The stick's range
+// covers the configured region, with the centre of the stick at the centre of
+// the region. The last return value is false if the stick is not in absolute
+// mode.
+func (cfg *G13Config) GetAbsolutePosition(input uint64) (float32, float32, bool) {
+	region := cfg.mapping.stick.absRegion
+	if cfg.mapping.stick.mode != StickModeAbsolute || region == nil {
+		return 0, 0, false
+	}
+
+	x, y := device.StickPosition(input)
+	pos := StickPosition{posX: x, posY: y}
+	ux := (min(pos.UinputX(), 1) + 1) / 2
+	uy := (min(pos.UinputY(), 1) + 1) / 2
+	return region.X + ux*region.Width, region.Y + uy*region.Height, true
+}
+
+// HasAbsoluteMode returns true if the stick can be in absolute mode, either
+// from the start or by switching to it with an action.
+func (cfg *G13Config) HasAbsoluteMode() bool {
+	return cfg.mapping.stick.absRegion != nil
+}
+
+// GetStickModes returns the modes the stick can be switched to: the current
+// mode, and the others that the config has the settings for, which are the
+// ones it starts in or switches to with actions. Modes that need no settings
+// are always included.
+func (cfg *G13Config) GetStickModes() []StickMode {
+	stickConfig := cfg.mapping.stick
+	var modes []StickMode
+	for mode := StickModeOff; mode <= StickModeAbsolute; mode++ {
+		configured := true
+		switch mode {
+		case StickModeScroll:
+			configured = stickConfig.scrollSpeed != 0
+		case StickModeMouse:
+			configured = stickConfig.mouseSpeed != 0
+		case StickModeKeys:
+			configured = stickConfig.keys != StickKeys{}
+		case StickModeAbsolute:
+			configured = stickConfig.absRegion != nil
+		}
+		if configured || mode == stickConfig.mode {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// GetStickMode returns the current stick mode.
+func (cfg *G13Config) GetStickMode() StickMode {
+	return cfg.mapping.stick.mode
+}
+
+// SetStickMode changes the stick mode.
+func (cfg *G13Config) SetStickMode(mode StickMode) {
+	cfg.mapping.stick.mode = mode
+}
+
+// GetStickKeys returns the keyboard keys mapped to the stick directions for
+// the keys mode.
+func (cfg *G13Config) GetStickKeys() StickKeys {
+	return cfg.mapping.stick.keys
+}
+
+// GetStickFilter returns the settings for processing the raw stick position
+// before it's mapped.
+func (cfg *G13Config) GetStickFilter() stick.Settings {
+	return cfg.mapping.stick.filter
+}
+
+// GetHatPosition returns the hat switch (D-pad) position for the stick
+// position in the given input. Each axis is -1 (left/up), 0 (centre), or 1
+// (right/down). The last return value is false if the stick is not in hat
+// mode.
+func (cfg *G13Config) GetHatPosition(input uint64) (int8, int8, bool) {
+	if cfg.mapping.stick.mode != StickModeHat {
+		return 0, 0, false
+	}
+
+	x, y := device.StickPosition(input)
+	return hatDirection(x), hatDirection(y), true
+}
+
+// deflection returns the position of a stick axis as a value between -1 and 1
+// scaled over the range outside the deadzone, or 0 when inside the deadzone.
+func deflection(pos uint8, deadzone int) float32 {
+	d := int(pos) - 127
+	switch {
+	case d > deadzone:
+		return min(float32(d-deadzone)/float32(127-deadzone), 1)
+	case d < -deadzone:
+		return max(float32(d+deadzone)/float32(127-deadzone), -1)
+	default:
+		return 0
+	}
+}
+
+func hatDirection(pos uint8) int8 {
+	switch d := int(pos) - 127; {
+	case d > hatDeadzone:
+		return 1
+	case d < -hatDeadzone:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// GetRadialMenus returns the configured radial menus.
+func (cfg *G13Config) GetRadialMenus() []*radial.Menu {
+	return cfg.mapping.radialMenus
+}
+
+// GetKeyboardName returns the name of the virtual keyboard.
+func (cfg *G13Config) GetKeyboardName() string {
+	if name := cfg.virtualDevices.keyboardName; name != "" {
+		return name
+	}
+	return DefaultKeyboardName
+}
+
+// GetMouseName returns the name of the virtual mouse.
+func (cfg *G13Config) GetMouseName() string {
+	if name := cfg.virtualDevices.mouseName; name != "" {
+		return name
+	}
+	return DefaultMouseName
+}
+
+// GetJoystickSettings returns the identity and capabilities of the virtual
+// joystick.
+func (cfg *G13Config) GetJoystickSettings() joystick.Settings {
+	return cfg.virtualDevices.joystickSettings()
+}
+
+func (vdevs virtualDevicesCfg) joystickSettings() joystick.Settings {
+	if js := vdevs.joystick; js != nil {
+		return *js
+	}
+	settings, err := joystick.Preset(joystick.DefaultPreset)
+	if err != nil {
+		// the default preset always exists
+		panic(err)
+	}
+	return settings
+}
+
 func (cfg *G13Config) GetBacklight() [3]uint8 {
 	return cfg.backlight
 }
@@ -162,34 +503,109 @@ func (cfg *G13Config) GetImage() (image.Image, error) {
 	if path == "" {
 		return nil, fmt.Errorf("no image file defined in config")
 	}
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open image file %q: %w", path, err)
-	}
-	img, err := bmp.Decode(file)
+	img, err := lcdimage.Load(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read image file %q: %w", path, err)
+		return nil, err
 	}
 
-	return img, nil
+	return cfg.GetImageConversion().Apply(lcdimage.Fit(img, cfg.lcdImageScale)), nil
+}
 
+// GetImageConversion returns the conversion applied to the configured image to
+// turn it black and white.
+func (cfg *G13Config) GetImageConversion() lcdimage.Conversion {
+	if conv := cfg.lcdImageConversion; conv != nil {
+		return *conv
+	}
+	return lcdimage.DefaultConversion()
 }
 
 // fileConfig describes the on-disk file format for the config file.
 type fileConfig struct {
-	Mapping   fileMapping         `json:"mapping"`
-	Backlight backlightFileConfig `json:"backlight"`
-	ImageFile string              `json:"image_file"`
+	Mapping         fileMapping          `json:"mapping"`
+	Backlight       backlightFileConfig  `json:"backlight"`
+	BacklightEffect fileBacklightEffects `json:"backlight_effects"`
+	Brightness      *float64             `json:"brightness"`
+	HostLEDs        *fileHostLEDs        `json:"host_leds"`
+	ImageFile       string               `json:"image_file"`
+	ImageScale      string               `json:"image_scale"`
+	ImageConversion *fileImageConversion `json:"image_conversion"`
+	Animation       *fileAnimation       `json:"animation"`
+	Text            *fileText            `json:"text"`
+	Pages           []fileLCDPage        `json:"pages"`
+	LCDInput        fileLCDInput         `json:"lcd_input"`
+	Notifications   *fileNotifications   `json:"notifications"`
+	Media           fileMedia            `json:"media"`
+	Timers          fileTimers           `json:"timers"`
+	VirtualDevices  fileVirtualDevices   `json:"virtual_devices"`
+}
+
+type fileVirtualDevices struct {
+	Keyboard fileVirtualDevice    `json:"keyboard"`
+	Mouse    fileVirtualDevice    `json:"mouse"`
+	Joystick *fileVirtualJoystick `json:"joystick"`
+}
+
+type fileVirtualDevice struct {
+	Name string `json:"name"`
+}
+
+type fileVirtualJoystick struct {
+	Preset  string  `json:"preset"`
+	Name    string  `json:"name"`
+	Vendor  *uint16 `json:"vendor"`
+	Product *uint16 `json:"product"`
+	Version *uint16 `json:"version"`
 }
 
 type fileMapping struct {
-	Keys  map[string]string `json:"keys"`
-	Stick fileStickConfig   `json:"stick"`
+	Keys      map[string]string       `json:"keys"`
+	Stick     fileStickConfig         `json:"stick"`
+	Radial    []fileRadialMenu        `json:"radial"`
+	Actions   map[string]fileAction   `json:"actions"`
+	Buttons   map[string]string       `json:"buttons"`
+	Cooldowns map[string]fileCooldown `json:"cooldowns"`
+}
+
+type fileRadialMenu struct {
+	Trigger string           `json:"trigger"`
+	Items   []fileRadialItem `json:"items"`
+}
+
+type fileRadialItem struct {
+	Label string   `json:"label"`
+	Keys  []string `json:"keys"`
 }
 
 type fileStickConfig struct {
-	Mode string           `json:"mode"`
-	Keys fileStickMapping `json:"keys"`
+	Mode   string           `json:"mode"`
+	Keys   fileStickMapping `json:"keys"`
+	Scroll fileStickSpeed   `json:"scroll"`
+	Mouse  fileStickSpeed   `json:"mouse"`
+	Filter fileStickFilter  `json:"filter"`
+
+	Absolute fileStickAbsolute `json:"absolute"`
+}
+
+type fileStickFilter struct {
+	Recenter    bool  `json:"recenter"`
+	SmoothingMS int64 `json:"smoothing_ms"`
+	Jitter      uint8 `json:"jitter"`
+}
+
+type fileStickAbsolute struct {
+	Region *fileRegion `json:"region"`
+}
+
+type fileRegion struct {
+	X      float32 `json:"x"`
+	Y      float32 `json:"y"`
+	Width  float32 `json:"width"`
+	Height float32 `json:"height"`
+}
+
+type fileStickSpeed struct {
+	Speed float32 `json:"speed"`
 }
 
 type fileStickMapping struct {
@@ -199,12 +615,6 @@ type fileStickMapping struct {
 	Right string `json:"Right"`
 }
 
-type backlightFileConfig struct {
-	Red   uint8 `json:"red"`
-	Green uint8 `json:"green"`
-	Blue  uint8 `json:"blue"`
-}
-
 func loadConfig(path string) (*G13Config, error) {
 	configFile, err := os.Open(path)
 	if err != nil {
@@ -232,43 +642,307 @@ func loadConfig(path string) (*G13Config, error) {
 		km[gKey] = kbKey
 	}
 
+	stickMode, err := ParseStickMode(cfg.Mapping.Stick.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	actions, err := loadActions(cfg.Mapping.Actions, km, cfg.Mapping.Buttons, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	// configure every mode the stick can be in, either from the start or by
+	// switching to it with an action
+	stickModes := map[StickMode]bool{stickMode: true}
+	for _, action := range actions {
+		for _, mode := range action.StickModes {
+			stickModes[mode] = true
+		}
+	}
+	stickConfig, err := loadStickConfig(cfg.Mapping.Stick, stickModes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	stickConfig.mode = stickMode
+
+	virtualDevices, err := loadVirtualDevices(cfg.VirtualDevices)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	buttonMap, err := loadButtonMap(cfg.Mapping.Buttons, km, virtualDevices.joystickSettings())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	// action and button keys can't be used as radial menu triggers
+	boundKeys := maps.Clone(km)
+	for gKey := range actions {
+		boundKeys[gKey] = 0
+	}
+	for gKey := range buttonMap {
+		boundKeys[gKey] = 0
+	}
+	radialMenus, err := loadRadialMenus(cfg.Mapping.Radial, boundKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	backlight, err := cfg.Backlight.colour()
+	if err != nil {
+		return nil, fmt.Errorf("%s: backlight: %w", errPrefix, err)
+	}
+
+	imageFile := cfg.ImageFile
+
+	if imageFile != "" {
+		imageFile, err = configRelativePath(path, imageFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// Check if the image file exists and is stat-able if it's set; no
+		// need for any extra validation right now
+		_, err := os.Stat(imageFile)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: image file %q (%s) set in config file does not exist", errPrefix, cfg.ImageFile, imageFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errPrefix, err)
+		}
+	}
+
+	imageScale, err := lcdimage.ParseScaleMode(cfg.ImageScale)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	imageConversion, err := loadImageConversion(cfg.ImageConversion)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	anim, err := loadAnimation(cfg.Animation, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	text, err := loadText(cfg.Text, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	pages, err := loadPages(cfg.Pages, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	if nContent := countTrue(imageFile != "", anim != nil, text != nil, len(pages) > 0); nContent > 1 {
+		return nil, fmt.Errorf("%s: only one of image file, animation, text, and pages can be set", errPrefix)
+	}
+	if len(pages) > 0 {
+		actions = addDefaultPageActions(actions, boundKeys, radialMenus)
+	}
+	lcdInput, err := loadLCDInput(cfg.LCDInput, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	notifications, err := loadNotifications(cfg.Notifications)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	timerCfg, err := loadTimers(cfg.Timers)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	if err := checkTimerActions(actions, timerCfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	cooldowns, err := loadCooldowns(cfg.Mapping.Cooldowns, timerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	backlightEffects, err := loadBacklightEffects(cfg.BacklightEffect, backlight, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	if metric := backlightEffects.Metric; metric != nil && metric.Pushed && lcdInput.socket == "" && lcdInput.fifo == "" {
+		return nil, fmt.Errorf("%s: pushed backlight metric set without an LCD input socket or FIFO", errPrefix)
+	}
+	brightness, err := loadBrightness(cfg.Brightness)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	hostLEDs, err := loadHostLEDs(cfg.HostLEDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	return &G13Config{
+		mapping: Mapping{
+			keyMap:      km,
+			stick:       stickConfig,
+			radialMenus: radialMenus,
+			actions:     actions,
+			buttonMap:   buttonMap,
+			cooldowns:   cooldowns,
+		},
+		backlight:          backlight,
+		backlightEffects:   backlightEffects,
+		brightness:         brightness,
+		hostLEDs:           hostLEDs,
+		lcdImage:           imageFile,
+		lcdImageScale:      imageScale,
+		lcdImageConversion: imageConversion,
+		animation:          anim,
+		text:               text,
+		pages:              pages,
+		lcdInput:           lcdInput,
+		notifications:      notifications,
+		media:              loadMedia(cfg.Media),
+		timers:             timerCfg,
+		virtualDevices:     virtualDevices,
+	}, nil
+}
+
+// configRelativePath returns the absolute path of a file referenced by the
+// config file at cfgPath. Relative paths are relative to the directory of the
+// config file.
+func configRelativePath(cfgPath, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	cfgDir, err := filepath.Abs(filepath.Dir(cfgPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path of config file %q: %w", cfgPath, err)
+	}
+	return filepath.Clean(filepath.Join(cfgDir, path)), nil
+}
+
+func loadRadialMenus(fileMenus []fileRadialMenu, km keyMap) ([]*radial.Menu, error) {
+	if len(fileMenus) == 0 {
+		return nil, nil
+	}
+
+	menus := make([]*radial.Menu, 0, len(fileMenus))
+	triggers := make(map[device.KeyBit]bool, len(fileMenus))
+	for _, fileMenu := range fileMenus {
+		trigger := device.KeyCode(fileMenu.Trigger)
+		if trigger == 0 {
+			return nil, fmt.Errorf("unknown G13 key name for radial menu trigger: %s", fileMenu.Trigger)
+		}
+		if _, mapped := km[trigger]; mapped {
+			return nil, fmt.Errorf("radial menu trigger %s is also mapped to a keyboard key or action", fileMenu.Trigger)
+		}
+		if triggers[trigger] {
+			return nil, fmt.Errorf("radial menu trigger %s used for more than one menu", fileMenu.Trigger)
+		}
+		triggers[trigger] = true
+
+		if len(fileMenu.Items) == 0 {
+			return nil, fmt.Errorf("radial menu with trigger %s has no items", fileMenu.Trigger)
+		}
+
+		menu := &radial.Menu{
+			Trigger: trigger,
+			Items:   make([]radial.Item, 0, len(fileMenu.Items)),
+		}
+		for idx, fileItem := range fileMenu.Items {
+			if len(fileItem.Keys) == 0 {
+				return nil, fmt.Errorf("radial menu with trigger %s: item %d has no keys", fileMenu.Trigger, idx)
+			}
+			keys := make([]int, 0, len(fileItem.Keys))
+			for _, kbKeyStr := range fileItem.Keys {
+				kbKey := keyboard.KeyCode(kbKeyStr)
+				if kbKey == 0 {
+					return nil, fmt.Errorf("unknown keyboard key name: %s", kbKeyStr)
+				}
+				keys = append(keys, kbKey)
+			}
+
+			label := fileItem.Label
+			if label == "" {
+				label = strings.Join(fileItem.Keys, "+")
+			}
+			menu.Items = append(menu.Items, radial.Item{Label: label, Keys: keys})
+		}
+		menus = append(menus, menu)
+	}
+	return menus, nil
+}
+
+func loadStickConfig(stickFile fileStickConfig, modes map[StickMode]bool) (stickCfg, error) {
 	stickConfig := stickCfg{}
-	switch stick := cfg.Mapping.Stick; stick.Mode {
-	case "":
-		stickConfig.mode = StickModeOff
-	case "joystick":
-		stickConfig.mode = StickModeJoystick
-	case "mouse":
-		return nil, fmt.Errorf("stick mode 'mouse' not yet supported")
-	case "keys":
-		stickConfig.mode = StickModeKeys
 
+	filter := stickFile.Filter
+	if filter.SmoothingMS < 0 {
+		return stickCfg{}, fmt.Errorf("invalid stick smoothing %d: must be positive", filter.SmoothingMS)
+	}
+	if filter.Jitter > maxStickJitter {
+		return stickCfg{}, fmt.Errorf("invalid stick jitter %d: must be at most %d", filter.Jitter, maxStickJitter)
+	}
+	stickConfig.filter = stick.Settings{
+		Recenter:  filter.Recenter,
+		Smoothing: time.Duration(filter.SmoothingMS) * time.Millisecond,
+		Jitter:    filter.Jitter,
+	}
+
+	if modes[StickModeScroll] {
+		stickConfig.scrollSpeed = defaultScrollSpeed
+		if speed := stickFile.Scroll.Speed; speed != 0 {
+			if speed < 0 {
+				return stickCfg{}, fmt.Errorf("invalid scroll speed %v: must be positive", speed)
+			}
+			stickConfig.scrollSpeed = speed
+		}
+	}
+
+	if modes[StickModeMouse] {
+		stickConfig.mouseSpeed = defaultMouseSpeed
+		if speed := stickFile.Mouse.Speed; speed != 0 {
+			if speed < 0 {
+				return stickCfg{}, fmt.Errorf("invalid mouse speed %v: must be positive", speed)
+			}
+			stickConfig.mouseSpeed = speed
+		}
+	}
+
+	if modes[StickModeAbsolute] {
+		region := Region{X: 0, Y: 0, Width: 1, Height: 1}
+		if r := stickFile.Absolute.Region; r != nil {
+			region = Region{X: r.X, Y: r.Y, Width: r.Width, Height: r.Height}
+			if region.X < 0 || region.Y < 0 || region.Width <= 0 || region.Height <= 0 ||
+				region.X+region.Width > 1 || region.Y+region.Height > 1 {
+				return stickCfg{}, fmt.Errorf("invalid absolute region %+v: must be within the screen (0 to 1) with a positive size", *r)
+			}
+		}
+		stickConfig.absRegion = &region
+	}
+
+	if modes[StickModeKeys] {
 		var up, down, left, right int
-		if stick.Keys.Up != "" {
-			up = keyboard.KeyCode(stick.Keys.Up)
+		if stickFile.Keys.Up != "" {
+			up = keyboard.KeyCode(stickFile.Keys.Up)
 			if up == 0 {
-				return nil, fmt.Errorf("%s: unknown keyboard key name: %s", errPrefix, stick.Keys.Up)
+				return stickCfg{}, fmt.Errorf("unknown keyboard key name: %s", stickFile.Keys.Up)
 			}
 		}
 
-		if stick.Keys.Down != "" {
-			down = keyboard.KeyCode(stick.Keys.Down)
+		if stickFile.Keys.Down != "" {
+			down = keyboard.KeyCode(stickFile.Keys.Down)
 			if down == 0 {
-				return nil, fmt.Errorf("%s: unknown keyboard key name: %s", errPrefix, stick.Keys.Down)
+				return stickCfg{}, fmt.Errorf("unknown keyboard key name: %s", stickFile.Keys.Down)
 			}
 		}
 
-		if stick.Keys.Left != "" {
-			left = keyboard.KeyCode(stick.Keys.Left)
+		if stickFile.Keys.Left != "" {
+			left = keyboard.KeyCode(stickFile.Keys.Left)
 			if left == 0 {
-				return nil, fmt.Errorf("%s: unknown keyboard key name: %s", errPrefix, stick.Keys.Left)
+				return stickCfg{}, fmt.Errorf("unknown keyboard key name: %s", stickFile.Keys.Left)
 			}
 		}
 
-		if stick.Keys.Right != "" {
-			right = keyboard.KeyCode(stick.Keys.Right)
+		if stickFile.Keys.Right != "" {
+			right = keyboard.KeyCode(stickFile.Keys.Right)
 			if right == 0 {
-				return nil, fmt.Errorf("%s: unknown keyboard key name: %s", errPrefix, stick.Keys.Right)
+				return stickCfg{}, fmt.Errorf("unknown keyboard key name: %s", stickFile.Keys.Right)
 			}
 		}
 		stickConfig.keys = StickKeys{
@@ -277,42 +951,67 @@ func loadConfig(path string) (*G13Config, error) {
 			Left:  left,
 			Right: right,
 		}
-	default:
-		return nil, fmt.Errorf("%s: unknown stick mode: %s", errPrefix, stick.Mode)
 	}
+	return stickConfig, nil
+}
 
-	backlight := [3]uint8{cfg.Backlight.Red, cfg.Backlight.Green, cfg.Backlight.Blue}
+func loadVirtualDevices(fileDevs fileVirtualDevices) (virtualDevicesCfg, error) {
+	vdevs := virtualDevicesCfg{
+		keyboardName: fileDevs.Keyboard.Name,
+		mouseName:    fileDevs.Mouse.Name,
+	}
 
-	imageFile := cfg.ImageFile
+	fileJS := fileDevs.Joystick
+	if fileJS == nil {
+		return vdevs, nil
+	}
 
-	if imageFile != "" {
-		// The image file, if defined, should be relative to the config file
-		// (unless it's already absolute)
-		if !filepath.IsAbs(imageFile) {
-			cfgDir, err := filepath.Abs(filepath.Dir(path))
-			if err != nil {
-				return nil, fmt.Errorf("failed to get absolute path of config file %q: %w", path, err)
-			}
-			imageFile = filepath.Clean(filepath.Join(cfgDir, imageFile))
-		}
+	presetName := fileJS.Preset
+	if presetName == "" {
+		presetName = joystick.DefaultPreset
+	}
+	settings, err := joystick.Preset(presetName)
+	if err != nil {
+		return virtualDevicesCfg{}, err
+	}
+	if fileJS.Name != "" {
+		settings.Name = fileJS.Name
+	}
+	if fileJS.Vendor != nil {
+		settings.Vendor = *fileJS.Vendor
+	}
+	if fileJS.Product != nil {
+		settings.Product = *fileJS.Product
+	}
+	if fileJS.Version != nil {
+		settings.Version = *fileJS.Version
+	}
+	vdevs.joystick = &settings
+	return vdevs, nil
+}
 
-		// Check if the image file exists and is stat-able if it's set; no
-		// need for any extra validation right now
-		_, err := os.Stat(imageFile)
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("%s: image file %q (%s) set in config file does not exist", errPrefix, cfg.ImageFile, imageFile)
+func loadButtonMap(fileButtons map[string]string, km keyMap, settings joystick.Settings) (map[device.KeyBit]int, error) {
+	if len(fileButtons) == 0 {
+		return nil, nil
+	}
+
+	buttonMap := make(map[device.KeyBit]int, len(fileButtons))
+	for gKeyStr, buttonStr := range fileButtons {
+		gKey := device.KeyCode(gKeyStr)
+		if gKey == 0 {
+			return nil, fmt.Errorf("unknown G13 key name: %s", gKeyStr)
 		}
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", errPrefix, err)
+		if _, mapped := km[gKey]; mapped {
+			return nil, fmt.Errorf("key %s mapped to a joystick button is also mapped to a keyboard key", gKeyStr)
+		}
+		button := joystick.ButtonCode(buttonStr)
+		if button == 0 {
+			return nil, fmt.Errorf("unknown joystick button name: %s", buttonStr)
 		}
+		if !settings.HasButton(button) {
+			return nil, fmt.Errorf("joystick button %s is not available on joystick %q", buttonStr, settings.Name)
+		}
+		buttonMap[gKey] = button
 	}
-
-	return &G13Config{
-		mapping: Mapping{
-			keyMap: km,
-			stick:  stickConfig,
-		},
-		backlight: backlight,
-		lcdImage:  imageFile,
-	}, nil
+	return buttonMap, nil
 }
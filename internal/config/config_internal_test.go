@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/radial"
+	"github.com/achilleas-k/gg13/internal/stick"
 	"github.com/bendahl/uinput"
 	"github.com/stretchr/testify/assert"
 )
@@ -184,6 +187,105 @@ func TestLoadConfig(t *testing.T) {
 				lcdImage:  "here.bmp",
 			},
 		},
+		"stick-scroll-default-speed": {
+			configData: `{"mapping":{"stick":{"mode":"scroll"}}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{},
+					stick: stickCfg{
+						mode:        StickModeScroll,
+						scrollSpeed: defaultScrollSpeed,
+					},
+				},
+			},
+		},
+		"stick-scroll": {
+			configData: `{"mapping":{"stick":{"mode":"scroll","scroll":{"speed":4.5}}}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{},
+					stick: stickCfg{
+						mode:        StickModeScroll,
+						scrollSpeed: 4.5,
+					},
+				},
+			},
+		},
+		"stick-hat": {
+			configData: `{"mapping":{"stick":{"mode":"hat"}}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{},
+					stick: stickCfg{
+						mode: StickModeHat,
+					},
+				},
+			},
+		},
+		"radial": {
+			configData: `{"mapping":{"keys":{"G1":"Key1"},"radial":[{"trigger":"G22","items":[{"label":"Wave","keys":["KeyLeftctrl","Key1"]},{"keys":["KeyF5"]}]}]}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{
+						device.G1: uinput.Key1,
+					},
+					radialMenus: []*radial.Menu{
+						{
+							Trigger: device.G22,
+							Items: []radial.Item{
+								{Label: "Wave", Keys: []int{uinput.KeyLeftctrl, uinput.Key1}},
+								{Label: "KeyF5", Keys: []int{uinput.KeyF5}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"stick-mode-actions": {
+			configData: `{"mapping":{"stick":{"mode":"joystick"},"actions":{"BD":{"type":"stick-mode","mode":"cycle","modes":["joystick","mouse"]},"L4":{"type":"stick-mode","mode":"scroll"}}}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{},
+					stick: stickCfg{
+						mode:        StickModeJoystick,
+						mouseSpeed:  defaultMouseSpeed,
+						scrollSpeed: defaultScrollSpeed,
+					},
+					actions: map[device.KeyBit]Action{
+						device.BD: {Type: ActionStickMode, StickModes: []StickMode{StickModeJoystick, StickModeMouse}},
+						device.L4: {Type: ActionStickMode, StickModes: []StickMode{StickModeScroll}},
+					},
+				},
+			},
+		},
+		"stick-filter": {
+			configData: `{"mapping":{"stick":{"mode":"joystick","filter":{"recenter":true,"smoothing_ms":40,"jitter":3}}}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{},
+					stick: stickCfg{
+						mode: StickModeJoystick,
+						filter: stick.Settings{
+							Recenter:  true,
+							Smoothing: 40 * time.Millisecond,
+							Jitter:    3,
+						},
+					},
+				},
+			},
+		},
+		"stick-absolute": {
+			configData: `{"mapping":{"stick":{"mode":"absolute","absolute":{"region":{"x":0.1,"y":0.2,"width":0.3,"height":0.4}}}}}`,
+			expectedConfig: G13Config{
+				mapping: Mapping{
+					keyMap: map[device.KeyBit]int{},
+					stick: stickCfg{
+						mode:      StickModeAbsolute,
+						absRegion: &Region{X: 0.1, Y: 0.2, Width: 0.3, Height: 0.4},
+					},
+				},
+			},
+		},
 		"stick-keys-ignored": { // stick keys are ignored when the mode is not "keys"
 			configData: `{"mapping":{"stick":{"mode":"","keys":{"Up":"not-a-key-but-ignored"}}}}`,
 			expectedConfig: G13Config{
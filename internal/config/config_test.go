@@ -1,12 +1,26 @@
 package config_test
 
 import (
+	"fmt"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/achilleas-k/gg13/internal/backlight"
 	"github.com/achilleas-k/gg13/internal/config"
 	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/hostleds"
+	"github.com/achilleas-k/gg13/internal/joystick"
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/achilleas-k/gg13/internal/lcdpage"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/achilleas-k/gg13/internal/notify"
+	"github.com/achilleas-k/gg13/internal/sysmon"
+	"github.com/achilleas-k/gg13/internal/timers"
 	"github.com/bendahl/uinput"
 	"github.com/stretchr/testify/assert"
 )
@@ -285,35 +299,1393 @@ func TestNewFromFileError(t *testing.T) {
 		assert.ErrorContains(err, "set in config file does not exist")
 	})
 
+	t.Run("bad-image-scale", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+
+		err := os.WriteFile(cfgPath, []byte(`{"image_scale":"stretch"}`), 0o660)
+		assert.NoError(err)
+
+		_, err = config.NewFromFile(cfgPath)
+		assert.EqualError(err, "failed reading config file: unknown image scale mode: stretch")
+	})
+
+	t.Run("bad-image-conversion", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+
+		err := os.WriteFile(cfgPath, []byte(`{"image_conversion":{"mode":"halftone"}}`), 0o660)
+		assert.NoError(err)
+
+		_, err = config.NewFromFile(cfgPath)
+		assert.EqualError(err, "failed reading config file: unknown image conversion mode: halftone")
+	})
+
+	t.Run("bad-animation", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"no-path": {
+				configData:  `{"animation":{"fps":10}}`,
+				expectedErr: "failed reading config file: animation path not set",
+			},
+			"negative-fps": {
+				configData:  `{"animation":{"path":".","fps":-1}}`,
+				expectedErr: "failed reading config file: invalid animation frame rate -1: must not be negative",
+			},
+			"negative-loops": {
+				configData:  `{"animation":{"path":".","loops":-2}}`,
+				expectedErr: "failed reading config file: invalid animation loop count -2: must not be negative",
+			},
+			"bad-scale": {
+				configData:  `{"animation":{"path":".","scale":"stretch"}}`,
+				expectedErr: "failed reading config file: unknown image scale mode: stretch",
+			},
+			"bad-conversion": {
+				configData:  `{"animation":{"path":".","conversion":{"mode":"halftone"}}}`,
+				expectedErr: "failed reading config file: unknown image conversion mode: halftone",
+			},
+			"and-image": {
+				configData:  `{"animation":{"path":"."},"image_file":"mapping.json"}`,
+				expectedErr: "failed reading config file: only one of image file, animation, text, and pages can be set",
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				err := os.WriteFile(cfgPath, []byte(tc.configData), 0o660)
+				assert.NoError(err)
+
+				_, err = config.NewFromFile(cfgPath)
+				assert.EqualError(err, tc.expectedErr)
+			})
+		}
+
+		t.Run("does-not-exist", func(t *testing.T) {
+			assert := assert.New(t)
+
+			tmpdir := t.TempDir()
+			cfgPath := filepath.Join(tmpdir, "mapping.json")
+			err := os.WriteFile(cfgPath, []byte(`{"animation":{"path":"frames"}}`), 0o660)
+			assert.NoError(err)
+
+			_, err = config.NewFromFile(cfgPath)
+			assert.ErrorContains(err, "failed reading config file: animation \"frames\"")
+			assert.ErrorContains(err, "set in config file does not exist")
+		})
+	})
+
+	t.Run("bad-text", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"no-lines": {
+				configData:  `{"text":{"align":"center"}}`,
+				expectedErr: "failed reading config file: text lines not set",
+			},
+			"bad-align": {
+				configData:  `{"text":{"lines":["hi"],"align":"justify"}}`,
+				expectedErr: "failed reading config file: unknown text alignment: justify",
+			},
+			"negative-size": {
+				configData:  `{"text":{"lines":["hi"],"font_size":-3}}`,
+				expectedErr: "failed reading config file: invalid font size -3: must not be negative",
+			},
+			"negative-speed": {
+				configData:  `{"text":{"lines":["hi"],"marquee_speed":-3}}`,
+				expectedErr: "failed reading config file: invalid marquee speed -3: must not be negative",
+			},
+			"and-animation": {
+				configData:  `{"text":{"lines":["hi"]},"animation":{"path":"."}}`,
+				expectedErr: "failed reading config file: only one of image file, animation, text, and pages can be set",
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				err := os.WriteFile(cfgPath, []byte(tc.configData), 0o660)
+				assert.NoError(err)
+
+				_, err = config.NewFromFile(cfgPath)
+				assert.EqualError(err, tc.expectedErr)
+			})
+		}
+
+		t.Run("font-does-not-exist", func(t *testing.T) {
+			assert := assert.New(t)
+
+			tmpdir := t.TempDir()
+			cfgPath := filepath.Join(tmpdir, "mapping.json")
+			err := os.WriteFile(cfgPath, []byte(`{"text":{"lines":["hi"],"font":"fonts/nope.ttf"}}`), 0o660)
+			assert.NoError(err)
+
+			_, err = config.NewFromFile(cfgPath)
+			assert.ErrorContains(err, "failed reading config file: font file \"fonts/nope.ttf\"")
+			assert.ErrorContains(err, "set in config file does not exist")
+		})
+	})
+
+	t.Run("bad-pages", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"empty-page": {
+				configData:  `{"pages":[{"name":"nothing"}]}`,
+				expectedErr: "failed reading config file: page 1: exactly one of image, animation, text, clock, monitor, keymap, and terminal must be set",
+			},
+			"two-contents": {
+				configData:  `{"pages":[{"clock":{}},{"clock":{},"text":{"lines":["hi"]}}]}`,
+				expectedErr: "failed reading config file: page 2: exactly one of image, animation, text, clock, monitor, keymap, and terminal must be set",
+			},
+			"no-image-path": {
+				configData:  `{"pages":[{"image":{"scale":"fit"}}]}`,
+				expectedErr: "failed reading config file: page 1: image path not set",
+			},
+			"bad-clock-align": {
+				configData:  `{"pages":[{"clock":{"align":"top"}}]}`,
+				expectedErr: "failed reading config file: page 1: unknown text alignment: top",
+			},
+			"no-terminal-command": {
+				configData:  `{"pages":[{"terminal":{"interval_ms":1000}}]}`,
+				expectedErr: "failed reading config file: page 1: terminal command not set",
+			},
+			"negative-terminal-interval": {
+				configData:  `{"pages":[{"terminal":{"command":["date"],"interval_ms":-1}}]}`,
+				expectedErr: "failed reading config file: page 1: invalid terminal interval -1: must not be negative",
+			},
+			"no-widgets": {
+				configData:  `{"pages":[{"monitor":{"interval_ms":1000}}]}`,
+				expectedErr: "failed reading config file: page 1: monitor widgets not set",
+			},
+			"bad-widget-type": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"cpu"},{"type":"gpu"}]}}]}`,
+				expectedErr: "failed reading config file: page 1: widget 2: unknown widget type: \"gpu\"",
+			},
+			"negative-interval": {
+				configData:  `{"pages":[{"monitor":{"interval_ms":-1,"widgets":[{"type":"cpu"}]}}]}`,
+				expectedErr: "failed reading config file: page 1: invalid monitor interval -1: must not be negative",
+			},
+			"widget-source": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"memory","source":"eth0"}]}}]}`,
+				expectedErr: "failed reading config file: page 1: widget 1: source set for memory widget",
+			},
+			"media-widget-source": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"media","source":"spotify"}]}}]}`,
+				expectedErr: "failed reading config file: page 1: widget 1: source set for media widget",
+			},
+			"widget-format": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"load","format":"15:04"}]}}]}`,
+				expectedErr: "failed reading config file: page 1: widget 1: format set for load widget",
+			},
+			"widget-off-screen": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"cpu","x":100,"width":80,"height":8}]}}]}`,
+				expectedErr: "failed reading config file: page 1: widget 1: invalid widget area (100,0)-(180,8): must have a size and fit on the 160x43 display",
+			},
+			"widget-no-size": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"cpu","x":10,"y":10}]}}]}`,
+				expectedErr: "failed reading config file: page 1: widget 1: invalid widget area (10,10)-(10,10): must have a size and fit on the 160x43 display",
+			},
+			"too-many-widgets": {
+				configData:  `{"pages":[{"monitor":{"widgets":[{"type":"cpu"},{"type":"memory"},{"type":"load"},{"type":"network"},{"type":"disk"},{"type":"temperature"}]}}]}`,
+				expectedErr: "failed reading config file: page 1: too many monitor widgets without a size: 6 (at most 5 fit)",
+			},
+			"bad-text": {
+				configData:  `{"pages":[{"text":{}}]}`,
+				expectedErr: "failed reading config file: page 1: text lines not set",
+			},
+			"and-text": {
+				configData:  `{"pages":[{"clock":{}}],"text":{"lines":["hi"]}}`,
+				expectedErr: "failed reading config file: only one of image file, animation, text, and pages can be set",
+			},
+			"page-action-with-mode": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"page-next","mode":"mouse"}}}}`,
+				expectedErr: "failed reading config file: action for key L4: stick mode set for page-next action",
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				err := os.WriteFile(cfgPath, []byte(tc.configData), 0o660)
+				assert.NoError(err)
+
+				_, err = config.NewFromFile(cfgPath)
+				assert.EqualError(err, tc.expectedErr)
+			})
+		}
+
+		t.Run("image-does-not-exist", func(t *testing.T) {
+			assert := assert.New(t)
+
+			tmpdir := t.TempDir()
+			cfgPath := filepath.Join(tmpdir, "mapping.json")
+			err := os.WriteFile(cfgPath, []byte(`{"pages":[{"image":{"path":"nope.png"}}]}`), 0o660)
+			assert.NoError(err)
+
+			_, err = config.NewFromFile(cfgPath)
+			assert.ErrorContains(err, "failed reading config file: page 1: image file \"nope.png\"")
+		})
+	})
+
 	t.Run("bad-stick-mode", func(t *testing.T) {
 		assert := assert.New(t)
 
 		tmpdir := t.TempDir()
 		cfgPath := filepath.Join(tmpdir, "mapping.json")
-		err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"bad"}}}`), 0o660)
+		err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"bad"}}}`), 0o660)
+		assert.NoError(err)
+
+		_, err = config.NewFromFile(cfgPath)
+		assert.ErrorContains(err, "unknown stick mode: bad")
+	})
+
+	t.Run("bad-scroll-speed", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"scroll","scroll":{"speed":-2}}}}`), 0o660)
+		assert.NoError(err)
+
+		_, err = config.NewFromFile(cfgPath)
+		assert.EqualError(err, "failed reading config file: invalid scroll speed -2: must be positive")
+	})
+
+	t.Run("bad-radial", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"bad-trigger": {
+				configData:  `{"mapping":{"radial":[{"trigger":"G30","items":[{"keys":["KeyA"]}]}]}}`,
+				expectedErr: "failed reading config file: unknown G13 key name for radial menu trigger: G30",
+			},
+			"mapped-trigger": {
+				configData:  `{"mapping":{"keys":{"G1":"KeyA"},"radial":[{"trigger":"G1","items":[{"keys":["KeyA"]}]}]}}`,
+				expectedErr: "failed reading config file: radial menu trigger G1 is also mapped to a keyboard key or action",
+			},
+			"duplicate-trigger": {
+				configData:  `{"mapping":{"radial":[{"trigger":"G1","items":[{"keys":["KeyA"]}]},{"trigger":"G1","items":[{"keys":["KeyB"]}]}]}}`,
+				expectedErr: "failed reading config file: radial menu trigger G1 used for more than one menu",
+			},
+			"action-trigger": {
+				configData:  `{"mapping":{"actions":{"G1":{"type":"stick-mode","mode":"cycle"}},"radial":[{"trigger":"G1","items":[{"keys":["KeyA"]}]}]}}`,
+				expectedErr: "failed reading config file: radial menu trigger G1 is also mapped to a keyboard key or action",
+			},
+			"no-items": {
+				configData:  `{"mapping":{"radial":[{"trigger":"G1"}]}}`,
+				expectedErr: "failed reading config file: radial menu with trigger G1 has no items",
+			},
+			"no-keys": {
+				configData:  `{"mapping":{"radial":[{"trigger":"G1","items":[{"label":"nothing"}]}]}}`,
+				expectedErr: "failed reading config file: radial menu with trigger G1: item 0 has no keys",
+			},
+			"bad-key": {
+				configData:  `{"mapping":{"radial":[{"trigger":"G1","items":[{"keys":["KeyNope"]}]}]}}`,
+				expectedErr: "failed reading config file: unknown keyboard key name: KeyNope",
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				err := os.WriteFile(cfgPath, []byte(tc.configData), 0o660)
+				assert.NoError(err)
+
+				_, err = config.NewFromFile(cfgPath)
+				assert.EqualError(err, tc.expectedErr)
+			})
+		}
+	})
+
+	t.Run("bad-actions", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"bad-key": {
+				configData:  `{"mapping":{"actions":{"G0":{"type":"stick-mode","mode":"cycle"}}}}`,
+				expectedErr: "failed reading config file: unknown G13 key name: G0",
+			},
+			"mapped-key": {
+				configData:  `{"mapping":{"keys":{"BD":"KeyA"},"actions":{"BD":{"type":"stick-mode","mode":"cycle"}}}}`,
+				expectedErr: "failed reading config file: key BD bound to an action is also mapped to a keyboard key",
+			},
+			"no-type": {
+				configData:  `{"mapping":{"actions":{"BD":{"mode":"cycle"}}}}`,
+				expectedErr: "failed reading config file: action for key BD: action type not set",
+			},
+			"bad-type": {
+				configData:  `{"mapping":{"actions":{"BD":{"type":"explode"}}}}`,
+				expectedErr: "failed reading config file: action for key BD: unknown action type: explode",
+			},
+			"no-mode": {
+				configData:  `{"mapping":{"actions":{"BD":{"type":"stick-mode"}}}}`,
+				expectedErr: "failed reading config file: action for key BD: stick mode not set",
+			},
+			"bad-mode": {
+				configData:  `{"mapping":{"actions":{"BD":{"type":"stick-mode","mode":"wiggle"}}}}`,
+				expectedErr: "failed reading config file: action for key BD: unknown stick mode: wiggle",
+			},
+			"bad-cycle-mode": {
+				configData:  `{"mapping":{"actions":{"BD":{"type":"stick-mode","mode":"cycle","modes":["mouse","wiggle"]}}}}`,
+				expectedErr: "failed reading config file: action for key BD: unknown stick mode: wiggle",
+			},
+			"modes-without-cycle": {
+				configData:  `{"mapping":{"actions":{"BD":{"type":"stick-mode","mode":"mouse","modes":["mouse","off"]}}}}`,
+				expectedErr: "failed reading config file: action for key BD: stick modes to cycle through set for non-cycle mode \"mouse\"",
+			},
+			"bad-stick-key-for-reachable-mode": {
+				configData:  `{"mapping":{"stick":{"mode":"off","keys":{"Up":"up"}},"actions":{"BD":{"type":"stick-mode","mode":"keys"}}}}`,
+				expectedErr: "failed reading config file: unknown keyboard key name: up",
+			},
+			"backlight-no-colour": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"backlight"}}}}`,
+				expectedErr: "failed reading config file: action for key L4: backlight colour not set",
+			},
+			"colour-for-menu": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"menu","colour":{"red":255}}}}}`,
+				expectedErr: "failed reading config file: action for key L4: colour set for menu action",
+			},
+			"profile-not-set": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"profile"}}}}`,
+				expectedErr: "failed reading config file: action for key L4: profile not set",
+			},
+			"profile-for-pause": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"pause","profile":"mapping"}}}}`,
+				expectedErr: "failed reading config file: action for key L4: profile set for pause action",
+			},
+			"bad-profile-name": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"profile","profile":"../mapping"}}}}`,
+				expectedErr: "failed reading config file: action for key L4: invalid profile name: \"../mapping\"",
+			},
+			"seek-no-offset": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"media-seek"}}}}`,
+				expectedErr: "failed reading config file: action for key L4: seek offset not set",
+			},
+			"offset-for-next": {
+				configData:  `{"mapping":{"actions":{"L4":{"type":"media-next","offset_ms":1000}}}}`,
+				expectedErr: "failed reading config file: action for key L4: seek offset set for media-next action",
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				err := os.WriteFile(cfgPath, []byte(tc.configData), 0o660)
+				assert.NoError(err)
+
+				_, err = config.NewFromFile(cfgPath)
+				assert.EqualError(err, tc.expectedErr)
+			})
+		}
+	})
+
+	t.Run("bad-stick-filter", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"joystick","filter":{"jitter":40}}}}`), 0o660)
+		assert.NoError(err)
+		_, err = config.NewFromFile(cfgPath)
+		assert.EqualError(err, "failed reading config file: invalid stick jitter 40: must be at most 32")
+
+		err = os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"joystick","filter":{"smoothing_ms":-1}}}}`), 0o660)
+		assert.NoError(err)
+		_, err = config.NewFromFile(cfgPath)
+		assert.EqualError(err, "failed reading config file: invalid stick smoothing -1: must be positive")
+	})
+
+	t.Run("bad-absolute-region", func(t *testing.T) {
+		for _, region := range []string{
+			`{"x":-0.1,"y":0,"width":0.5,"height":0.5}`,
+			`{"x":0.6,"y":0,"width":0.5,"height":0.5}`,
+			`{"x":0,"y":0.2,"width":0.5,"height":0.9}`,
+			`{"x":0,"y":0,"width":0,"height":0.5}`,
+		} {
+			assert := assert.New(t)
+
+			tmpdir := t.TempDir()
+			cfgPath := filepath.Join(tmpdir, "mapping.json")
+			err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"absolute","absolute":{"region":`+region+`}}}}`), 0o660)
+			assert.NoError(err)
+
+			_, err = config.NewFromFile(cfgPath)
+			assert.ErrorContains(err, "failed reading config file: invalid absolute region")
+		}
+	})
+
+	t.Run("bad-virtual-devices", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"bad-preset": {
+				configData:  `{"virtual_devices":{"joystick":{"preset":"atari"}}}`,
+				expectedErr: "failed reading config file: unknown joystick preset: atari",
+			},
+			"bad-button": {
+				configData:  `{"mapping":{"buttons":{"G1":"BtnNope"}}}`,
+				expectedErr: "failed reading config file: unknown joystick button name: BtnNope",
+			},
+			"bad-button-key": {
+				configData:  `{"mapping":{"buttons":{"G99":"BtnA"}}}`,
+				expectedErr: "failed reading config file: unknown G13 key name: G99",
+			},
+			"button-not-in-preset": {
+				configData:  `{"mapping":{"buttons":{"G1":"BtnTL2"}},"virtual_devices":{"joystick":{"preset":"xbox360"}}}`,
+				expectedErr: "failed reading config file: joystick button BtnTL2 is not available on joystick \"Microsoft X-Box 360 pad\"",
+			},
+			"button-and-key": {
+				configData:  `{"mapping":{"keys":{"G1":"KeyA"},"buttons":{"G1":"BtnA"}}}`,
+				expectedErr: "failed reading config file: key G1 mapped to a joystick button is also mapped to a keyboard key",
+			},
+			"button-and-action": {
+				configData:  `{"mapping":{"buttons":{"G1":"BtnA"},"actions":{"G1":{"type":"stick-mode","mode":"off"}}}}`,
+				expectedErr: "failed reading config file: key G1 bound to an action is also mapped to a joystick button",
+			},
+			"button-and-radial": {
+				configData:  `{"mapping":{"buttons":{"G1":"BtnA"},"radial":[{"trigger":"G1","items":[{"keys":["KeyA"]}]}]}}`,
+				expectedErr: "failed reading config file: radial menu trigger G1 is also mapped to a keyboard key or action",
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				err := os.WriteFile(cfgPath, []byte(tc.configData), 0o660)
+				assert.NoError(err)
+
+				_, err = config.NewFromFile(cfgPath)
+				assert.EqualError(err, tc.expectedErr)
+			})
+		}
+	})
+
+	t.Run("bad-stick-key", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"keys","keys":{"Up":"up"}}}}`), 0o660)
+		assert.NoError(err)
+
+		_, err = config.NewFromFile(cfgPath)
+		assert.ErrorContains(err, "unknown keyboard key name: up")
+	})
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfgPath := "../../configs/default.json"
+	_, err := config.NewFromFile(cfgPath)
+	assert.NoError(t, err)
+}
+
+func TestGetImage(t *testing.T) {
+	for _, scale := range []string{"", "fit", "fill", "crop", "center"} {
+		t.Run("scale-"+scale, func(t *testing.T) {
+			assert := assert.New(t)
+
+			tmpdir := t.TempDir()
+			cfgPath := filepath.Join(tmpdir, "mapping.json")
+			err := os.WriteFile(cfgPath, []byte(fmt.Sprintf(`{"image_file":"image.png","image_scale":%q}`, scale)), 0o660)
+			assert.NoError(err)
+
+			fp, err := os.Create(filepath.Join(tmpdir, "image.png"))
+			assert.NoError(err)
+			assert.NoError(png.Encode(fp, image.NewGray(image.Rect(0, 0, 320, 20))))
+			assert.NoError(fp.Close())
+
+			cfg, err := config.NewFromFile(cfgPath)
+			assert.NoError(err)
+
+			img, err := cfg.GetImage()
+			assert.NoError(err)
+			assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+		})
+	}
+}
+
+func TestGetImageConversion(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := loadTestConfig(t, `{}`)
+	assert.Equal(lcdimage.DefaultConversion(), cfg.GetImageConversion())
+
+	cfg = loadTestConfig(t, `{"image_conversion":{"mode":"atkinson"}}`)
+	assert.Equal(lcdimage.Conversion{Mode: lcdimage.ConvertAtkinson, Threshold: lcdimage.DefaultThreshold}, cfg.GetImageConversion())
+
+	cfg = loadTestConfig(t, `{"image_conversion":{"mode":"bayer","threshold":0,"invert":true}}`)
+	assert.Equal(lcdimage.Conversion{Mode: lcdimage.ConvertBayer, Threshold: 0, Invert: true}, cfg.GetImageConversion())
+}
+
+func TestGetAnimation(t *testing.T) {
+	// writeFrames writes a directory of n frames next to the config file
+	writeFrames := func(t *testing.T, dir string, n int) {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, "frames"), 0o755))
+		for idx := range n {
+			fp, err := os.Create(filepath.Join(dir, "frames", fmt.Sprintf("%02d.png", idx)))
+			assert.NoError(t, err)
+			assert.NoError(t, png.Encode(fp, image.NewGray(image.Rect(0, 0, 16, 16))))
+			assert.NoError(t, fp.Close())
+		}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		writeFrames(t, tmpdir, 3)
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		assert.NoError(os.WriteFile(cfgPath, []byte(`{"animation":{"path":"frames"}}`), 0o660))
+
+		cfg, err := config.NewFromFile(cfgPath)
+		assert.NoError(err)
+		assert.True(cfg.HasAnimation())
+
+		anim, err := cfg.GetAnimation()
+		assert.NoError(err)
+		assert.Len(anim.Frames, 3)
+		assert.Equal(0, anim.Loops)
+		for _, frame := range anim.Frames {
+			assert.Equal(100*time.Millisecond, frame.Delay)
+			assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), frame.Image.Bounds())
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		writeFrames(t, tmpdir, 2)
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		assert.NoError(os.WriteFile(cfgPath, []byte(`{"animation":{"path":"frames","fps":4,"loops":3,"scale":"center"}}`), 0o660))
+
+		cfg, err := config.NewFromFile(cfgPath)
+		assert.NoError(err)
+
+		anim, err := cfg.GetAnimation()
+		assert.NoError(err)
+		assert.Equal(3, anim.Loops)
+		assert.Equal(250*time.Millisecond, anim.Frames[0].Delay)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{}`)
+		assert.False(cfg.HasAnimation())
+		_, err := cfg.GetAnimation()
+		assert.EqualError(err, "no animation defined in config")
+	})
+}
+
+func TestGetTextAnimation(t *testing.T) {
+	t.Run("static", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"text":{"lines":["G13","ready"],"align":"center","inverse":true}}`)
+		assert.True(cfg.HasText())
+
+		anim, err := cfg.GetTextAnimation()
+		assert.NoError(err)
+		assert.Len(anim.Frames, 1)
+		expected := lcdtext.Render([]string{"G13", "ready"}, lcdtext.Style{Align: lcdtext.AlignCenter, Inverse: true})
+		assert.Equal(expected, anim.Frames[0].Image)
+	})
+
+	t.Run("marquee", func(t *testing.T) {
+		assert := assert.New(t)
+		long := strings.Repeat("scrolling ", 5)
+		cfg := loadTestConfig(t, fmt.Sprintf(`{"text":{"lines":[%q],"font":"basic"}}`, long))
+
+		anim, err := cfg.GetTextAnimation()
+		assert.NoError(err)
+		assert.Greater(len(anim.Frames), 1)
+
+		cfg = loadTestConfig(t, fmt.Sprintf(`{"text":{"lines":[%q],"marquee_speed":0}}`, long))
+		anim, err = cfg.GetTextAnimation()
+		assert.NoError(err)
+		assert.Len(anim.Frames, 1)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{}`)
+		assert.False(cfg.HasText())
+		_, err := cfg.GetTextAnimation()
+		assert.EqualError(err, "no text defined in config")
+	})
+}
+
+func TestGetPages(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert := assert.New(t)
+		pages, err := loadTestConfig(t, `{}`).GetPages(nil)
+		assert.NoError(err)
+		assert.Empty(pages)
+	})
+
+	t.Run("single-content", func(t *testing.T) {
+		assert := assert.New(t)
+		pages, err := loadTestConfig(t, `{"text":{"lines":["hello"]}}`).GetPages(nil)
+		assert.NoError(err)
+		assert.Len(pages, 1)
+		assert.Equal("text", pages[0].Name())
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpdir := t.TempDir()
+		fp, err := os.Create(filepath.Join(tmpdir, "logo.png"))
+		assert.NoError(err)
+		assert.NoError(png.Encode(fp, image.NewGray(image.Rect(0, 0, 20, 20))))
+		assert.NoError(fp.Close())
+
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		assert.NoError(os.WriteFile(cfgPath, []byte(`{
+	"pages": [
+		{"name": "Logo", "image": {"path": "logo.png", "scale": "center"}},
+		{"clock": {"time_format": "15:04", "font": "basic", "align": "center"}},
+		{"name": "Notes", "text": {"lines": ["one", "two"], "inverse": true}},
+		{"monitor": {"interval_ms": 500, "widgets": [
+			{"type": "cpu"},
+			{"type": "network", "source": "eth0", "label": "ETH"},
+			{"type": "clock", "format": "15:04", "x": 120, "y": 0, "width": 40, "height": 8}
+		]}},
+		{"keymap": {}},
+		{"terminal": {"command": ["git", "status", "--short"], "dir": "repo", "interval_ms": 5000}}
+	]
+}`), 0o660))
+		cfg, err := config.NewFromFile(cfgPath)
+		assert.NoError(err)
+
+		pages, err := cfg.GetPages(nil)
+		assert.NoError(err)
+		assert.Len(pages, 6)
+		assert.Equal("Logo", pages[0].Name())
+		assert.Equal("clock", pages[1].Name())
+		assert.Equal("Notes", pages[2].Name())
+		assert.Equal("monitor", pages[3].Name())
+		assert.Equal("keymap", pages[4].Name())
+		assert.Equal("terminal", pages[5].Name())
+		assert.Implements((*lcdpage.Selecter)(nil), pages[1])
+		assert.IsType(&lcdpage.Monitor{}, pages[3])
+		assert.IsType(&lcdpage.Keymap{}, pages[4])
+		assert.IsType(&lcdpage.Terminal{}, pages[5])
+	})
+}
+
+func TestGetKeyLabels(t *testing.T) {
+	t.Run("bindings", func(t *testing.T) {
+		assert := assert.New(t)
+
+		cfg := loadTestConfig(t, `{
+	"mapping": {
+		"keys": {"G1": "KeyEsc", "G2": "KeyLeftshift"},
+		"buttons": {"G3": "BtnSouth"},
+		"actions": {
+			"G4": {"type": "stick-mode", "mode": "cycle", "modes": ["mouse", "keys"]},
+			"G5": {"type": "stick-mode", "mode": "scroll"},
+			"G6": {"type": "pause"}
+		},
+		"radial": [{"trigger": "G7", "items": [{"keys": ["KeyA"]}]}],
+		"stick": {"mode": "mouse"}
+	},
+	"pages": [{"keymap": {}}]
+}`)
+		labels := cfg.GetKeyLabels()
+		assert.Equal(map[device.KeyBit]string{
+			device.G1: "Esc",
+			device.G2: "Leftshift",
+			device.G3: "A",
+			device.G4: "Stick",
+			device.G5: "scroll",
+			device.G6: "Pause",
+			device.G7: "Radial",
+			device.BD: "Menu",
+			device.L1: "<Page",
+			device.L2: "Page>",
+			device.L3: "Select",
+		}, labels.Keys)
+		assert.Equal("mouse", labels.Stick)
+
+		cfg.SetStickMode(config.StickModeKeys)
+		assert.Equal("keys", cfg.GetKeyLabels().Stick)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert := assert.New(t)
+		labels := loadTestConfig(t, `{}`).GetKeyLabels()
+		assert.Equal(map[device.KeyBit]string{device.BD: "Menu"}, labels.Keys)
+		assert.Equal("off", labels.Stick)
+	})
+}
+
+func TestGetLCDInput(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert := assert.New(t)
+		socket, fifo := loadTestConfig(t, `{}`).GetLCDInput()
+		assert.Empty(socket)
+		assert.Empty(fifo)
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv("GG13_TEST_RUNTIME_DIR", "/run/user/1000")
+
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		assert.NoError(os.WriteFile(cfgPath, []byte(`{"lcd_input":{"socket":"$GG13_TEST_RUNTIME_DIR/g13.sock","fifo":"lcd.fifo"}}`), 0o660))
+		cfg, err := config.NewFromFile(cfgPath)
+		assert.NoError(err)
+
+		socket, fifo := cfg.GetLCDInput()
+		assert.Equal("/run/user/1000/g13.sock", socket)
+		assert.Equal(filepath.Join(tmpdir, "lcd.fifo"), fifo)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"same-path": {
+				configData:  `{"lcd_input":{"socket":"/tmp/g13","fifo":"/tmp/g13"}}`,
+				expectedErr: "failed reading config file: LCD input socket and FIFO have the same path: /tmp/g13",
+			},
+			"unset-variable": {
+				configData:  `{"lcd_input":{"fifo":"$GG13_TEST_UNSET"}}`,
+				expectedErr: "failed reading config file: LCD input FIFO path \"$GG13_TEST_UNSET\" is empty after expanding environment variables",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				assert.NoError(t, os.WriteFile(cfgPath, []byte(tc.configData), 0o660))
+				_, err := config.NewFromFile(cfgPath)
+				assert.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+}
+
+func TestGetNotifications(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		_, ok := loadTestConfig(t, `{}`).GetNotifications()
+		assert.False(t, ok)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		assert := assert.New(t)
+		notif, ok := loadTestConfig(t, `{"notifications":{}}`).GetNotifications()
+		assert.True(ok)
+		assert.Equal(config.Notifications{
+			Timeout:       config.DefaultNotificationTimeout,
+			FlashDuration: config.DefaultFlashDuration,
+		}, notif)
+	})
+
+	t.Run("settings", func(t *testing.T) {
+		assert := assert.New(t)
+		notif, ok := loadTestConfig(t, `{"notifications":{
+	"bus": "unix:path=/tmp/bus",
+	"allow": ["discord"],
+	"deny": ["spotify"],
+	"timeout_ms": 3000,
+	"flash": {"red": 255, "green": 128},
+	"flash_ms": 150
+}}`).GetNotifications()
+		assert.True(ok)
+		assert.Equal(config.Notifications{
+			Address:       "unix:path=/tmp/bus",
+			Filter:        notify.Filter{Allow: []string{"discord"}, Deny: []string{"spotify"}},
+			Timeout:       3 * time.Second,
+			Flash:         &[3]uint8{255, 128, 0},
+			FlashDuration: 150 * time.Millisecond,
+		}, notif)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"negative-timeout": {
+				configData:  `{"notifications":{"timeout_ms":-1}}`,
+				expectedErr: "failed reading config file: invalid notification timeout -1: must not be negative",
+			},
+			"negative-flash": {
+				configData:  `{"notifications":{"flash":{"red":1},"flash_ms":-1}}`,
+				expectedErr: "failed reading config file: invalid notification flash duration -1: must not be negative",
+			},
+			"flash-without-colour": {
+				configData:  `{"notifications":{"flash_ms":100}}`,
+				expectedErr: "failed reading config file: notification flash duration set without a flash colour",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				assert.NoError(t, os.WriteFile(cfgPath, []byte(tc.configData), 0o660))
+				_, err := config.NewFromFile(cfgPath)
+				assert.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+}
+
+func TestGetTimers(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{}`)
+		assert.Equal(config.Timers{}, cfg.GetTimers())
+		assert.Empty(cfg.GetCooldowns(0, device.G1.Uint64()))
+	})
+
+	t.Run("settings", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"mapping": {
+		"keys": {"G5": "Key1"},
+		"actions": {
+			"G1": {"type": "timer-toggle", "timer": "run"},
+			"G2": {"type": "timer-reset", "timer": "run"},
+			"G3": {"type": "timer-start", "timer": "boss"},
+			"G4": {"type": "timer-stop", "timer": "boss"}
+		},
+		"cooldowns": {
+			"G5": {"duration_ms": 8000, "name": "Fireball"},
+			"G6": {"duration_ms": 30000}
+		}
+	},
+	"timers": {
+		"items": [
+			{"name": "run", "type": "stopwatch"},
+			{"name": "boss", "type": "countdown", "duration_ms": 90000}
+		],
+		"flash": {"red": 255}
+	}
+}`)
+		assert.Equal(config.Timers{
+			Timers: []timers.Timer{
+				{Name: "run", Kind: timers.Stopwatch},
+				{Name: "boss", Kind: timers.Countdown, Duration: 90 * time.Second},
+			},
+			Flash:         &[3]uint8{255, 0, 0},
+			FlashDuration: config.DefaultFlashDuration,
+		}, cfg.GetTimers())
+
+		assert.Equal([]config.Action{{Type: config.ActionTimerToggle, Timer: "run"}}, cfg.GetActions(0, device.G1.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionTimerReset, Timer: "run"}}, cfg.GetActions(0, device.G2.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionTimerStart, Timer: "boss"}}, cfg.GetActions(0, device.G3.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionTimerStop, Timer: "boss"}}, cfg.GetActions(0, device.G4.Uint64()))
+
+		// a cooldown doesn't change what its key is bound to, and starts
+		// only when the key is pressed
+		assert.Equal(map[int]bool{uinput.Key1: true}, cfg.GetKeyStates(device.G5.Uint64()))
+		assert.Equal([]config.Cooldown{{Name: "Fireball", Duration: 8 * time.Second}}, cfg.GetCooldowns(0, device.G5.Uint64()))
+		assert.Empty(cfg.GetCooldowns(device.G5.Uint64(), device.G5.Uint64()))
+		assert.Equal([]config.Cooldown{{Name: "G6", Duration: 30 * time.Second}}, cfg.GetCooldowns(0, device.G6.Uint64()))
+
+		labels := cfg.GetKeyLabels()
+		assert.Equal("run", labels.Keys[device.G1])
+		assert.Equal("Reset", labels.Keys[device.G2])
+		assert.Equal("boss", labels.Keys[device.G3])
+		assert.Equal("Stop", labels.Keys[device.G4])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"no-name": {
+				configData:  `{"timers":{"items":[{"type":"stopwatch"}]}}`,
+				expectedErr: "failed reading config file: timer 1: name not set",
+			},
+			"duplicate-name": {
+				configData:  `{"timers":{"items":[{"name":"a","type":"stopwatch"},{"name":"a","type":"stopwatch"}]}}`,
+				expectedErr: "failed reading config file: timer 2: name \"a\" used for more than one timer",
+			},
+			"bad-type": {
+				configData:  `{"timers":{"items":[{"name":"a","type":"hourglass"}]}}`,
+				expectedErr: "failed reading config file: timer 1: unknown timer type: \"hourglass\"",
+			},
+			"countdown-no-duration": {
+				configData:  `{"timers":{"items":[{"name":"a","type":"countdown"}]}}`,
+				expectedErr: "failed reading config file: timer 1: invalid countdown duration 0: must be positive",
+			},
+			"stopwatch-duration": {
+				configData:  `{"timers":{"items":[{"name":"a","type":"stopwatch","duration_ms":100}]}}`,
+				expectedErr: "failed reading config file: timer 1: duration set for stopwatch",
+			},
+			"flash-without-colour": {
+				configData:  `{"timers":{"flash_ms":100}}`,
+				expectedErr: "failed reading config file: timer flash duration set without a flash colour",
+			},
+			"unknown-timer": {
+				configData:  `{"mapping":{"actions":{"G1":{"type":"timer-start","timer":"run"}}}}`,
+				expectedErr: "failed reading config file: action for key G1: unknown timer: \"run\"",
+			},
+			"action-no-timer": {
+				configData:  `{"mapping":{"actions":{"G1":{"type":"timer-start"}}}}`,
+				expectedErr: "failed reading config file: action for key G1: timer not set",
+			},
+			"timer-for-pause": {
+				configData:  `{"mapping":{"actions":{"G1":{"type":"pause","timer":"run"}}}}`,
+				expectedErr: "failed reading config file: action for key G1: timer set for pause action",
+			},
+			"cooldown-bad-key": {
+				configData:  `{"mapping":{"cooldowns":{"G30":{"duration_ms":100}}}}`,
+				expectedErr: "failed reading config file: unknown G13 key name for cooldown: G30",
+			},
+			"cooldown-no-duration": {
+				configData:  `{"mapping":{"cooldowns":{"G1":{}}}}`,
+				expectedErr: "failed reading config file: cooldown for key G1: invalid duration 0: must be positive",
+			},
+			"cooldown-timer-name": {
+				configData:  `{"mapping":{"cooldowns":{"G1":{"name":"run","duration_ms":100}}},"timers":{"items":[{"name":"run","type":"stopwatch"}]}}`,
+				expectedErr: "failed reading config file: cooldown for key G1: name \"run\" is also used for a timer",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				assert.NoError(t, os.WriteFile(cfgPath, []byte(tc.configData), 0o660))
+				_, err := config.NewFromFile(cfgPath)
+				assert.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+}
+
+func TestGetBacklightEffects(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"backlight": {"red": 10, "green": 20, "blue": 30}}`)
+		assert.Equal(config.BacklightEffects{
+			Effect:      backlight.Static{10, 20, 30},
+			MinInterval: backlight.DefaultMinInterval,
+		}, cfg.GetBacklightEffects())
+	})
+
+	t.Run("breathing", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"backlight": {"red": 200},
+	"backlight_effects": {
+		"effect": {"type": "breathing", "period_ms": 4000, "min_brightness": 0.25},
+		"fade_ms": 500,
+		"min_interval_ms": 100,
+		"flash": {
+			"stick-mode": {"colour": {"blue": 255}, "duration_ms": 150},
+			"pause": {"colour": {"green": 255}}
+		}
+	}
+}`)
+		assert.Equal(config.BacklightEffects{
+			Effect: backlight.Breathing{Colour: [3]uint8{200, 0, 0}, Period: 4 * time.Second, Min: 0.25},
+			Fade:   500 * time.Millisecond,
+			Flashes: map[string]backlight.Flash{
+				config.FlashStickMode: {Colour: [3]uint8{0, 0, 255}, Duration: 150 * time.Millisecond},
+				config.FlashPause:     {Colour: [3]uint8{0, 255, 0}, Duration: config.DefaultFlashDuration},
+			},
+			MinInterval: 100 * time.Millisecond,
+		}, cfg.GetBacklightEffects())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"backlight_effects": {
+		"effect": {"type": "cycle", "period_ms": 1000, "colours": [{"red": 255}, {"green": 255}, {"blue": 255}]}
+	}
+}`)
+		assert.Equal(backlight.Cycle{
+			Colours: [][3]uint8{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}},
+			Period:  time.Second,
+		}, cfg.GetBacklightEffects().Effect)
+	})
+
+	t.Run("metric", func(t *testing.T) {
+		assert := assert.New(t)
+		tmpdir := t.TempDir()
+		cfgPath := filepath.Join(tmpdir, "mapping.json")
+		assert.NoError(os.WriteFile(cfgPath, []byte(`{
+	"backlight_effects": {
+		"effect": {
+			"type": "metric",
+			"metric": "temperature",
+			"source": "coretemp",
+			"root": "fixture",
+			"gradient": [{"value": 40, "colour": "green"}, {"value": 90, "colour": "#f00"}]
+		}
+	}
+}`), 0o660))
+		cfg, err := config.NewFromFile(cfgPath)
 		assert.NoError(err)
+		assert.Equal(&config.BacklightMetric{
+			Metric:   sysmon.MetricTemperature,
+			Source:   "coretemp",
+			Interval: config.DefaultMetricInterval,
+			Root:     filepath.Join(tmpdir, "fixture"),
+			Gradient: backlight.Gradient{
+				{Value: 40, Colour: [3]uint8{0, 128, 0}},
+				{Value: 90, Colour: [3]uint8{255, 0, 0}},
+			},
+		}, cfg.GetBacklightEffects().Metric)
 
-		_, err = config.NewFromFile(cfgPath)
-		assert.ErrorContains(err, "unknown stick mode: bad")
+		cfg = loadTestConfig(t, `{
+	"lcd_input": {"socket": "/tmp/gg13.sock"},
+	"backlight_effects": {
+		"effect": {"type": "metric", "metric": "pushed", "gradient": [{"value": 0, "colour": "blue"}]}
+	}
+}`)
+		assert.Equal(&config.BacklightMetric{
+			Pushed:   true,
+			Gradient: backlight.Gradient{{Value: 0, Colour: [3]uint8{0, 0, 255}}},
+		}, cfg.GetBacklightEffects().Metric)
 	})
 
-	t.Run("bad-stick-key", func(t *testing.T) {
+	t.Run("errors", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"no-type": {
+				configData:  `{"backlight_effects":{"effect":{}}}`,
+				expectedErr: "failed reading config file: backlight effect: type not set",
+			},
+			"bad-type": {
+				configData:  `{"backlight_effects":{"effect":{"type":"disco"}}}`,
+				expectedErr: "failed reading config file: backlight effect: unknown type: \"disco\"",
+			},
+			"breathing-no-period": {
+				configData:  `{"backlight_effects":{"effect":{"type":"breathing"}}}`,
+				expectedErr: "failed reading config file: backlight effect: invalid period 0: must be positive",
+			},
+			"breathing-bad-brightness": {
+				configData:  `{"backlight_effects":{"effect":{"type":"breathing","period_ms":1000,"min_brightness":1.5}}}`,
+				expectedErr: "failed reading config file: backlight effect: invalid minimum brightness 1.5: must be between 0 and 1",
+			},
+			"cycle-one-colour": {
+				configData:  `{"backlight_effects":{"effect":{"type":"cycle","period_ms":1000,"colours":[{"red":255}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: colour cycle needs at least 2 colours",
+			},
+			"static-options": {
+				configData:  `{"backlight_effects":{"effect":{"type":"static","period_ms":1000}}}`,
+				expectedErr: "failed reading config file: backlight effect: options set for static effect",
+			},
+			"negative-fade": {
+				configData:  `{"backlight_effects":{"fade_ms":-1}}`,
+				expectedErr: "failed reading config file: invalid backlight fade duration -1: must not be negative",
+			},
+			"negative-interval": {
+				configData:  `{"backlight_effects":{"min_interval_ms":-1}}`,
+				expectedErr: "failed reading config file: invalid backlight minimum interval -1: must not be negative",
+			},
+			"bad-event": {
+				configData:  `{"backlight_effects":{"flash":{"boot":{"colour":{"red":255}}}}}`,
+				expectedErr: "failed reading config file: unknown backlight flash event: \"boot\"",
+			},
+			"flash-no-colour": {
+				configData:  `{"backlight_effects":{"flash":{"page":{"duration_ms":100}}}}`,
+				expectedErr: "failed reading config file: backlight flash for page: colour not set",
+			},
+			"no-metric": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","gradient":[{"value":0,"colour":"red"}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: metric not set",
+			},
+			"bad-metric": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"fan","gradient":[{"value":0,"colour":"red"}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: unknown metric: \"fan\"",
+			},
+			"no-gradient": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"cpu"}}}`,
+				expectedErr: "failed reading config file: backlight effect: metric gradient not set",
+			},
+			"gradient-order": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"cpu","gradient":[{"value":50,"colour":"red"},{"value":50,"colour":"blue"}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: gradient stop 2: value 50 must be greater than the value of the stop before it",
+			},
+			"gradient-no-colour": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"cpu","gradient":[{"value":50}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: gradient stop 1: colour not set",
+			},
+			"cpu-source": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"cpu","source":"cpu0","gradient":[{"value":0,"colour":"red"}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: source set for cpu metric",
+			},
+			"pushed-interval": {
+				configData:  `{"lcd_input":{"socket":"/tmp/gg13.sock"},"backlight_effects":{"effect":{"type":"metric","metric":"pushed","interval_ms":100,"gradient":[{"value":0,"colour":"red"}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: source, interval, and root set for pushed metric",
+			},
+			"pushed-no-input": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"pushed","gradient":[{"value":0,"colour":"red"}]}}}`,
+				expectedErr: "failed reading config file: pushed backlight metric set without an LCD input socket or FIFO",
+			},
+			"metric-options-for-breathing": {
+				configData:  `{"backlight_effects":{"effect":{"type":"breathing","period_ms":1000,"metric":"cpu"}}}`,
+				expectedErr: "failed reading config file: backlight effect: metric options set for breathing effect",
+			},
+			"breathing-options-for-metric": {
+				configData:  `{"backlight_effects":{"effect":{"type":"metric","metric":"cpu","period_ms":1000,"gradient":[{"value":0,"colour":"red"}]}}}`,
+				expectedErr: "failed reading config file: backlight effect: options set for metric effect",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				assert.NoError(t, os.WriteFile(cfgPath, []byte(tc.configData), 0o660))
+				_, err := config.NewFromFile(cfgPath)
+				assert.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+}
+
+func TestBacklightColours(t *testing.T) {
+	t.Run("formats", func(t *testing.T) {
 		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"backlight": "#17a3d1",
+	"brightness": 0.5,
+	"notifications": {"flash": "orange"},
+	"timers": {"flash": "hsv(120, 100%, 100%)"},
+	"mapping": {"actions": {"G1": {"type": "backlight", "colour": {"red": 1, "green": 2, "blue": 3}}}}
+}`)
+		assert.Equal([3]uint8{0x17, 0xa3, 0xd1}, cfg.GetBacklight())
+		assert.Equal(0.5, cfg.GetBrightness())
+		notifications, _ := cfg.GetNotifications()
+		assert.Equal(&[3]uint8{255, 165, 0}, notifications.Flash)
+		assert.Equal(&[3]uint8{0, 255, 0}, cfg.GetTimers().Flash)
+		assert.Equal([]config.Action{{Type: config.ActionBacklight, Colour: [3]uint8{1, 2, 3}}}, cfg.GetActions(0, device.G1.Uint64()))
+	})
 
-		tmpdir := t.TempDir()
-		cfgPath := filepath.Join(tmpdir, "mapping.json")
-		err := os.WriteFile(cfgPath, []byte(`{"mapping":{"stick":{"mode":"keys","keys":{"Up":"up"}}}}`), 0o660)
-		assert.NoError(err)
+	t.Run("default-brightness", func(t *testing.T) {
+		assert.Equal(t, 1.0, loadTestConfig(t, `{}`).GetBrightness())
+	})
 
-		_, err = config.NewFromFile(cfgPath)
-		assert.ErrorContains(err, "unknown keyboard key name: up")
+	t.Run("errors", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"bad-hex": {
+				configData:  `{"backlight": "#17a3d"}`,
+				expectedErr: `failed reading config file: backlight: invalid colour "#17a3d": hex colours need 3 or 6 digits`,
+			},
+			"bad-name": {
+				configData:  `{"backlight_effects": {"flash": {"page": {"colour": "blurple"}}}}`,
+				expectedErr: `backlight flash for page: invalid colour "blurple": unknown colour name`,
+			},
+			"bad-hsv": {
+				configData:  `{"timers": {"flash": "hsv(10, 200%, 50%)"}}`,
+				expectedErr: `timer flash: invalid colour "hsv(10, 200%, 50%)": saturation 200 must be between 0 and 100`,
+			},
+			"bad-action": {
+				configData:  `{"mapping": {"actions": {"G1": {"type": "backlight", "colour": "tael"}}}}`,
+				expectedErr: `action for key G1: backlight colour: invalid colour "tael": unknown colour name`,
+			},
+			"bad-notification": {
+				configData:  `{"notifications": {"flash": "tael"}}`,
+				expectedErr: `notification flash: invalid colour "tael": unknown colour name`,
+			},
+			"bad-cycle": {
+				configData:  `{"backlight_effects": {"effect": {"type": "cycle", "period_ms": 1000, "colours": ["red", "tael"]}}}`,
+				expectedErr: `backlight effect: colour 2: invalid colour "tael": unknown colour name`,
+			},
+			"bad-gradient": {
+				configData:  `{"backlight_effects": {"effect": {"type": "metric", "metric": "cpu", "gradient": [{"value": 0, "colour": "tael"}]}}}`,
+				expectedErr: `backlight effect: gradient stop 1: invalid colour "tael": unknown colour name`,
+			},
+			"bad-host-led": {
+				configData:  `{"host_leds": {"backlight": [{"led": "caps-lock", "colour": "tael"}]}}`,
+				expectedErr: `host LED backlight colour 1: invalid colour "tael": unknown colour name`,
+			},
+			"empty": {
+				configData:  `{"backlight": ""}`,
+				expectedErr: `backlight: invalid colour "": unknown colour name`,
+			},
+			"unknown-field": {
+				configData:  `{"backlight": {"red": 10, "alpha": 1}}`,
+				expectedErr: `unknown field "alpha"`,
+			},
+			"brightness": {
+				configData:  `{"brightness": 1.5}`,
+				expectedErr: "failed reading config file: invalid brightness 1.5: must be greater than 0 and at most 1",
+			},
+			"zero-brightness": {
+				configData:  `{"brightness": 0}`,
+				expectedErr: "failed reading config file: invalid brightness 0: must be greater than 0 and at most 1",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				assert.NoError(t, os.WriteFile(cfgPath, []byte(tc.configData), 0o660))
+				_, err := config.NewFromFile(cfgPath)
+				assert.ErrorContains(t, err, tc.expectedErr)
+			})
+		}
 	})
 }
 
-func TestDefaultConfig(t *testing.T) {
-	cfgPath := "../../configs/default.json"
-	_, err := config.NewFromFile(cfgPath)
-	assert.NoError(t, err)
+func TestGetHostLEDs(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		_, ok := loadTestConfig(t, `{}`).GetHostLEDs()
+		assert.False(t, ok)
+	})
+
+	t.Run("settings", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"host_leds": {
+		"devices": ["/dev/input/event3"],
+		"backlight": [
+			{"led": "caps-lock", "colour": "red"},
+			{"led": "scroll-lock", "colour": {"blue": 255}}
+		],
+		"mr_led": "caps-lock",
+		"lcd": ["caps-lock", "num-lock"]
+	}
+}`)
+		caps := hostleds.CapsLock
+		leds, ok := cfg.GetHostLEDs()
+		assert.True(ok)
+		assert.Equal(config.HostLEDs{
+			Devices: []string{"/dev/input/event3"},
+			Backlight: []config.LEDColour{
+				{LED: hostleds.CapsLock, Colour: [3]uint8{255, 0, 0}},
+				{LED: hostleds.ScrollLock, Colour: [3]uint8{0, 0, 255}},
+			},
+			MR:  &caps,
+			LCD: []hostleds.LED{hostleds.CapsLock, hostleds.NumLock},
+		}, leds)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type testCase struct {
+			configData  string
+			expectedErr string
+		}
+		testCases := map[string]testCase{
+			"nothing-shown": {
+				configData:  `{"host_leds":{"devices":["/dev/input/event3"]}}`,
+				expectedErr: "failed reading config file: host LEDs set without backlight colours, MR LED, or LCD indicators",
+			},
+			"bad-backlight-led": {
+				configData:  `{"host_leds":{"backlight":[{"led":"kana","colour":"red"}]}}`,
+				expectedErr: "failed reading config file: host LED backlight colour 1: unknown lock LED: \"kana\"",
+			},
+			"no-colour": {
+				configData:  `{"host_leds":{"backlight":[{"led":"caps-lock"}]}}`,
+				expectedErr: "failed reading config file: host LED backlight colour 1: colour not set",
+			},
+			"bad-mr-led": {
+				configData:  `{"host_leds":{"mr_led":"caps"}}`,
+				expectedErr: "failed reading config file: host LED for MR: unknown lock LED: \"caps\"",
+			},
+			"bad-lcd-led": {
+				configData:  `{"host_leds":{"lcd":["compose"]}}`,
+				expectedErr: "failed reading config file: host LED for LCD: unknown lock LED: \"compose\"",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tmpdir := t.TempDir()
+				cfgPath := filepath.Join(tmpdir, "mapping.json")
+				assert.NoError(t, os.WriteFile(cfgPath, []byte(tc.configData), 0o660))
+				_, err := config.NewFromFile(cfgPath)
+				assert.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+}
+
+func TestPageActions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"pages":[{"clock":{}}]}`)
+		assert.Equal([]config.Action{{Type: config.ActionPagePrevious}}, cfg.GetActions(0, device.L1.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionPageNext}}, cfg.GetActions(0, device.L2.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionPageSelect}}, cfg.GetActions(0, device.L3.Uint64()))
+		assert.Empty(cfg.GetActions(0, device.L4.Uint64()))
+	})
+
+	t.Run("no-pages", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{}`)
+		assert.Empty(cfg.GetActions(0, device.L1.Uint64()|device.L2.Uint64()|device.L3.Uint64()))
+	})
+
+	t.Run("mapped-l-keys", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"mapping": {
+		"keys": {"L1": "KeyF1"},
+		"buttons": {"L2": "BtnA"},
+		"actions": {"L4": {"type": "page-next"}}
+	},
+	"pages": [{"clock": {}}]
+}`)
+		// L1 and L2 stay mapped to the keyboard and joystick
+		assert.Empty(cfg.GetActions(0, device.L1.Uint64()))
+		assert.Empty(cfg.GetActions(0, device.L2.Uint64()))
+		assert.Equal(map[int]bool{uinput.KeyF1: true}, cfg.GetKeyStates(device.L1.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionPageSelect}}, cfg.GetActions(0, device.L3.Uint64()))
+		assert.Equal([]config.Action{{Type: config.ActionPageNext}}, cfg.GetActions(0, device.L4.Uint64()))
+	})
+
+	t.Run("radial-trigger", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"mapping":{"radial":[{"trigger":"L3","items":[{"keys":["KeyA"]}]}]},"pages":[{"clock":{}}]}`)
+		assert.Empty(cfg.GetActions(0, device.L3.Uint64()))
+	})
 }
 
 func TestGetImageErrors(t *testing.T) {
@@ -369,6 +1741,494 @@ func TestGetImageErrors(t *testing.T) {
 
 		_, err = cfg.GetImage()
 		assert.ErrorContains(err, "failed to read image file")
-		assert.ErrorContains(err, "invalid format")
+		assert.ErrorContains(err, "unknown format")
+	})
+}
+
+// stickInput returns a device input value with the stick at the given
+// position and no keys pressed.
+func stickInput(x, y uint8) uint64 {
+	return uint64(x)<<8 | uint64(y)<<16
+}
+
+func loadTestConfig(t *testing.T, data string) *config.G13Config {
+	t.Helper()
+	tmpdir := t.TempDir()
+	cfgPath := filepath.Join(tmpdir, "mapping.json")
+	if err := os.WriteFile(cfgPath, []byte(data), 0o660); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewFromFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestGetScrollSpeed(t *testing.T) {
+	type testCase struct {
+		x, y       uint8
+		expH, expV float32
+	}
+
+	testCases := map[string]testCase{
+		"centre":         {x: 127, y: 127, expH: 0, expV: 0},
+		"resting-offset": {x: 120, y: 112, expH: 0, expV: 0},
+		"full-up":        {x: 127, y: 0, expH: 0, expV: 10},
+		"full-down":      {x: 127, y: 255, expH: 0, expV: -10},
+		"full-left":      {x: 0, y: 127, expH: -10, expV: 0},
+		"full-right":     {x: 255, y: 127, expH: 10, expV: 0},
+		"half-up":        {x: 127, y: 127 - 32 - 95/2, expH: 0, expV: 10 * float32(95/2) / 95},
+	}
+
+	cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"scroll","scroll":{"speed":10}}}}`)
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			h, v, ok := cfg.GetScrollSpeed(stickInput(tc.x, tc.y))
+			assert.True(ok)
+			assert.InDelta(tc.expH, h, 0.0001)
+			assert.InDelta(tc.expV, v, 0.0001)
+		})
+	}
+
+	t.Run("not-scroll-mode", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"joystick"}}}`)
+		_, _, ok := cfg.GetScrollSpeed(stickInput(0, 0))
+		assert.False(t, ok)
+	})
+}
+
+func TestGetHatPosition(t *testing.T) {
+	type testCase struct {
+		x, y       uint8
+		expX, expY int8
+	}
+
+	testCases := map[string]testCase{
+		"centre":         {x: 127, y: 127, expX: 0, expY: 0},
+		"resting-offset": {x: 120, y: 112, expX: 0, expY: 0},
+		"up":             {x: 127, y: 10, expX: 0, expY: -1},
+		"down":           {x: 127, y: 250, expX: 0, expY: 1},
+		"left":           {x: 3, y: 127, expX: -1, expY: 0},
+		"right":          {x: 255, y: 127, expX: 1, expY: 0},
+		"up-left":        {x: 0, y: 0, expX: -1, expY: -1},
+		"down-right":     {x: 255, y: 255, expX: 1, expY: 1},
+	}
+
+	cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"hat"}}}`)
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			x, y, ok := cfg.GetHatPosition(stickInput(tc.x, tc.y))
+			assert.True(ok)
+			assert.Equal(tc.expX, x)
+			assert.Equal(tc.expY, y)
+		})
+	}
+
+	t.Run("not-hat-mode", func(t *testing.T) {
+		cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"scroll"}}}`)
+		_, _, ok := cfg.GetHatPosition(stickInput(0, 0))
+		assert.False(t, ok)
+	})
+}
+
+func TestGetMouseSpeed(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"mouse","mouse":{"speed":100}}}}`)
+	x, y, ok := cfg.GetMouseSpeed(stickInput(120, 112))
+	assert.True(ok)
+	assert.Zero(x)
+	assert.Zero(y)
+
+	x, y, ok = cfg.GetMouseSpeed(stickInput(0, 255))
+	assert.True(ok)
+	assert.InDelta(-100, x, 0.0001)
+	assert.InDelta(100, y, 0.0001)
+
+	cfg.SetStickMode(config.StickModeOff)
+	_, _, ok = cfg.GetMouseSpeed(stickInput(0, 255))
+	assert.False(ok)
+}
+
+func TestStickModeActions(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := loadTestConfig(t, `{
+	"mapping": {
+		"stick": {
+			"mode": "joystick",
+			"keys": {"Up": "KeyW", "Down": "KeyS", "Left": "KeyA", "Right": "KeyD"}
+		},
+		"actions": {
+			"BD": {"type": "stick-mode", "mode": "cycle"},
+			"L1": {"type": "stick-mode", "mode": "cycle", "modes": ["scroll", "hat"]},
+			"L2": {"type": "stick-mode", "mode": "off"}
+		}
+	}
+}`)
+
+	// nothing pressed
+	assert.Empty(cfg.GetActions(0, 0))
+	// held, not newly pressed
+	assert.Empty(cfg.GetActions(device.BD.Uint64(), device.BD.Uint64()))
+
+	actions := cfg.GetActions(0, device.BD.Uint64()|device.L2.Uint64())
+	assert.Len(actions, 2)
+
+	cycle := cfg.GetActions(0, device.BD.Uint64())[0]
+	assert.Equal(config.ActionStickMode, cycle.Type)
+
+	mode := cfg.GetStickMode()
+	assert.Equal(config.StickModeJoystick, mode)
+	var visited []string
+	for range 4 {
+		mode = cycle.NextStickMode(mode)
+		visited = append(visited, mode.String())
+	}
+	assert.Equal([]string{"keys", "mouse", "off", "joystick"}, visited)
+
+	// stick keys are configured since the keys mode can be reached
+	assert.Equal(config.StickKeys{Up: uinput.KeyW, Down: uinput.KeyS, Left: uinput.KeyA, Right: uinput.KeyD}, cfg.GetStickKeys())
+
+	custom := cfg.GetActions(0, device.L1.Uint64())[0]
+	assert.Equal(config.StickModeScroll, custom.NextStickMode(config.StickModeJoystick))
+	assert.Equal(config.StickModeHat, custom.NextStickMode(config.StickModeScroll))
+	assert.Equal(config.StickModeScroll, custom.NextStickMode(config.StickModeHat))
+
+	set := cfg.GetActions(0, device.L2.Uint64())[0]
+	assert.Equal(config.StickModeOff, set.NextStickMode(config.StickModeJoystick))
+	assert.Equal(config.StickModeOff, set.NextStickMode(config.StickModeOff))
+
+	cfg.SetStickMode(config.StickModeScroll)
+	_, _, ok := cfg.GetScrollSpeed(stickInput(0, 0))
+	assert.True(ok)
+}
+
+func TestMenuActions(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir := t.TempDir()
+	for _, name := range []string{"mapping", "games"} {
+		assert.NoError(os.WriteFile(filepath.Join(tmpdir, name+".json"), []byte(`{
+	"mapping": {
+		"actions": {
+			"L1": {"type": "pause"},
+			"L2": {"type": "backlight", "colour": {"red": 255, "green": 128}},
+			"L3": {"type": "profile", "profile": "games"}
+		}
+	}
+}`), 0o660))
+	}
+	cfg, err := config.NewFromFile(filepath.Join(tmpdir, "mapping.json"))
+	assert.NoError(err)
+
+	assert.Equal([]config.Action{{Type: config.ActionPause}}, cfg.GetActions(0, device.L1.Uint64()))
+	assert.Equal([]config.Action{{Type: config.ActionBacklight, Colour: [3]uint8{255, 128, 0}}}, cfg.GetActions(0, device.L2.Uint64()))
+	assert.Equal([]config.Action{{Type: config.ActionProfile, Profile: "games"}}, cfg.GetActions(0, device.L3.Uint64()))
+
+	// BD opens the menu by default
+	assert.Equal([]config.Action{{Type: config.ActionMenu}}, cfg.GetActions(0, device.BD.Uint64()))
+	menuKey, ok := cfg.MenuKey()
+	assert.True(ok)
+	assert.Equal(device.BD, menuKey)
+
+	// missing profile
+	err = os.WriteFile(filepath.Join(tmpdir, "mapping.json"), []byte(`{"mapping":{"actions":{"L3":{"type":"profile","profile":"work"}}}}`), 0o660)
+	assert.NoError(err)
+	_, err = config.NewFromFile(filepath.Join(tmpdir, "mapping.json"))
+	assert.ErrorContains(err, "action for key L3: profile \"work\"")
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestMediaActions(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := loadTestConfig(t, `{
+	"mapping": {
+		"actions": {
+			"G1": {"type": "media-play-pause"},
+			"G2": {"type": "media-next"},
+			"G3": {"type": "media-previous"},
+			"G4": {"type": "media-seek", "offset_ms": -5000}
+		}
+	},
+	"media": {"bus": "unix:path=/tmp/bus", "player": "spotify"}
+}`)
+	assert.Equal([]config.Action{{Type: config.ActionMediaPlayPause}}, cfg.GetActions(0, device.G1.Uint64()))
+	assert.Equal([]config.Action{{Type: config.ActionMediaNext}}, cfg.GetActions(0, device.G2.Uint64()))
+	assert.Equal([]config.Action{{Type: config.ActionMediaPrevious}}, cfg.GetActions(0, device.G3.Uint64()))
+	assert.Equal([]config.Action{{Type: config.ActionMediaSeek, SeekOffset: -5 * time.Second}}, cfg.GetActions(0, device.G4.Uint64()))
+	assert.Equal(config.Media{Address: "unix:path=/tmp/bus", Player: "spotify"}, cfg.GetMedia())
+
+	labels := cfg.GetKeyLabels()
+	assert.Equal("Play", labels.Keys[device.G1])
+	assert.Equal("Next", labels.Keys[device.G2])
+	assert.Equal("Prev", labels.Keys[device.G3])
+	assert.Equal("Seek-", labels.Keys[device.G4])
+
+	// the session bus and any player by default
+	assert.Equal(config.Media{}, loadTestConfig(t, `{}`).GetMedia())
+
+	// the player is only used by media actions and widgets
+	assert.True(cfg.UsesMedia())
+	assert.True(loadTestConfig(t, `{"pages":[{"monitor":{"widgets":[{"type":"cpu"},{"type":"media"}]}}]}`).UsesMedia())
+	assert.False(loadTestConfig(t, `{"pages":[{"monitor":{"widgets":[{"type":"cpu"}]}}],"media":{"player":"vlc"}}`).UsesMedia())
+	assert.False(loadTestConfig(t, `{}`).UsesMedia())
+}
+
+func TestMenuKey(t *testing.T) {
+	type testCase struct {
+		configData string
+		menuKey    device.KeyBit
+		ok         bool
+	}
+
+	testCases := map[string]testCase{
+		"default": {
+			configData: `{}`,
+			menuKey:    device.BD,
+			ok:         true,
+		},
+		"bound": {
+			configData: `{"mapping":{"actions":{"M1":{"type":"menu"}}}}`,
+			menuKey:    device.M1,
+			ok:         true,
+		},
+		"bd-mapped-to-key": {
+			configData: `{"mapping":{"keys":{"BD":"KeyA"}}}`,
+		},
+		"bd-mapped-to-button": {
+			configData: `{"mapping":{"buttons":{"BD":"BtnA"}}}`,
+		},
+		"bd-bound-to-action": {
+			configData: `{"mapping":{"actions":{"BD":{"type":"pause"}}}}`,
+		},
+		"bd-radial-trigger": {
+			configData: `{"mapping":{"radial":[{"trigger":"BD","items":[{"keys":["KeyA"]}]}]}}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			cfg := loadTestConfig(t, tc.configData)
+
+			menuKey, ok := cfg.MenuKey()
+			assert.Equal(tc.ok, ok)
+			assert.Equal(tc.menuKey, menuKey)
+
+			var menuActions int
+			for _, action := range cfg.GetActions(0, device.BD.Uint64()|device.M1.Uint64()) {
+				if action.Type == config.ActionMenu {
+					menuActions++
+				}
+			}
+			if tc.ok {
+				assert.Equal(1, menuActions)
+			} else {
+				assert.Zero(menuActions)
+			}
+		})
+	}
+}
+
+func TestFindProfiles(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir := t.TempDir()
+	for _, name := range []string{"work.json", "games.json", "notes.txt", ".json"} {
+		assert.NoError(os.WriteFile(filepath.Join(tmpdir, name), []byte("{}"), 0o660))
+	}
+	assert.NoError(os.Mkdir(filepath.Join(tmpdir, "old.json"), 0o770))
+
+	profiles, err := config.FindProfiles(filepath.Join(tmpdir, "work.json"))
+	assert.NoError(err)
+	assert.Equal([]config.Profile{
+		{Name: "games", Path: filepath.Join(tmpdir, "games.json")},
+		{Name: "work", Path: filepath.Join(tmpdir, "work.json")},
+	}, profiles)
+
+	path, err := config.ProfilePath(filepath.Join(tmpdir, "work.json"), "games")
+	assert.NoError(err)
+	assert.Equal(filepath.Join(tmpdir, "games.json"), path)
+
+	for _, name := range []string{"", ".", "..", "sub/games"} {
+		_, err := config.ProfilePath(filepath.Join(tmpdir, "work.json"), name)
+		assert.EqualError(err, fmt.Sprintf("invalid profile name: %q", name))
+	}
+}
+
+func TestParseStickMode(t *testing.T) {
+	assert := assert.New(t)
+	for _, name := range []string{"off", "joystick", "keys", "mouse", "scroll", "hat", "absolute"} {
+		mode, err := config.ParseStickMode(name)
+		assert.NoError(err)
+		assert.Equal(name, mode.String())
+	}
+
+	mode, err := config.ParseStickMode("")
+	assert.NoError(err)
+	assert.Equal(config.StickModeOff, mode)
+
+	_, err = config.ParseStickMode("nope")
+	assert.EqualError(err, "unknown stick mode: nope")
+}
+
+func TestGetAbsolutePosition(t *testing.T) {
+	type testCase struct {
+		config     string
+		x, y       uint8
+		expX, expY float32
+	}
+
+	fullScreen := `{"mapping":{"stick":{"mode":"absolute"}}}`
+	region := `{"mapping":{"stick":{"mode":"absolute","absolute":{"region":{"x":0.5,"y":0.25,"width":0.5,"height":0.5}}}}}`
+	testCases := map[string]testCase{
+		"full-centre":       {config: fullScreen, x: 127, y: 127, expX: 0.5, expY: 0.5},
+		"full-top-left":     {config: fullScreen, x: 0, y: 0, expX: 0, expY: 0},
+		"full-bottom-right": {config: fullScreen, x: 255, y: 255, expX: 1, expY: 1},
+		"region-centre":     {config: region, x: 127, y: 127, expX: 0.75, expY: 0.5},
+		"region-top-left":   {config: region, x: 0, y: 0, expX: 0.5, expY: 0.25},
+		"region-right":      {config: region, x: 255, y: 127, expX: 1, expY: 0.5},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			cfg := loadTestConfig(t, tc.config)
+			assert.True(cfg.HasAbsoluteMode())
+			x, y, ok := cfg.GetAbsolutePosition(stickInput(tc.x, tc.y))
+			assert.True(ok)
+			assert.InDelta(tc.expX, x, 0.0001)
+			assert.InDelta(tc.expY, y, 0.0001)
+		})
+	}
+
+	t.Run("not-absolute-mode", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"mouse"}}}`)
+		assert.False(cfg.HasAbsoluteMode())
+		_, _, ok := cfg.GetAbsolutePosition(stickInput(0, 0))
+		assert.False(ok)
+	})
+
+	t.Run("reachable-by-action", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"mapping":{"stick":{"mode":"mouse"},"actions":{"BD":{"type":"stick-mode","mode":"absolute"}}}}`)
+		assert.True(cfg.HasAbsoluteMode())
+		_, _, ok := cfg.GetAbsolutePosition(stickInput(0, 0))
+		assert.False(ok)
+		cfg.SetStickMode(config.StickModeAbsolute)
+		_, _, ok = cfg.GetAbsolutePosition(stickInput(0, 0))
+		assert.True(ok)
+	})
+}
+
+func TestGetStickModes(t *testing.T) {
+	type testCase struct {
+		config   string
+		expected []config.StickMode
+	}
+
+	always := []config.StickMode{config.StickModeOff, config.StickModeJoystick, config.StickModeHat}
+	testCases := map[string]testCase{
+		"joystick": {
+			config:   `{"mapping":{"stick":{"mode":"joystick"}}}`,
+			expected: always,
+		},
+		"keys-without-keys": {
+			config:   `{"mapping":{"stick":{"mode":"keys"}}}`,
+			expected: []config.StickMode{config.StickModeOff, config.StickModeJoystick, config.StickModeKeys, config.StickModeHat},
+		},
+		"by-action": {
+			config: `{"mapping":{"stick":{"mode":"joystick","keys":{"up":"KeyW"}},"actions":{"BD":{"type":"stick-mode","mode":"cycle","modes":["mouse","scroll","keys","absolute"]}}}}`,
+			expected: []config.StickMode{
+				config.StickModeOff, config.StickModeJoystick, config.StickModeKeys, config.StickModeMouse,
+				config.StickModeScroll, config.StickModeHat, config.StickModeAbsolute,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			cfg := loadTestConfig(t, tc.config)
+			modes := cfg.GetStickModes()
+			assert.Equal(tc.expected, modes)
+
+			// the stick moves in every offered mode once it's switched to
+			for _, mode := range modes {
+				cfg.SetStickMode(mode)
+				switch mode {
+				case config.StickModeScroll:
+					x, y, _ := cfg.GetScrollSpeed(stickInput(255, 0))
+					assert.NotZero(x)
+					assert.NotZero(y)
+				case config.StickModeMouse:
+					x, y, _ := cfg.GetMouseSpeed(stickInput(255, 0))
+					assert.NotZero(x)
+					assert.NotZero(y)
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualDevices(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{}`)
+		assert.Equal("g13-vkb", cfg.GetKeyboardName())
+		assert.Equal("g13-vms", cfg.GetMouseName())
+
+		defaultPreset, err := joystick.Preset("default")
+		assert.NoError(err)
+		assert.Equal(defaultPreset, cfg.GetJoystickSettings())
+		assert.Equal("g13-vjs", cfg.GetJoystickSettings().Name)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{
+	"virtual_devices": {
+		"keyboard": {"name": "my keyboard"},
+		"mouse": {"name": "my mouse"},
+		"joystick": {"preset": "xbox360", "name": "my pad", "version": 2}
+	}
+}`)
+		assert.Equal("my keyboard", cfg.GetKeyboardName())
+		assert.Equal("my mouse", cfg.GetMouseName())
+
+		js := cfg.GetJoystickSettings()
+		assert.Equal("my pad", js.Name)
+		assert.Equal(uint16(0x045e), js.Vendor)
+		assert.Equal(uint16(0x028e), js.Product)
+		assert.Equal(uint16(2), js.Version)
+		assert.True(js.HasButton(joystick.BtnA))
+	})
+
+	t.Run("identity-only", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := loadTestConfig(t, `{"virtual_devices":{"joystick":{"vendor":1234,"product":5678}}}`)
+		js := cfg.GetJoystickSettings()
+		assert.Equal("g13-vjs", js.Name)
+		assert.Equal(uint16(1234), js.Vendor)
+		assert.Equal(uint16(5678), js.Product)
 	})
 }
+
+func TestGetButtonStates(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := loadTestConfig(t, `{"mapping":{"buttons":{"G1":"BtnA","G2":"BtnB","G3":"BtnB"}},"virtual_devices":{"joystick":{"preset":"xbox360"}}}`)
+	assert.Equal(map[int]bool{joystick.BtnA: false, joystick.BtnB: false}, cfg.GetButtonStates(0))
+	assert.Equal(map[int]bool{joystick.BtnA: true, joystick.BtnB: false}, cfg.GetButtonStates(device.G1.Uint64()))
+	// either key holds the shared button down
+	assert.Equal(map[int]bool{joystick.BtnA: false, joystick.BtnB: true}, cfg.GetButtonStates(device.G3.Uint64()))
+	assert.Equal(map[int]bool{joystick.BtnA: false, joystick.BtnB: true}, cfg.GetButtonStates(device.G2.Uint64()|device.G3.Uint64()))
+
+	assert.Nil(loadTestConfig(t, `{}`).GetButtonStates(device.G1.Uint64()))
+}
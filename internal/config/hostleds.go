@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/achilleas-k/gg13/internal/hostleds"
+)
+
+// HostLEDs configures showing the lock LEDs of the host's keyboards, like
+// Caps Lock, on the G13.
+type HostLEDs struct {
+	// evdev devices to watch (empty for every keyboard with lock LEDs).
+	Devices []string
+
+	// Backlight colours shown while LEDs are on. The first one whose LED is
+	// on is shown.
+	Backlight []LEDColour
+
+	// LED that the MR key LED follows (nil if it doesn't).
+	MR *hostleds.LED
+
+	// LEDs whose labels are shown in the top right corner of the LCD while
+	// they're on.
+	LCD []hostleds.LED
+}
+
+// LEDColour is a backlight colour shown while a lock LED is on.
+type LEDColour struct {
+	LED    hostleds.LED
+	Colour [3]uint8
+}
+
+type fileHostLEDs struct {
+	Devices   []string        `json:"devices"`
+	Backlight []fileLEDColour `json:"backlight"`
+	MR        string          `json:"mr_led"`
+	LCD       []string        `json:"lcd"`
+}
+
+type fileLEDColour struct {
+	LED    string               `json:"led"`
+	Colour *backlightFileConfig `json:"colour"`
+}
+
+// GetHostLEDs returns how the lock LEDs of the host's keyboards are shown. It
+// returns false if they aren't.
+func (cfg *G13Config) GetHostLEDs() (HostLEDs, bool) {
+	if cfg.hostLEDs == nil {
+		return HostLEDs{}, false
+	}
+	return *cfg.hostLEDs, true
+}
+
+func loadHostLEDs(fileLEDs *fileHostLEDs) (*HostLEDs, error) {
+	if fileLEDs == nil {
+		return nil, nil
+	}
+	if len(fileLEDs.Backlight) == 0 && fileLEDs.MR == "" && len(fileLEDs.LCD) == 0 {
+		return nil, fmt.Errorf("host LEDs set without backlight colours, MR LED, or LCD indicators")
+	}
+
+	leds := &HostLEDs{Devices: fileLEDs.Devices}
+	for idx, fc := range fileLEDs.Backlight {
+		led, err := hostleds.ParseLED(fc.LED)
+		if err != nil {
+			return nil, fmt.Errorf("host LED backlight colour %d: %w", idx+1, err)
+		}
+		if fc.Colour == nil {
+			return nil, fmt.Errorf("host LED backlight colour %d: colour not set", idx+1)
+		}
+		colour, err := fc.Colour.colour()
+		if err != nil {
+			return nil, fmt.Errorf("host LED backlight colour %d: %w", idx+1, err)
+		}
+		leds.Backlight = append(leds.Backlight, LEDColour{LED: led, Colour: colour})
+	}
+	if fileLEDs.MR != "" {
+		led, err := hostleds.ParseLED(fileLEDs.MR)
+		if err != nil {
+			return nil, fmt.Errorf("host LED for MR: %w", err)
+		}
+		leds.MR = &led
+	}
+	for _, name := range fileLEDs.LCD {
+		led, err := hostleds.ParseLED(name)
+		if err != nil {
+			return nil, fmt.Errorf("host LED for LCD: %w", err)
+		}
+		leds.LCD = append(leds.LCD, led)
+	}
+	return leds, nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/joystick"
+	"github.com/achilleas-k/gg13/internal/keyboard"
+	"github.com/achilleas-k/gg13/internal/keymap"
+)
+
+// Labels of actions with a fixed label.
+var actionLabels = map[ActionType]string{
+	ActionPageNext:     "Page>",
+	ActionPagePrevious: "<Page",
+	ActionPageSelect:   "Select",
+	ActionMenu:         "Menu",
+	ActionPause:        "Pause",
+	ActionBacklight:    "Light",
+
+	ActionMediaPlayPause: "Play",
+	ActionMediaNext:      "Next",
+	ActionMediaPrevious:  "Prev",
+
+	ActionTimerStop:  "Stop",
+	ActionTimerReset: "Reset",
+}
+
+// label returns a short description of an action.
+func (a Action) label() string {
+	switch a.Type {
+	case ActionStickMode:
+		if len(a.StickModes) == 1 {
+			return a.StickModes[0].String()
+		}
+		return "Stick"
+	case ActionProfile:
+		return a.Profile
+	case ActionTimerStart, ActionTimerToggle:
+		return a.Timer
+	case ActionMediaSeek:
+		if a.SeekOffset < 0 {
+			return "Seek-"
+		}
+		return "Seek+"
+	}
+	return actionLabels[a.Type]
+}
+
+// GetKeyLabels returns a short description of what each key and the stick is
+// bound to: keyboard keys and joystick buttons without their Key and Btn
+// prefixes, actions, and radial menu triggers.
+func (cfg *G13Config) GetKeyLabels() keymap.Labels {
+	labels := keymap.Labels{
+		Keys:  make(map[device.KeyBit]string),
+		Stick: cfg.GetStickMode().String(),
+	}
+	for gkey, kbKey := range cfg.mapping.keyMap {
+		labels.Keys[gkey] = strings.TrimPrefix(keyboard.KeyName(kbKey), "Key")
+	}
+	for gkey, button := range cfg.mapping.buttonMap {
+		labels.Keys[gkey] = strings.TrimPrefix(joystick.ButtonName(button), "Btn")
+	}
+	for gkey, action := range cfg.mapping.actions {
+		labels.Keys[gkey] = action.label()
+	}
+	if cfg.usesDefaultMenuKey() {
+		labels.Keys[defaultMenuKey] = actionLabels[ActionMenu]
+	}
+	for _, menu := range cfg.mapping.radialMenus {
+		labels.Keys[menu.Trigger] = "Radial"
+	}
+	return labels
+}
@@ -0,0 +1,731 @@
+package config
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/animation"
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/achilleas-k/gg13/internal/lcdpage"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/achilleas-k/gg13/internal/mpris"
+	"github.com/achilleas-k/gg13/internal/radial"
+)
+
+// Page actions bound to the keys under the LCD, if they aren't mapped to
+// anything else, when pages are configured.
+var defaultPageActions = map[device.KeyBit]Action{
+	device.L1: {Type: ActionPagePrevious},
+	device.L2: {Type: ActionPageNext},
+	device.L3: {Type: ActionPageSelect},
+}
+
+type imageCfg struct {
+	path       string
+	scale      lcdimage.ScaleMode
+	conversion lcdimage.Conversion
+}
+
+type animationCfg struct {
+	// path to an animated GIF or a directory of frames
+	path string
+
+	// frame rate overriding the delays of the animation (zero to keep them)
+	fps float64
+
+	// number of times to play the animation, overriding the loop count of the
+	// animation (nil to keep it, zero to loop forever)
+	loops *int
+
+	scale      lcdimage.ScaleMode
+	conversion lcdimage.Conversion
+}
+
+type textStyleCfg struct {
+	// font name or path to a font file, and its size for font files
+	font     string
+	fontSize float64
+
+	align   lcdtext.Align
+	inverse bool
+}
+
+type textCfg struct {
+	lines []string
+	style textStyleCfg
+
+	// speed of lines that scroll, in pixels per second (zero disables
+	// scrolling)
+	marqueeSpeed float64
+}
+
+type clockCfg struct {
+	timeFormat string
+	dateFormat string
+	style      textStyleCfg
+}
+
+type monitorCfg struct {
+	widgets  []lcdpage.Widget
+	interval time.Duration
+
+	// root of the /proc and /sys trees that statistics are read from
+	root string
+}
+
+// hasMedia returns true if any of the widgets shows the track being played.
+func (mon *monitorCfg) hasMedia() bool {
+	for _, widget := range mon.widgets {
+		if widget.Type == lcdpage.WidgetMedia {
+			return true
+		}
+	}
+	return false
+}
+
+type terminalCfg struct {
+	command  []string
+	dir      string
+	interval time.Duration
+}
+
+type lcdInputCfg struct {
+	socket string
+	fifo   string
+}
+
+// pageCfg is a single page of the display. Exactly one of the content fields
+// is set.
+type pageCfg struct {
+	name string
+
+	image     *imageCfg
+	animation *animationCfg
+	text      *textCfg
+	clock     *clockCfg
+	monitor   *monitorCfg
+	terminal  *terminalCfg
+
+	// show the key bindings
+	keymap bool
+}
+
+// HasAnimation returns true if an animation is configured for the display.
+func (cfg *G13Config) HasAnimation() bool {
+	return cfg.animation != nil
+}
+
+// GetAnimation loads the configured animation, with its frames ready to be
+// drawn on the LCD.
+func (cfg *G13Config) GetAnimation() (*animation.Animation, error) {
+	if cfg.animation == nil {
+		return nil, fmt.Errorf("no animation defined in config")
+	}
+	return cfg.animation.load()
+}
+
+// HasText returns true if text is configured for the display.
+func (cfg *G13Config) HasText() bool {
+	return cfg.text != nil
+}
+
+// GetTextAnimation renders the configured text as an animation, which has a
+// single frame unless some lines scroll.
+func (cfg *G13Config) GetTextAnimation() (*animation.Animation, error) {
+	if cfg.text == nil {
+		return nil, fmt.Errorf("no text defined in config")
+	}
+	return cfg.text.load()
+}
+
+// GetLCDInput returns the paths of the UNIX socket and the FIFO that other
+// programs push content to the display through. Empty paths are disabled.
+func (cfg *G13Config) GetLCDInput() (socket, fifo string) {
+	return cfg.lcdInput.socket, cfg.lcdInput.fifo
+}
+
+// GetPages loads the pages of the display. If no pages are configured but an
+// image, animation, or text is, it's returned as the only page. Media widgets
+// show the player of the media client, which is needed if [UsesMedia] returns
+// true.
+func (cfg *G13Config) GetPages(media *mpris.Client) ([]lcdpage.Page, error) {
+	if len(cfg.pages) == 0 {
+		switch {
+		case cfg.lcdImage != "":
+			img, err := cfg.GetImage()
+			if err != nil {
+				return nil, err
+			}
+			return []lcdpage.Page{lcdpage.NewStatic("image", img)}, nil
+		case cfg.animation != nil:
+			anim, err := cfg.GetAnimation()
+			if err != nil {
+				return nil, err
+			}
+			return []lcdpage.Page{lcdpage.NewAnimated("animation", anim)}, nil
+		case cfg.text != nil:
+			anim, err := cfg.GetTextAnimation()
+			if err != nil {
+				return nil, err
+			}
+			return []lcdpage.Page{lcdpage.NewAnimated("text", anim)}, nil
+		}
+		return nil, nil
+	}
+
+	pages := make([]lcdpage.Page, 0, len(cfg.pages))
+	for _, pc := range cfg.pages {
+		page, err := pc.load(cfg, media)
+		if err != nil {
+			return nil, fmt.Errorf("page %q: %w", pc.name, err)
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+func (pc pageCfg) load(cfg *G13Config, media *mpris.Client) (lcdpage.Page, error) {
+	switch {
+	case pc.image != nil:
+		img, err := lcdimage.Load(pc.image.path)
+		if err != nil {
+			return nil, err
+		}
+		img = pc.image.conversion.Apply(lcdimage.Fit(img, pc.image.scale))
+		return lcdpage.NewStatic(pc.name, img), nil
+	case pc.animation != nil:
+		anim, err := pc.animation.load()
+		if err != nil {
+			return nil, err
+		}
+		return lcdpage.NewAnimated(pc.name, anim), nil
+	case pc.text != nil:
+		anim, err := pc.text.load()
+		if err != nil {
+			return nil, err
+		}
+		return lcdpage.NewAnimated(pc.name, anim), nil
+	case pc.clock != nil:
+		style, err := pc.clock.style.load()
+		if err != nil {
+			return nil, err
+		}
+		return lcdpage.NewClock(pc.name, pc.clock.timeFormat, pc.clock.dateFormat, style), nil
+	case pc.keymap:
+		return lcdpage.NewKeymap(pc.name, cfg.GetKeyLabels), nil
+	case pc.terminal != nil:
+		return lcdpage.NewTerminal(pc.name, pc.terminal.command, pc.terminal.dir, pc.terminal.interval), nil
+	default:
+		if !pc.monitor.hasMedia() {
+			media = nil
+		}
+		return lcdpage.NewMonitor(pc.name, pc.monitor.widgets, pc.monitor.interval, pc.monitor.root, media), nil
+	}
+}
+
+func (animCfg *animationCfg) load() (*animation.Animation, error) {
+	anim, err := animation.Load(animCfg.path)
+	if err != nil {
+		return nil, err
+	}
+	if animCfg.fps > 0 {
+		anim.SetFPS(animCfg.fps)
+	}
+	if animCfg.loops != nil {
+		anim.Loops = *animCfg.loops
+	}
+	anim.Prepare(animCfg.scale, animCfg.conversion)
+	return anim, nil
+}
+
+func (text *textCfg) load() (*animation.Animation, error) {
+	style, err := text.style.load()
+	if err != nil {
+		return nil, err
+	}
+	return lcdtext.Marquee(text.lines, style, text.marqueeSpeed), nil
+}
+
+func (style textStyleCfg) load() (lcdtext.Style, error) {
+	face, err := lcdtext.LoadFont(style.font, style.fontSize)
+	if err != nil {
+		return lcdtext.Style{}, err
+	}
+	return lcdtext.Style{Face: face, Align: style.align, Inverse: style.inverse}, nil
+}
+
+// addDefaultPageActions binds the default page actions to the keys under the
+// LCD that aren't bound to anything else.
+func addDefaultPageActions(actions map[device.KeyBit]Action, boundKeys keyMap, menus []*radial.Menu) map[device.KeyBit]Action {
+	if actions == nil {
+		actions = make(map[device.KeyBit]Action, len(defaultPageActions))
+	}
+	for gKey, action := range defaultPageActions {
+		if _, bound := boundKeys[gKey]; bound {
+			continue
+		}
+		isTrigger := false
+		for _, menu := range menus {
+			isTrigger = isTrigger || menu.Trigger == gKey
+		}
+		if !isTrigger {
+			actions[gKey] = action
+		}
+	}
+	return actions
+}
+
+// fileLCDPage describes a page of the display. Exactly one of the content
+// fields must be set.
+type fileLCDPage struct {
+	Name      string         `json:"name"`
+	Image     *fileImage     `json:"image"`
+	Animation *fileAnimation `json:"animation"`
+	Text      *fileText      `json:"text"`
+	Clock     *fileClock     `json:"clock"`
+	Monitor   *fileMonitor   `json:"monitor"`
+	Keymap    *fileKeymap    `json:"keymap"`
+	Terminal  *fileTerminal  `json:"terminal"`
+}
+
+// fileImage describes an image shown on the display.
+type fileImage struct {
+	Path       string               `json:"path"`
+	Scale      string               `json:"scale"`
+	Conversion *fileImageConversion `json:"conversion"`
+}
+
+// fileAnimation describes an animation played on the display.
+type fileAnimation struct {
+	Path       string               `json:"path"`
+	FPS        float64              `json:"fps"`
+	Loops      *int                 `json:"loops"`
+	Scale      string               `json:"scale"`
+	Conversion *fileImageConversion `json:"conversion"`
+}
+
+// fileTextStyle describes the font and layout of text on the display.
+type fileTextStyle struct {
+	Font     string  `json:"font"`
+	FontSize float64 `json:"font_size"`
+	Align    string  `json:"align"`
+	Inverse  bool    `json:"inverse"`
+}
+
+// fileText describes text shown on the display.
+type fileText struct {
+	fileTextStyle
+	Lines        []string `json:"lines"`
+	MarqueeSpeed *float64 `json:"marquee_speed"`
+}
+
+// fileClock describes a clock shown on the display.
+type fileClock struct {
+	fileTextStyle
+	TimeFormat string `json:"time_format"`
+	DateFormat string `json:"date_format"`
+}
+
+// fileKeymap describes a page showing the key bindings. It has no settings.
+type fileKeymap struct{}
+
+// fileTerminal describes a page showing the output of a command. The command
+// is run again after each interval, or kept running if there is none.
+type fileTerminal struct {
+	Command    []string `json:"command"`
+	Dir        string   `json:"dir"`
+	IntervalMS int64    `json:"interval_ms"`
+}
+
+// fileMonitor describes a page of system statistics.
+type fileMonitor struct {
+	Widgets    []fileWidget `json:"widgets"`
+	IntervalMS int64        `json:"interval_ms"`
+
+	// root of the /proc and /sys trees, for reading statistics from a copy
+	Root string `json:"root"`
+}
+
+// fileWidget describes a widget of a monitor page. Widgets without a size are
+// stacked in rows.
+type fileWidget struct {
+	Type   string `json:"type"`
+	Label  string `json:"label"`
+	Source string `json:"source"`
+	Format string `json:"format"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// fileLCDInput enables pushing content to the display from other programs.
+// Paths can contain environment variables, like $XDG_RUNTIME_DIR.
+type fileLCDInput struct {
+	Socket string `json:"socket"`
+	FIFO   string `json:"fifo"`
+}
+
+// fileImageConversion describes the conversion of an image to black and white.
+type fileImageConversion struct {
+	Mode      string `json:"mode"`
+	Threshold *uint8 `json:"threshold"`
+	Invert    bool   `json:"invert"`
+}
+
+func loadPages(filePages []fileLCDPage, cfgPath string) ([]pageCfg, error) {
+	if len(filePages) == 0 {
+		return nil, nil
+	}
+
+	pages := make([]pageCfg, 0, len(filePages))
+	for idx, fp := range filePages {
+		page, err := loadPage(fp, cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", idx+1, err)
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+func loadPage(fp fileLCDPage, cfgPath string) (pageCfg, error) {
+	if countTrue(fp.Image != nil, fp.Animation != nil, fp.Text != nil, fp.Clock != nil, fp.Monitor != nil, fp.Keymap != nil, fp.Terminal != nil) != 1 {
+		return pageCfg{}, fmt.Errorf("exactly one of image, animation, text, clock, monitor, keymap, and terminal must be set")
+	}
+
+	page := pageCfg{name: fp.Name}
+	var err error
+	switch {
+	case fp.Image != nil:
+		page.image, err = loadImage(fp.Image, cfgPath)
+		if page.name == "" {
+			page.name = "image"
+		}
+	case fp.Animation != nil:
+		page.animation, err = loadAnimation(fp.Animation, cfgPath)
+		if page.name == "" {
+			page.name = "animation"
+		}
+	case fp.Text != nil:
+		page.text, err = loadText(fp.Text, cfgPath)
+		if page.name == "" {
+			page.name = "text"
+		}
+	case fp.Clock != nil:
+		page.clock, err = loadClock(fp.Clock, cfgPath)
+		if page.name == "" {
+			page.name = "clock"
+		}
+	case fp.Monitor != nil:
+		page.monitor, err = loadMonitor(fp.Monitor, cfgPath)
+		if page.name == "" {
+			page.name = "monitor"
+		}
+	case fp.Keymap != nil:
+		page.keymap = true
+		if page.name == "" {
+			page.name = "keymap"
+		}
+	case fp.Terminal != nil:
+		page.terminal, err = loadTerminal(fp.Terminal, cfgPath)
+		if page.name == "" {
+			page.name = "terminal"
+		}
+	}
+	if err != nil {
+		return pageCfg{}, err
+	}
+	return page, nil
+}
+
+func loadImage(fileImg *fileImage, cfgPath string) (*imageCfg, error) {
+	if fileImg.Path == "" {
+		return nil, fmt.Errorf("image path not set")
+	}
+	path, err := configRelativePath(cfgPath, fileImg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("image file %q (%s) set in config file does not exist", fileImg.Path, path)
+	}
+
+	scale, err := lcdimage.ParseScaleMode(fileImg.Scale)
+	if err != nil {
+		return nil, err
+	}
+	conv, err := loadImageConversion(fileImg.Conversion)
+	if err != nil {
+		return nil, err
+	}
+	img := &imageCfg{
+		path:       path,
+		scale:      scale,
+		conversion: lcdimage.DefaultConversion(),
+	}
+	if conv != nil {
+		img.conversion = *conv
+	}
+	return img, nil
+}
+
+func loadAnimation(fileAnim *fileAnimation, cfgPath string) (*animationCfg, error) {
+	if fileAnim == nil {
+		return nil, nil
+	}
+	if fileAnim.Path == "" {
+		return nil, fmt.Errorf("animation path not set")
+	}
+	if fileAnim.FPS < 0 {
+		return nil, fmt.Errorf("invalid animation frame rate %v: must not be negative", fileAnim.FPS)
+	}
+	if fileAnim.Loops != nil && *fileAnim.Loops < 0 {
+		return nil, fmt.Errorf("invalid animation loop count %d: must not be negative", *fileAnim.Loops)
+	}
+
+	path, err := configRelativePath(cfgPath, fileAnim.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("animation %q (%s) set in config file does not exist", fileAnim.Path, path)
+	}
+
+	scale, err := lcdimage.ParseScaleMode(fileAnim.Scale)
+	if err != nil {
+		return nil, err
+	}
+	conv, err := loadImageConversion(fileAnim.Conversion)
+	if err != nil {
+		return nil, err
+	}
+	anim := &animationCfg{
+		path:       path,
+		fps:        fileAnim.FPS,
+		loops:      fileAnim.Loops,
+		scale:      scale,
+		conversion: lcdimage.DefaultConversion(),
+	}
+	if conv != nil {
+		anim.conversion = *conv
+	}
+	return anim, nil
+}
+
+func loadText(fileText *fileText, cfgPath string) (*textCfg, error) {
+	if fileText == nil {
+		return nil, nil
+	}
+	if len(fileText.Lines) == 0 {
+		return nil, fmt.Errorf("text lines not set")
+	}
+	style, err := loadTextStyle(fileText.fileTextStyle, cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	text := &textCfg{
+		lines:        fileText.Lines,
+		style:        style,
+		marqueeSpeed: lcdtext.DefaultMarqueeSpeed,
+	}
+	if speed := fileText.MarqueeSpeed; speed != nil {
+		if *speed < 0 {
+			return nil, fmt.Errorf("invalid marquee speed %v: must not be negative", *speed)
+		}
+		text.marqueeSpeed = *speed
+	}
+	return text, nil
+}
+
+func loadClock(fileClock *fileClock, cfgPath string) (*clockCfg, error) {
+	style, err := loadTextStyle(fileClock.fileTextStyle, cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	return &clockCfg{
+		timeFormat: fileClock.TimeFormat,
+		dateFormat: fileClock.DateFormat,
+		style:      style,
+	}, nil
+}
+
+func loadTerminal(fileTerm *fileTerminal, cfgPath string) (*terminalCfg, error) {
+	if len(fileTerm.Command) == 0 || fileTerm.Command[0] == "" {
+		return nil, fmt.Errorf("terminal command not set")
+	}
+	if fileTerm.IntervalMS < 0 {
+		return nil, fmt.Errorf("invalid terminal interval %d: must not be negative", fileTerm.IntervalMS)
+	}
+
+	term := &terminalCfg{
+		command:  fileTerm.Command,
+		interval: time.Duration(fileTerm.IntervalMS) * time.Millisecond,
+	}
+	if fileTerm.Dir != "" {
+		dir, err := configRelativePath(cfgPath, fileTerm.Dir)
+		if err != nil {
+			return nil, err
+		}
+		term.dir = dir
+	}
+	return term, nil
+}
+
+func loadMonitor(fileMon *fileMonitor, cfgPath string) (*monitorCfg, error) {
+	if len(fileMon.Widgets) == 0 {
+		return nil, fmt.Errorf("monitor widgets not set")
+	}
+	if fileMon.IntervalMS < 0 {
+		return nil, fmt.Errorf("invalid monitor interval %d: must not be negative", fileMon.IntervalMS)
+	}
+
+	mon := &monitorCfg{interval: time.Duration(fileMon.IntervalMS) * time.Millisecond}
+	if fileMon.Root != "" {
+		root, err := configRelativePath(cfgPath, fileMon.Root)
+		if err != nil {
+			return nil, err
+		}
+		mon.root = root
+	}
+
+	stacked := 0
+	for idx, fw := range fileMon.Widgets {
+		widget, err := loadWidget(fw)
+		if err != nil {
+			return nil, fmt.Errorf("widget %d: %w", idx+1, err)
+		}
+		if widget.Area.Empty() {
+			stacked++
+		}
+		mon.widgets = append(mon.widgets, widget)
+	}
+	if maxRows := device.LCDHeight / lcdpage.WidgetRowHeight; stacked > maxRows {
+		return nil, fmt.Errorf("too many monitor widgets without a size: %d (at most %d fit)", stacked, maxRows)
+	}
+	return mon, nil
+}
+
+func loadWidget(fw fileWidget) (lcdpage.Widget, error) {
+	wt, err := lcdpage.ParseWidgetType(fw.Type)
+	if err != nil {
+		return lcdpage.Widget{}, err
+	}
+	if fw.Format != "" && wt != lcdpage.WidgetClock {
+		return lcdpage.Widget{}, fmt.Errorf("format set for %s widget", wt)
+	}
+	switch wt {
+	case lcdpage.WidgetNetwork, lcdpage.WidgetDisk, lcdpage.WidgetTemperature:
+	default:
+		if fw.Source != "" {
+			return lcdpage.Widget{}, fmt.Errorf("source set for %s widget", wt)
+		}
+	}
+
+	widget := lcdpage.Widget{
+		Type:   wt,
+		Label:  fw.Label,
+		Source: fw.Source,
+		Format: fw.Format,
+	}
+	if fw.X == 0 && fw.Y == 0 && fw.Width == 0 && fw.Height == 0 {
+		return widget, nil
+	}
+	area := image.Rect(fw.X, fw.Y, fw.X+fw.Width, fw.Y+fw.Height)
+	if fw.Width <= 0 || fw.Height <= 0 || fw.X < 0 || fw.Y < 0 || !area.In(image.Rect(0, 0, device.LCDWidth, device.LCDHeight)) {
+		return lcdpage.Widget{}, fmt.Errorf("invalid widget area %v: must have a size and fit on the %dx%d display", area, device.LCDWidth, device.LCDHeight)
+	}
+	widget.Area = area
+	return widget, nil
+}
+
+func loadLCDInput(fileInput fileLCDInput, cfgPath string) (lcdInputCfg, error) {
+	var input lcdInputCfg
+	for _, path := range []struct {
+		name string
+		from string
+		to   *string
+	}{
+		{"socket", fileInput.Socket, &input.socket},
+		{"FIFO", fileInput.FIFO, &input.fifo},
+	} {
+		if path.from == "" {
+			continue
+		}
+		expanded := os.ExpandEnv(path.from)
+		if expanded == "" {
+			return lcdInputCfg{}, fmt.Errorf("LCD input %s path %q is empty after expanding environment variables", path.name, path.from)
+		}
+		abs, err := configRelativePath(cfgPath, expanded)
+		if err != nil {
+			return lcdInputCfg{}, err
+		}
+		*path.to = abs
+	}
+	if input.socket != "" && input.socket == input.fifo {
+		return lcdInputCfg{}, fmt.Errorf("LCD input socket and FIFO have the same path: %s", input.socket)
+	}
+	return input, nil
+}
+
+func loadTextStyle(fileStyle fileTextStyle, cfgPath string) (textStyleCfg, error) {
+	if fileStyle.FontSize < 0 {
+		return textStyleCfg{}, fmt.Errorf("invalid font size %v: must not be negative", fileStyle.FontSize)
+	}
+	align, err := lcdtext.ParseAlign(fileStyle.Align)
+	if err != nil {
+		return textStyleCfg{}, err
+	}
+	style := textStyleCfg{
+		font:     fileStyle.Font,
+		fontSize: fileStyle.FontSize,
+		align:    align,
+		inverse:  fileStyle.Inverse,
+	}
+
+	switch style.font {
+	case "", lcdtext.FontBuiltin, lcdtext.FontBasic:
+	default:
+		// anything else is a font file
+		style.font, err = configRelativePath(cfgPath, style.font)
+		if err != nil {
+			return textStyleCfg{}, err
+		}
+		if _, err := os.Stat(style.font); os.IsNotExist(err) {
+			return textStyleCfg{}, fmt.Errorf("font file %q (%s) set in config file does not exist", fileStyle.Font, style.font)
+		}
+	}
+	return style, nil
+}
+
+func loadImageConversion(fileConv *fileImageConversion) (*lcdimage.Conversion, error) {
+	if fileConv == nil {
+		return nil, nil
+	}
+	mode, err := lcdimage.ParseConversionMode(fileConv.Mode)
+	if err != nil {
+		return nil, err
+	}
+	conv := lcdimage.DefaultConversion()
+	conv.Mode = mode
+	if fileConv.Threshold != nil {
+		conv.Threshold = *fileConv.Threshold
+	}
+	conv.Invert = fileConv.Invert
+	return &conv, nil
+}
+
+// countTrue returns the number of true values.
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
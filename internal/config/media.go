@@ -0,0 +1,44 @@
+package config
+
+// Media configures the media player that media widgets show and media actions
+// control.
+type Media struct {
+	// Address of the bus the player is on (empty for the session bus).
+	Address string
+
+	// Start of the name of the player to use after the MPRIS prefix, like
+	// "spotify" (empty for the first player that is playing).
+	Player string
+}
+
+type fileMedia struct {
+	Bus    string `json:"bus"`
+	Player string `json:"player"`
+}
+
+// GetMedia returns the media player that media widgets show and media actions
+// control.
+func (cfg *G13Config) GetMedia() Media {
+	return cfg.media
+}
+
+// UsesMedia returns true if media actions or media widgets use the media
+// player, so that a client for it is needed.
+func (cfg *G13Config) UsesMedia() bool {
+	for _, action := range cfg.mapping.actions {
+		switch action.Type {
+		case ActionMediaPlayPause, ActionMediaNext, ActionMediaPrevious, ActionMediaSeek:
+			return true
+		}
+	}
+	for _, pc := range cfg.pages {
+		if pc.monitor != nil && pc.monitor.hasMedia() {
+			return true
+		}
+	}
+	return false
+}
+
+func loadMedia(fileMed fileMedia) Media {
+	return Media{Address: fileMed.Bus, Player: fileMed.Player}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/notify"
+)
+
+// Defaults for showing desktop notifications.
+const (
+	DefaultNotificationTimeout = 5 * time.Second
+	DefaultFlashDuration       = 300 * time.Millisecond
+)
+
+// Notifications configures showing desktop notifications on the LCD.
+type Notifications struct {
+	// Address of the bus to listen on (empty for the session bus).
+	Address string
+
+	// Applications whose notifications are shown.
+	Filter notify.Filter
+
+	// How long each notification is shown.
+	Timeout time.Duration
+
+	// Colour the backlight flashes for each notification, and for how long
+	// (nil if the backlight doesn't flash).
+	Flash         *[3]uint8
+	FlashDuration time.Duration
+}
+
+type fileNotifications struct {
+	Bus       string               `json:"bus"`
+	Allow     []string             `json:"allow"`
+	Deny      []string             `json:"deny"`
+	TimeoutMS int64                `json:"timeout_ms"`
+	Flash     *backlightFileConfig `json:"flash"`
+	FlashMS   int64                `json:"flash_ms"`
+}
+
+// GetNotifications returns how desktop notifications are shown on the LCD. It
+// returns false if they aren't.
+func (cfg *G13Config) GetNotifications() (Notifications, bool) {
+	if cfg.notifications == nil {
+		return Notifications{}, false
+	}
+	return *cfg.notifications, true
+}
+
+func loadNotifications(fileNotif *fileNotifications) (*Notifications, error) {
+	if fileNotif == nil {
+		return nil, nil
+	}
+	if fileNotif.TimeoutMS < 0 {
+		return nil, fmt.Errorf("invalid notification timeout %d: must not be negative", fileNotif.TimeoutMS)
+	}
+	if fileNotif.FlashMS < 0 {
+		return nil, fmt.Errorf("invalid notification flash duration %d: must not be negative", fileNotif.FlashMS)
+	}
+	if fileNotif.FlashMS > 0 && fileNotif.Flash == nil {
+		return nil, fmt.Errorf("notification flash duration set without a flash colour")
+	}
+
+	notif := &Notifications{
+		Address:       fileNotif.Bus,
+		Filter:        notify.Filter{Allow: fileNotif.Allow, Deny: fileNotif.Deny},
+		Timeout:       DefaultNotificationTimeout,
+		FlashDuration: DefaultFlashDuration,
+	}
+	if fileNotif.TimeoutMS > 0 {
+		notif.Timeout = time.Duration(fileNotif.TimeoutMS) * time.Millisecond
+	}
+	if fileNotif.Flash != nil {
+		colour, err := fileNotif.Flash.colour()
+		if err != nil {
+			return nil, fmt.Errorf("notification flash: %w", err)
+		}
+		notif.Flash = &colour
+		if fileNotif.FlashMS > 0 {
+			notif.FlashDuration = time.Duration(fileNotif.FlashMS) * time.Millisecond
+		}
+	}
+	return notif, nil
+}
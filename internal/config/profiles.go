@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile is a config file that can be switched to at runtime. Profiles are
+// the config files in the same directory as the loaded config.
+type Profile struct {
+	Name string
+	Path string
+}
+
+const profileExt = ".json"
+
+// ProfilePath returns the path of the profile with the given name for the
+// config file at cfgPath.
+func ProfilePath(cfgPath, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return "", fmt.Errorf("invalid profile name: %q", name)
+	}
+	return configRelativePath(cfgPath, name+profileExt)
+}
+
+// FindProfiles returns the profiles available to the config file at cfgPath,
+// sorted by name.
+func FindProfiles(cfgPath string) ([]Profile, error) {
+	cfgDir, err := filepath.Abs(filepath.Dir(cfgPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of config file %q: %w", cfgPath, err)
+	}
+	entries, err := os.ReadDir(cfgDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading profiles directory %q: %w", cfgDir, err)
+	}
+
+	var profiles []Profile
+	for _, entry := range entries {
+		name, isProfile := strings.CutSuffix(entry.Name(), profileExt)
+		if !isProfile || name == "" || entry.IsDir() {
+			continue
+		}
+		profiles = append(profiles, Profile{Name: name, Path: filepath.Join(cfgDir, entry.Name())})
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/timers"
+)
+
+// Timers configures named stopwatches and countdowns, which are started and
+// stopped by timer actions.
+type Timers struct {
+	Timers []timers.Timer
+
+	// Colour the backlight flashes when a countdown or cooldown expires, and
+	// for how long (nil if the backlight doesn't flash).
+	Flash         *[3]uint8
+	FlashDuration time.Duration
+}
+
+// Cooldown is a countdown started each time a key is pressed, whatever the
+// key is bound to.
+type Cooldown struct {
+	Name     string
+	Duration time.Duration
+}
+
+type fileTimers struct {
+	Items   []fileTimer          `json:"items"`
+	Flash   *backlightFileConfig `json:"flash"`
+	FlashMS int64                `json:"flash_ms"`
+}
+
+type fileTimer struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type fileCooldown struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// GetTimers returns the configured timers and how their expiry is shown.
+func (cfg *G13Config) GetTimers() Timers {
+	return cfg.timers
+}
+
+// GetCooldowns returns the cooldowns of the keys that were pressed between the
+// previous and current input (from [device.ReadInput]).
+func (cfg *G13Config) GetCooldowns(prevInput, input uint64) []Cooldown {
+	pressed := input &^ prevInput
+	if pressed == 0 {
+		return nil
+	}
+
+	var cooldowns []Cooldown
+	for _, gkey := range device.AllKeys() {
+		if gkey.Uint64()&pressed == 0 {
+			continue
+		}
+		if cooldown, ok := cfg.mapping.cooldowns[gkey]; ok {
+			cooldowns = append(cooldowns, cooldown)
+		}
+	}
+	return cooldowns
+}
+
+func loadTimers(fileTim fileTimers) (Timers, error) {
+	if fileTim.FlashMS < 0 {
+		return Timers{}, fmt.Errorf("invalid timer flash duration %d: must not be negative", fileTim.FlashMS)
+	}
+	if fileTim.FlashMS > 0 && fileTim.Flash == nil {
+		return Timers{}, fmt.Errorf("timer flash duration set without a flash colour")
+	}
+
+	var tim Timers
+	if fileTim.Flash != nil {
+		colour, err := fileTim.Flash.colour()
+		if err != nil {
+			return Timers{}, fmt.Errorf("timer flash: %w", err)
+		}
+		tim.Flash = &colour
+		tim.FlashDuration = DefaultFlashDuration
+		if fileTim.FlashMS > 0 {
+			tim.FlashDuration = time.Duration(fileTim.FlashMS) * time.Millisecond
+		}
+	}
+
+	names := make(map[string]bool, len(fileTim.Items))
+	for idx, ft := range fileTim.Items {
+		if ft.Name == "" {
+			return Timers{}, fmt.Errorf("timer %d: name not set", idx+1)
+		}
+		if names[ft.Name] {
+			return Timers{}, fmt.Errorf("timer %d: name %q used for more than one timer", idx+1, ft.Name)
+		}
+		names[ft.Name] = true
+
+		kind, err := timers.ParseKind(ft.Type)
+		if err != nil {
+			return Timers{}, fmt.Errorf("timer %d: %w", idx+1, err)
+		}
+		timer := timers.Timer{Name: ft.Name, Kind: kind}
+		switch kind {
+		case timers.Countdown:
+			if ft.DurationMS <= 0 {
+				return Timers{}, fmt.Errorf("timer %d: invalid countdown duration %d: must be positive", idx+1, ft.DurationMS)
+			}
+			timer.Duration = time.Duration(ft.DurationMS) * time.Millisecond
+		case timers.Stopwatch:
+			if ft.DurationMS != 0 {
+				return Timers{}, fmt.Errorf("timer %d: duration set for stopwatch", idx+1)
+			}
+		}
+		tim.Timers = append(tim.Timers, timer)
+	}
+	return tim, nil
+}
+
+// hasTimer returns true if a timer with the name is configured.
+func (tim Timers) hasTimer(name string) bool {
+	for _, timer := range tim.Timers {
+		if timer.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCooldowns reads the cooldowns of keys. Cooldowns are named after their
+// key unless a name is set, and can't share a name with a timer.
+func loadCooldowns(fileCooldowns map[string]fileCooldown, tim Timers) (map[device.KeyBit]Cooldown, error) {
+	if len(fileCooldowns) == 0 {
+		return nil, nil
+	}
+
+	cooldowns := make(map[device.KeyBit]Cooldown, len(fileCooldowns))
+	for gKeyStr, fc := range fileCooldowns {
+		gKey := device.KeyCode(gKeyStr)
+		if gKey == 0 {
+			return nil, fmt.Errorf("unknown G13 key name for cooldown: %s", gKeyStr)
+		}
+		if fc.DurationMS <= 0 {
+			return nil, fmt.Errorf("cooldown for key %s: invalid duration %d: must be positive", gKeyStr, fc.DurationMS)
+		}
+		name := fc.Name
+		if name == "" {
+			name = gKeyStr
+		}
+		if tim.hasTimer(name) {
+			return nil, fmt.Errorf("cooldown for key %s: name %q is also used for a timer", gKeyStr, name)
+		}
+		cooldowns[gKey] = Cooldown{Name: name, Duration: time.Duration(fc.DurationMS) * time.Millisecond}
+	}
+	return cooldowns, nil
+}
+
+// checkTimerActions returns an error if a timer action refers to a timer that
+// isn't configured.
+func checkTimerActions(actions map[device.KeyBit]Action, tim Timers) error {
+	for gKey, action := range actions {
+		if action.Timer != "" && !tim.hasTimer(action.Timer) {
+			return fmt.Errorf("action for key %s: unknown timer: %q", gKey, action.Timer)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ type Device interface {
 	ReadBytes() ([]byte, error)
 	ReadInput() (uint64, error)
 	SetBacklightColour(r, g, b uint8) error
+	SetModeLEDs(leds uint8) error
 	SetLCD(image.Image) error
 	ResetLCD() error
 }
@@ -108,6 +109,9 @@ func (d *G13Device) Close() {
 		if err := d.ResetBacklightColour(); err != nil {
 			fmt.Fprintf(os.Stderr, "error resetting backlight during shutdown: %s\n", err)
 		}
+		if err := d.SetModeLEDs(0); err != nil {
+			fmt.Fprintf(os.Stderr, "error resetting mode LEDs during shutdown: %s\n", err)
+		}
 		if err := d.ResetLCD(); err != nil {
 			fmt.Fprintf(os.Stderr, "error resetting LCD during shutdown: %s\n", err)
 		}
@@ -2,3 +2,5 @@ package device
 
 // export private functions for testing
 var BtoiLE = btoiLE
+var ImageToG13Bytes = imageToG13Bytes
+var G13BytesToImage = g13BytesToImage
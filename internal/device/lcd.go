@@ -3,6 +3,7 @@ package device
 import (
 	"fmt"
 	"image"
+	"image/color"
 
 	"github.com/google/gousb"
 )
@@ -13,6 +14,8 @@ const (
 
 	BacklightColourVal = uint16(0x307)
 
+	ModeLEDsVal = uint16(0x305)
+
 	SetupPacketRequest = uint8(9)
 
 	SetupPacketIndex = uint16(0)
@@ -32,6 +35,9 @@ const (
 	// Magic number that needs to be set as the first byte of the byte array to
 	// send when writing to the LCD
 	LCDMagicNumber = 3
+
+	// Luminance below which pixels are turned on
+	lcdThreshold = 128
 )
 
 func (d *G13Device) SetBacklightColour(r, g, b uint8) error {
@@ -51,14 +57,32 @@ func (d *G13Device) ResetBacklightColour() error {
 	return d.SetBacklightColour(uint8(0), uint8(0), uint8(0))
 }
 
-func (d *G13Device) SetLCD(img image.Image) error {
-	bounds := img.Bounds()
-	if bounds.Min.X != 0 || bounds.Min.Y != 0 {
-		return fmt.Errorf("invalid image: bounds to not start at 0,0")
+// LEDs of the mode keys, combined with | for [G13Device.SetModeLEDs].
+const (
+	LEDM1 = uint8(1 << iota)
+	LEDM2
+	LEDM3
+	LEDMR
+)
+
+// SetModeLEDs turns on the LEDs of the mode keys that are set in leds and
+// turns off the rest.
+func (d *G13Device) SetModeLEDs(leds uint8) error {
+	data := []byte{5, leds, 0, 0, 0}
+	n, err := d.dev.Control(ControlRequestType, SetupPacketRequest, ModeLEDsVal, SetupPacketIndex, data)
+	if err != nil {
+		return fmt.Errorf("failed setting mode LEDs %+v: %w", data, err)
 	}
-	if bounds.Max.X != LCDWidth || bounds.Max.Y != LCDHeight {
-		return fmt.Errorf("image data has incorrect size %dx%d: %dx%d required", bounds.Max.X, bounds.Max.Y, LCDWidth, LCDHeight)
+	if n != len(data) {
+		return fmt.Errorf("sent %d bytes but wrote %d while setting mode LEDs", len(data), n)
 	}
+	return nil
+}
+
+// SetLCD draws the image on the LCD. The top left corner of the image is placed
+// at the top left corner of the LCD. Images larger than the LCD are cropped and
+// any area not covered by a smaller image is left blank.
+func (d *G13Device) SetLCD(img image.Image) error {
 	data := imageToG13Bytes(img)
 
 	n, err := d.oep.Write(data)
@@ -116,13 +140,14 @@ func imageToG13Bytes(img image.Image) []uint8 {
 	// run through the image and for each position, find the appropriate bit in
 	// the appropriate byte of the LCD to flip
 
-	bounds := img.Bounds() // must be 160x43
-	for y := range bounds.Max.Y {
-		for x := range bounds.Max.X {
-			r, g, b, _ := img.At(x, y).RGBA()
-			// convert the image to monochrome by turning on any non-white
-			// pixels
-			if r+g+b < 255*3 {
+	bounds := img.Bounds()
+	for y := range min(bounds.Dy(), LCDHeight) {
+		for x := range min(bounds.Dx(), LCDWidth) {
+			// convert the image to monochrome by turning on any pixels darker
+			// than mid-grey; images that need better conversion should be
+			// dithered before being drawn
+			lum := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			if lum < lcdThreshold {
 				byteIdx := y/8*LCDWidth + x // index of the byte that represents the 8-pixel column we're in
 				bitIdx := y % 8             // index of the bit (within the byte) to flip on
 
@@ -133,3 +158,25 @@ func imageToG13Bytes(img image.Image) []uint8 {
 	}
 	return vbitmap
 }
+
+// LCDPreview returns the image that the LCD shows when img is drawn on it, by
+// decoding the data that [G13Device.SetLCD] sends to the device. Pixels that
+// are turned on are black and the rest are white.
+func LCDPreview(img image.Image) *image.Gray {
+	return g13BytesToImage(imageToG13Bytes(img))
+}
+
+// g13BytesToImage is the inverse of imageToG13Bytes.
+func g13BytesToImage(data []uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, LCDWidth, LCDHeight))
+	for y := range LCDHeight {
+		for x := range LCDWidth {
+			byteIdx := y/8*LCDWidth + x
+			on := data[byteIdx+LCDImageStartIdx]&(uint8(1)<<(y%8)) != 0
+			if !on {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,91 @@
+package device_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/stretchr/testify/assert"
+)
+
+// pixelOn returns true if the pixel at x, y is turned on in the LCD data.
+func pixelOn(data []uint8, x, y int) bool {
+	return data[device.LCDImageStartIdx+y/8*device.LCDWidth+x]&(1<<(y%8)) != 0
+}
+
+func TestImageToG13Bytes(t *testing.T) {
+	t.Run("full-size", func(t *testing.T) {
+		assert := assert.New(t)
+		img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+		for idx := range img.Pix {
+			img.Pix[idx] = 0xff
+		}
+		img.SetGray(0, 0, color.Gray{})
+		img.SetGray(159, 42, color.Gray{})
+
+		data := device.ImageToG13Bytes(img)
+		assert.Len(data, device.LCDDataLength)
+		assert.Equal(uint8(device.LCDMagicNumber), data[0])
+		assert.True(pixelOn(data, 0, 0))
+		assert.True(pixelOn(data, 159, 42))
+		assert.False(pixelOn(data, 1, 0))
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		assert := assert.New(t)
+		img := image.NewGray(image.Rect(0, 0, 3, 1))
+		img.Pix = []uint8{0x7f, 0x80, 0xff}
+
+		data := device.ImageToG13Bytes(img)
+		assert.True(pixelOn(data, 0, 0))
+		assert.False(pixelOn(data, 1, 0))
+		assert.False(pixelOn(data, 2, 0))
+	})
+
+	t.Run("small-offset", func(t *testing.T) {
+		assert := assert.New(t)
+		// 2x2 black image with bounds that don't start at 0,0
+		img := image.NewGray(image.Rect(10, 20, 12, 22))
+
+		data := device.ImageToG13Bytes(img)
+		assert.True(pixelOn(data, 0, 0))
+		assert.True(pixelOn(data, 1, 1))
+		assert.False(pixelOn(data, 2, 0))
+		assert.False(pixelOn(data, 0, 2))
+		assert.False(pixelOn(data, 10, 20))
+	})
+
+	t.Run("large", func(t *testing.T) {
+		assert := assert.New(t)
+		img := image.NewGray(image.Rect(0, 0, 400, 300))
+		data := device.ImageToG13Bytes(img)
+		assert.Len(data, device.LCDDataLength)
+		assert.True(pixelOn(data, 159, 42))
+	})
+}
+
+func TestLCDPreview(t *testing.T) {
+	assert := assert.New(t)
+
+	// a small grey image: the preview is cropped to what the LCD shows,
+	// thresholded, and padded with blank pixels
+	img := image.NewGray(image.Rect(10, 10, 14, 12))
+	img.Pix = []uint8{
+		0x00, 0x7f, 0x80, 0xff,
+		0xff, 0x80, 0x7f, 0x00,
+	}
+	preview := device.LCDPreview(img)
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), preview.Bounds())
+
+	black, white := color.Gray{}, color.Gray{Y: 0xff}
+	assert.Equal([]color.Gray{black, black, white, white}, []color.Gray{preview.GrayAt(0, 0), preview.GrayAt(1, 0), preview.GrayAt(2, 0), preview.GrayAt(3, 0)})
+	assert.Equal([]color.Gray{white, white, black, black}, []color.Gray{preview.GrayAt(0, 1), preview.GrayAt(1, 1), preview.GrayAt(2, 1), preview.GrayAt(3, 1)})
+	assert.Equal(white, preview.GrayAt(4, 0))
+	assert.Equal(white, preview.GrayAt(159, 42))
+
+	// decoding is the inverse of encoding
+	data := device.ImageToG13Bytes(preview)
+	assert.Equal(data, device.ImageToG13Bytes(device.G13BytesToImage(data)))
+	assert.Equal(preview, device.G13BytesToImage(data))
+}
@@ -11,3 +11,9 @@ func StickPosition(input uint64) (uint8, uint8) {
 	y := (input & YMask) >> 16 // (input & (255 << 16) >> 16)
 	return uint8(x), uint8(y)
 }
+
+// CentreStick returns the input with the stick position replaced by the centre
+// position, leaving the key states unchanged.
+func CentreStick(input uint64) uint64 {
+	return input&^(XMask|YMask) | 127<<8 | 127<<16
+}
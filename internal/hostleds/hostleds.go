@@ -0,0 +1,301 @@
+// Package hostleds watches the lock LEDs (Num Lock, Caps Lock, and Scroll
+// Lock) of the host's keyboards through their evdev devices.
+package hostleds
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Event types from linux/input-event-codes.h
+const (
+	evLED = 0x11
+)
+
+// ioctl requests from linux/input.h for the LED state and capabilities of a
+// device, reading a bitmap of ledBytes bytes
+const (
+	ledBytes     = 1
+	eviocgLED    = 2<<30 | ledBytes<<16 | 'E'<<8 | 0x19
+	eviocgBitLED = 2<<30 | ledBytes<<16 | 'E'<<8 | (0x20 + evLED)
+)
+
+// Pattern of the evdev devices searched for keyboards.
+const devicePattern = "/dev/input/event*"
+
+// LED is a lock LED of a keyboard. The values are the evdev LED codes.
+type LED uint16
+
+const (
+	NumLock LED = iota
+	CapsLock
+	ScrollLock
+)
+
+// AllLEDs returns the lock LEDs in order.
+func AllLEDs() []LED {
+	return []LED{NumLock, CapsLock, ScrollLock}
+}
+
+var ledNames = map[LED]string{
+	NumLock:    "num-lock",
+	CapsLock:   "caps-lock",
+	ScrollLock: "scroll-lock",
+}
+
+// Short names of the LEDs shown on the LCD.
+var ledLabels = map[LED]string{
+	NumLock:    "NUM",
+	CapsLock:   "CAPS",
+	ScrollLock: "SCRL",
+}
+
+func (l LED) String() string {
+	if name, ok := ledNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LED(%d)", uint16(l))
+}
+
+// ParseLED returns the LED with a name: num-lock, caps-lock, or scroll-lock.
+func ParseLED(name string) (LED, error) {
+	for _, led := range AllLEDs() {
+		if ledNames[led] == name {
+			return led, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown lock LED: %q", name)
+}
+
+// State is the set of lock LEDs that are on.
+type State uint8
+
+// On returns true if the LED is on.
+func (s State) On(led LED) bool {
+	return s&(1<<led) != 0
+}
+
+// with returns the state with an LED turned on or off.
+func (s State) with(led LED, on bool) State {
+	if on {
+		return s | 1<<led
+	}
+	return s &^ (1 << led)
+}
+
+// input_event from linux/input.h
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+var eventSize = binary.Size(inputEvent{})
+
+// Listener reports the lock LED state of keyboards. The LEDs of all
+// keyboards are combined, so an LED is on if it's on for any of them.
+type Listener struct {
+	files  []*os.File
+	states chan State
+
+	mu      sync.Mutex
+	state   State
+	devices []State
+}
+
+// FindKeyboards returns the evdev devices that have a Caps Lock LED. Devices
+// that can't be opened are skipped.
+func FindKeyboards() ([]string, error) {
+	paths, err := filepath.Glob(devicePattern)
+	if err != nil {
+		return nil, err
+	}
+	var keyboards []string
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		var leds [ledBytes]byte
+		err = ioctl(file, eviocgBitLED, unsafe.Pointer(&leds[0]))
+		file.Close()
+		if err == nil && State(leds[0]).On(CapsLock) {
+			keyboards = append(keyboards, path)
+		}
+	}
+	if len(keyboards) == 0 {
+		return nil, fmt.Errorf("no keyboards with lock LEDs found in %s", devicePattern)
+	}
+	return keyboards, nil
+}
+
+// Listen starts watching the LEDs of evdev devices. With no paths, it watches
+// every keyboard found by [FindKeyboards]. The current state is sent first.
+func Listen(paths []string) (*Listener, error) {
+	if len(paths) == 0 {
+		var err error
+		paths, err = FindKeyboards()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l := &Listener{
+		states:  make(chan State, 1),
+		devices: make([]State, len(paths)),
+	}
+	for idx, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			l.closeFiles()
+			return nil, fmt.Errorf("failed to open input device %q: %w", path, err)
+		}
+		l.files = append(l.files, file)
+
+		// devices that don't report their LEDs, like pipes, start with all
+		// of them off
+		var leds [ledBytes]byte
+		if err := ioctl(file, eviocgLED, unsafe.Pointer(&leds[0])); err == nil {
+			l.devices[idx] = State(leds[0]) & (1<<len(AllLEDs()) - 1)
+		}
+		l.state |= l.devices[idx]
+	}
+	l.states <- l.state
+
+	var wg sync.WaitGroup
+	for idx, file := range l.files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.read(idx, file)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(l.states)
+	}()
+	return l, nil
+}
+
+// read handles the events of a device until it fails, which happens when the
+// device is removed or the listener is closed.
+func (l *Listener) read(idx int, file *os.File) {
+	buf := make([]byte, eventSize)
+	for {
+		if _, err := io.ReadFull(file, buf); err != nil {
+			return
+		}
+		var event inputEvent
+		if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &event); err != nil {
+			return
+		}
+		if event.Type != evLED || int(event.Code) >= len(AllLEDs()) {
+			continue
+		}
+		l.update(idx, LED(event.Code), event.Value != 0)
+	}
+}
+
+// update sets an LED of a device and sends the combined state if it changed.
+func (l *Listener) update(idx int, led LED, on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.devices[idx] = l.devices[idx].with(led, on)
+	var state State
+	for _, ds := range l.devices {
+		state |= ds
+	}
+	if state == l.state {
+		return
+	}
+	l.state = state
+
+	// only the latest state matters, so an unread one is replaced
+	select {
+	case <-l.states:
+	default:
+	}
+	l.states <- state
+}
+
+// States returns the channel that the LED state is sent to when it changes.
+// It's closed when the listener is closed or all its devices are removed.
+func (l *Listener) States() <-chan State {
+	return l.states
+}
+
+// Close stops watching the devices.
+func (l *Listener) Close() error {
+	return l.closeFiles()
+}
+
+func (l *Listener) closeFiles() error {
+	var firstErr error
+	for _, file := range l.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Render draws the labels of the LEDs that are on, out of the given ones,
+// inverted in the top right corner of an otherwise transparent image for the
+// LCD.
+func Render(state State, leds []LED) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	face := lcdtext.Builtin
+	right := device.LCDWidth
+	for idx := len(leds) - 1; idx >= 0; idx-- {
+		if !state.On(leds[idx]) {
+			continue
+		}
+		label := ledLabels[leds[idx]]
+		box := image.Rect(right-len(label)*face.Advance-1, 0, right, face.Height)
+		draw.Draw(img, box, image.Black, image.Point{}, draw.Src)
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  image.White,
+			Face: face,
+			Dot:  fixed.P(box.Min.X+1, face.Ascent),
+		}
+		drawer.DrawString(label)
+		right = box.Min.X - 1
+	}
+	return img
+}
+
+// ioctl runs an ioctl on a file without [os.File.Fd], which would put the file
+// in blocking mode, so that closing it wouldn't stop a read.
+func ioctl(file *os.File, cmd uintptr, arg unsafe.Pointer) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, uintptr(arg))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package hostleds_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/hostleds"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeEvent writes an input_event like the ones read from evdev devices.
+func writeEvent(t *testing.T, file *os.File, evType, code uint16, value int32) {
+	t.Helper()
+	event := struct {
+		Time  syscall.Timeval
+		Type  uint16
+		Code  uint16
+		Value int32
+	}{Type: evType, Code: code, Value: value}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, binary.Write(buf, binary.LittleEndian, event))
+	_, err := file.Write(buf.Bytes())
+	assert.NoError(t, err)
+}
+
+// newFIFO returns the path of a FIFO standing in for an evdev device, and a
+// channel that receives its writing end once the listener opens it.
+func newFIFO(t *testing.T, name string) (string, <-chan *os.File) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, syscall.Mkfifo(path, 0o600))
+	writers := make(chan *os.File, 1)
+	go func() {
+		// blocks until the listener opens the FIFO for reading
+		writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			close(writers)
+			return
+		}
+		writers <- writer
+	}()
+	return path, writers
+}
+
+func receive(t *testing.T, states <-chan hostleds.State) (hostleds.State, bool) {
+	t.Helper()
+	select {
+	case state, ok := <-states:
+		return state, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the LED state")
+		return 0, false
+	}
+}
+
+func TestListener(t *testing.T) {
+	assert := assert.New(t)
+	path1, writers1 := newFIFO(t, "event0")
+	path2, writers2 := newFIFO(t, "event1")
+	listener, err := hostleds.Listen([]string{path1, path2})
+	assert.NoError(err)
+	defer listener.Close()
+	kbd1, kbd2 := <-writers1, <-writers2
+	defer kbd1.Close()
+	defer kbd2.Close()
+
+	// FIFOs don't report LEDs, so they start off
+	state, ok := receive(t, listener.States())
+	assert.True(ok)
+	assert.Equal(hostleds.State(0), state)
+
+	// key and sync events are ignored
+	writeEvent(t, kbd1, 0x01, 58, 1)
+	writeEvent(t, kbd1, 0x11, uint16(hostleds.CapsLock), 1)
+	writeEvent(t, kbd1, 0x00, 0, 0)
+	state, _ = receive(t, listener.States())
+	assert.True(state.On(hostleds.CapsLock))
+	assert.False(state.On(hostleds.NumLock))
+
+	// an LED is on while it's on for any keyboard
+	writeEvent(t, kbd2, 0x11, uint16(hostleds.CapsLock), 1)
+	writeEvent(t, kbd2, 0x11, uint16(hostleds.NumLock), 1)
+	state, _ = receive(t, listener.States())
+	assert.True(state.On(hostleds.NumLock))
+	writeEvent(t, kbd1, 0x11, uint16(hostleds.CapsLock), 0)
+	writeEvent(t, kbd2, 0x11, uint16(hostleds.NumLock), 0)
+	state, _ = receive(t, listener.States())
+	assert.True(state.On(hostleds.CapsLock))
+	assert.False(state.On(hostleds.NumLock))
+
+	// the channel is closed once every device is gone
+	kbd1.Close()
+	kbd2.Close()
+	_, ok = receive(t, listener.States())
+	assert.False(ok)
+}
+
+func TestListenerClose(t *testing.T) {
+	assert := assert.New(t)
+	path, writers := newFIFO(t, "event0")
+	listener, err := hostleds.Listen([]string{path})
+	assert.NoError(err)
+	kbd := <-writers
+	defer kbd.Close()
+
+	_, _ = receive(t, listener.States())
+	assert.NoError(listener.Close())
+	_, ok := receive(t, listener.States())
+	assert.False(ok)
+}
+
+func TestListenMissing(t *testing.T) {
+	_, err := hostleds.Listen([]string{"/nonexistent/event0"})
+	assert.ErrorContains(t, err, `failed to open input device "/nonexistent/event0"`)
+}
+
+func TestParseLED(t *testing.T) {
+	assert := assert.New(t)
+	for _, led := range hostleds.AllLEDs() {
+		parsed, err := hostleds.ParseLED(led.String())
+		assert.NoError(err)
+		assert.Equal(led, parsed)
+	}
+	_, err := hostleds.ParseLED("kana")
+	assert.EqualError(err, `unknown lock LED: "kana"`)
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+	leds := []hostleds.LED{hostleds.CapsLock, hostleds.NumLock}
+
+	img := hostleds.Render(0, leds)
+	assert.Equal(color.RGBA{}, img.At(device.LCDWidth-1, 0))
+
+	// the last LED is in the corner, and the ones that are off leave no gap
+	img = hostleds.Render(hostleds.State(1<<hostleds.CapsLock|1<<hostleds.ScrollLock), leds)
+	assert.Equal(color.RGBA{A: 0xff}, img.At(device.LCDWidth-1, 0))
+	assert.Equal(color.RGBA{A: 0xff}, img.At(device.LCDWidth-4*6-1, 0))
+	assert.Equal(color.RGBA{}, img.At(device.LCDWidth-4*6-2, 0))
+	assert.Equal(color.RGBA{}, img.At(0, device.LCDHeight-1))
+}
@@ -0,0 +1,77 @@
+package joystick
+
+// Gamepad button codes from linux/input-event-codes.h
+const (
+	BtnSouth     = 0x130
+	BtnEast      = 0x131
+	BtnC         = 0x132
+	BtnNorth     = 0x133
+	BtnWest      = 0x134
+	BtnZ         = 0x135
+	BtnTL        = 0x136
+	BtnTR        = 0x137
+	BtnTL2       = 0x138
+	BtnTR2       = 0x139
+	BtnSelect    = 0x13a
+	BtnStart     = 0x13b
+	BtnMode      = 0x13c
+	BtnThumbL    = 0x13d
+	BtnThumbR    = 0x13e
+	BtnDpadUp    = 0x220
+	BtnDpadDown  = 0x221
+	BtnDpadLeft  = 0x222
+	BtnDpadRight = 0x223
+
+	// Aliases used by Xbox style controllers
+	BtnA = BtnSouth
+	BtnB = BtnEast
+	BtnX = BtnNorth
+	BtnY = BtnWest
+)
+
+var buttonsByName = map[string]int{
+	"BtnSouth":     BtnSouth,
+	"BtnEast":      BtnEast,
+	"BtnC":         BtnC,
+	"BtnNorth":     BtnNorth,
+	"BtnWest":      BtnWest,
+	"BtnZ":         BtnZ,
+	"BtnTL":        BtnTL,
+	"BtnTR":        BtnTR,
+	"BtnTL2":       BtnTL2,
+	"BtnTR2":       BtnTR2,
+	"BtnSelect":    BtnSelect,
+	"BtnStart":     BtnStart,
+	"BtnMode":      BtnMode,
+	"BtnThumbL":    BtnThumbL,
+	"BtnThumbR":    BtnThumbR,
+	"BtnDpadUp":    BtnDpadUp,
+	"BtnDpadDown":  BtnDpadDown,
+	"BtnDpadLeft":  BtnDpadLeft,
+	"BtnDpadRight": BtnDpadRight,
+	"BtnA":         BtnA,
+	"BtnB":         BtnB,
+	"BtnX":         BtnX,
+	"BtnY":         BtnY,
+}
+
+// ButtonCode returns the button code for the given name, or 0 if the name is
+// unknown.
+func ButtonCode(name string) int {
+	return buttonsByName[name]
+}
+
+// ButtonName returns the name of the button with the given code, or an empty
+// string if the code is unknown. Buttons with an alias get the shorter name.
+func ButtonName(code int) string {
+	var found string
+	for name, c := range buttonsByName {
+		if c != code {
+			continue
+		}
+		if found == "" || len(name) < len(found) || len(name) == len(found) && name < found {
+			found = name
+		}
+	}
+	return found
+}
@@ -3,7 +3,7 @@ package joystick
 import (
 	"fmt"
 
-	"github.com/bendahl/uinput"
+	"github.com/achilleas-k/gg13/internal/uinputdev"
 )
 
 type Joystick interface {
@@ -12,19 +12,44 @@ type Joystick interface {
 	ButtonDown(b int) error
 	ButtonUp(b int) error
 	StickPosition(x, y float32) error
+	HatPosition(x, y int8) error
 }
 
 type UinputJoystick struct {
-	js uinput.Gamepad
+	dev *uinputdev.Device
+
+	// ranges of the stick axes, for scaling positions
+	axisX uinputdev.AbsInfo
+	axisY uinputdev.AbsInfo
+
+	// current hat position, to avoid sending events when it doesn't change
+	hatX int8
+	hatY int8
 }
 
-func New(name string) (Joystick, error) {
-	js, err := uinput.CreateGamepad("/dev/uinput", []byte(name), 12, 12)
+// New returns a [Joystick] instance backed by a virtual uinput gamepad with
+// the identity and capabilities described by settings.
+func New(settings Settings) (Joystick, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	dev, err := uinputdev.Create(uinputdev.DefaultPath, uinputdev.Spec{
+		Name:    settings.Name,
+		Bus:     settings.Bus,
+		Vendor:  settings.Vendor,
+		Product: settings.Product,
+		Version: settings.Version,
+		Keys:    settings.Buttons,
+		Abs:     settings.Axes,
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &UinputJoystick{
-		js: js,
+		dev:   dev,
+		axisX: settings.Axes[uinputdev.AbsX],
+		axisY: settings.Axes[uinputdev.AbsY],
 	}, nil
 }
 
@@ -33,37 +58,89 @@ func (vjs *UinputJoystick) Close() error {
 		// just do nothing
 		return nil
 	}
-	return vjs.js.Close()
+	return vjs.dev.Close()
 }
 
 func (vjs *UinputJoystick) ButtonPress(k int) error {
 	if !vjs.hasJoystick() {
 		return fmt.Errorf("button press before initialising joystick")
 	}
-	return vjs.js.ButtonPress(k)
+	if err := vjs.button(k, 1); err != nil {
+		return err
+	}
+	return vjs.button(k, 0)
 }
 
 func (vjs *UinputJoystick) ButtonDown(k int) error {
 	if !vjs.hasJoystick() {
 		return fmt.Errorf("button down before initialising joystick")
 	}
-	return vjs.js.ButtonDown(k)
+	return vjs.button(k, 1)
 }
 
 func (vjs *UinputJoystick) ButtonUp(k int) error {
 	if !vjs.hasJoystick() {
 		return fmt.Errorf("button up before initialising joystick")
 	}
-	return vjs.js.ButtonUp(k)
+	return vjs.button(k, 0)
 }
 
+func (vjs *UinputJoystick) button(k int, value int32) error {
+	if err := vjs.dev.Emit(uinputdev.EvKey, uint16(k), value); err != nil {
+		return err
+	}
+	return vjs.dev.Sync()
+}
+
+// StickPosition sets the position of the (left) stick. Each axis ranges from
+// -1 (left/up) to 1 (right/down).
 func (vjs *UinputJoystick) StickPosition(x, y float32) error {
 	if !vjs.hasJoystick() {
 		return fmt.Errorf("stick position set before initialising joystick")
 	}
-	return vjs.js.LeftStickMove(x, y)
+	if err := vjs.dev.Emit(uinputdev.EvAbs, uinputdev.AbsX, scaleAxis(x, vjs.axisX)); err != nil {
+		return err
+	}
+	if err := vjs.dev.Emit(uinputdev.EvAbs, uinputdev.AbsY, scaleAxis(y, vjs.axisY)); err != nil {
+		return err
+	}
+	return vjs.dev.Sync()
+}
+
+// HatPosition sets the position of the hat switch (D-pad). Each axis is -1
+// (left/up), 0 (centre), or 1 (right/down).
+func (vjs *UinputJoystick) HatPosition(x, y int8) error {
+	if !vjs.hasJoystick() {
+		return fmt.Errorf("hat position set before initialising joystick")
+	}
+	if x == vjs.hatX && y == vjs.hatY {
+		return nil
+	}
+
+	if x != vjs.hatX {
+		if err := vjs.dev.Emit(uinputdev.EvAbs, uinputdev.AbsHat0X, int32(x)); err != nil {
+			return err
+		}
+		vjs.hatX = x
+	}
+	if y != vjs.hatY {
+		if err := vjs.dev.Emit(uinputdev.EvAbs, uinputdev.AbsHat0Y, int32(y)); err != nil {
+			return err
+		}
+		vjs.hatY = y
+	}
+	return vjs.dev.Sync()
+}
+
+// scaleAxis converts a normalised axis position (-1 to 1) to the range of the
+// axis.
+func scaleAxis(v float32, axis uinputdev.AbsInfo) int32 {
+	v = min(max(v, -1), 1)
+	mid := (float32(axis.Min) + float32(axis.Max)) / 2
+	half := (float32(axis.Max) - float32(axis.Min)) / 2
+	return int32(mid + v*half)
 }
 
 func (vjs *UinputJoystick) hasJoystick() bool {
-	return vjs.js != nil
+	return vjs.dev != nil
 }
@@ -0,0 +1,139 @@
+package joystick
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/achilleas-k/gg13/internal/uinputdev"
+)
+
+// Settings describe the identity and capabilities of the virtual joystick.
+type Settings struct {
+	Name    string
+	Bus     uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+
+	// Buttons the device reports.
+	Buttons []uint16
+
+	// Absolute axes the device reports and their ranges. The stick is always
+	// reported on the X and Y axes and the hat on the HAT0X and HAT0Y axes.
+	Axes map[uint16]uinputdev.AbsInfo
+}
+
+// Validate checks that the settings describe a device the joystick can drive.
+func (s Settings) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("joystick name may not be empty")
+	}
+	for _, axis := range []uint16{uinputdev.AbsX, uinputdev.AbsY, uinputdev.AbsHat0X, uinputdev.AbsHat0Y} {
+		if _, ok := s.Axes[axis]; !ok {
+			return fmt.Errorf("joystick is missing required axis %#x", axis)
+		}
+	}
+	return nil
+}
+
+// HasButton returns true if the device reports the given button.
+func (s Settings) HasButton(button int) bool {
+	return slices.Contains(s.Buttons, uint16(button))
+}
+
+var (
+	stickAxis   = uinputdev.AbsInfo{Min: -32768, Max: 32767, Fuzz: 16, Flat: 128}
+	triggerAxis = uinputdev.AbsInfo{Min: 0, Max: 255}
+	hatAxis     = uinputdev.AbsInfo{Min: -1, Max: 1}
+
+	presets = map[string]Settings{
+		// Generic gamepad, matching the original virtual joystick
+		"default": {
+			Name:    "g13-vjs",
+			Bus:     uinputdev.BusUSB,
+			Vendor:  12,
+			Product: 12,
+			Version: 1,
+			Buttons: []uint16{
+				BtnSouth, BtnEast, BtnNorth, BtnWest,
+				BtnTL, BtnTR, BtnTL2, BtnTR2, BtnThumbL, BtnThumbR,
+				BtnSelect, BtnStart, BtnMode,
+				BtnDpadUp, BtnDpadDown, BtnDpadLeft, BtnDpadRight,
+			},
+			Axes: map[uint16]uinputdev.AbsInfo{
+				uinputdev.AbsX:     stickAxis,
+				uinputdev.AbsY:     stickAxis,
+				uinputdev.AbsZ:     stickAxis,
+				uinputdev.AbsRX:    stickAxis,
+				uinputdev.AbsRY:    stickAxis,
+				uinputdev.AbsRZ:    stickAxis,
+				uinputdev.AbsHat0X: hatAxis,
+				uinputdev.AbsHat0Y: hatAxis,
+			},
+		},
+
+		// Wired Xbox 360 controller as reported by the xpad driver
+		"xbox360": {
+			Name:    "Microsoft X-Box 360 pad",
+			Bus:     uinputdev.BusUSB,
+			Vendor:  0x045e,
+			Product: 0x028e,
+			Version: 0x0110,
+			Buttons: []uint16{
+				BtnA, BtnB, BtnX, BtnY,
+				BtnTL, BtnTR, BtnThumbL, BtnThumbR,
+				BtnSelect, BtnStart, BtnMode,
+			},
+			Axes: map[uint16]uinputdev.AbsInfo{
+				uinputdev.AbsX:     stickAxis,
+				uinputdev.AbsY:     stickAxis,
+				uinputdev.AbsZ:     triggerAxis,
+				uinputdev.AbsRX:    stickAxis,
+				uinputdev.AbsRY:    stickAxis,
+				uinputdev.AbsRZ:    triggerAxis,
+				uinputdev.AbsHat0X: hatAxis,
+				uinputdev.AbsHat0Y: hatAxis,
+			},
+		},
+
+		// DualShock 4 (first revision) as reported by the hid-sony driver
+		"dualshock4": {
+			Name:    "Sony Computer Entertainment Wireless Controller",
+			Bus:     uinputdev.BusUSB,
+			Vendor:  0x054c,
+			Product: 0x05c4,
+			Version: 0x8111,
+			Buttons: []uint16{
+				BtnSouth, BtnEast, BtnNorth, BtnWest,
+				BtnTL, BtnTR, BtnTL2, BtnTR2, BtnThumbL, BtnThumbR,
+				BtnSelect, BtnStart, BtnMode,
+			},
+			Axes: map[uint16]uinputdev.AbsInfo{
+				uinputdev.AbsX:     {Min: 0, Max: 255, Flat: 15},
+				uinputdev.AbsY:     {Min: 0, Max: 255, Flat: 15},
+				uinputdev.AbsZ:     triggerAxis,
+				uinputdev.AbsRX:    {Min: 0, Max: 255, Flat: 15},
+				uinputdev.AbsRY:    {Min: 0, Max: 255, Flat: 15},
+				uinputdev.AbsRZ:    triggerAxis,
+				uinputdev.AbsHat0X: hatAxis,
+				uinputdev.AbsHat0Y: hatAxis,
+			},
+		},
+	}
+)
+
+// Preset returns the settings of a named preset. The returned settings can be
+// modified freely.
+func Preset(name string) (Settings, error) {
+	preset, ok := presets[name]
+	if !ok {
+		return Settings{}, fmt.Errorf("unknown joystick preset: %s", name)
+	}
+	preset.Buttons = slices.Clone(preset.Buttons)
+	preset.Axes = maps.Clone(preset.Axes)
+	return preset, nil
+}
+
+// DefaultPreset is the name of the preset used when none is configured.
+const DefaultPreset = "default"
@@ -0,0 +1,60 @@
+package joystick_test
+
+import (
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/joystick"
+	"github.com/achilleas-k/gg13/internal/uinputdev"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresets(t *testing.T) {
+	for _, name := range []string{"default", "xbox360", "dualshock4"} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			settings, err := joystick.Preset(name)
+			assert.NoError(err)
+			assert.NoError(settings.Validate())
+		})
+	}
+
+	_, err := joystick.Preset("nes")
+	assert.EqualError(t, err, "unknown joystick preset: nes")
+}
+
+func TestPresetIsCopy(t *testing.T) {
+	assert := assert.New(t)
+
+	settings, err := joystick.Preset("xbox360")
+	assert.NoError(err)
+	settings.Name = "changed"
+	settings.Buttons[0] = 0
+	delete(settings.Axes, uinputdev.AbsX)
+
+	fresh, err := joystick.Preset("xbox360")
+	assert.NoError(err)
+	assert.Equal("Microsoft X-Box 360 pad", fresh.Name)
+	assert.True(fresh.HasButton(joystick.BtnA))
+	assert.NoError(fresh.Validate())
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	settings, err := joystick.Preset("default")
+	assert.NoError(err)
+	settings.Name = ""
+	assert.EqualError(settings.Validate(), "joystick name may not be empty")
+
+	settings.Name = "js"
+	delete(settings.Axes, uinputdev.AbsHat0Y)
+	assert.EqualError(settings.Validate(), "joystick is missing required axis 0x11")
+}
+
+func TestButtonCode(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(joystick.BtnSouth, joystick.ButtonCode("BtnA"))
+	assert.Equal(joystick.BtnSouth, joystick.ButtonCode("BtnSouth"))
+	assert.Equal(joystick.BtnMode, joystick.ButtonCode("BtnMode"))
+	assert.Equal(0, joystick.ButtonCode("BtnNope"))
+}
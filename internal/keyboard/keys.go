@@ -250,3 +250,24 @@ var (
 func KeyCode(name string) int {
 	return keysByName[name]
 }
+
+// keyNames maps key codes back to names. Codes with more than one name get the
+// shortest one.
+var keyNames = reverseNames(keysByName)
+
+// KeyName returns the name of the key with the given code, or an empty string
+// if the code is unknown.
+func KeyName(code int) string {
+	return keyNames[code]
+}
+
+func reverseNames(byName map[string]int) map[int]string {
+	names := make(map[int]string, len(byName))
+	for name, code := range byName {
+		if prev, ok := names[code]; ok && (len(prev) < len(name) || len(prev) == len(name) && prev < name) {
+			continue
+		}
+		names[code] = name
+	}
+	return names
+}
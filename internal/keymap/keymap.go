@@ -0,0 +1,228 @@
+// Package keymap draws the layout of the G13 keys with what each key is bound
+// to: on the LCD, as an image, and as an SVG drawing.
+package keymap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"strings"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Labels describes what the keys and the stick are bound to.
+type Labels struct {
+	// Short description of the binding of each key. Unbound keys have no
+	// label.
+	Keys map[device.KeyBit]string
+
+	// Description of the stick mode.
+	Stick string
+}
+
+// position of a key in the layout, in key sizes from the top left.
+type position struct {
+	key  device.KeyBit
+	x, y float64
+}
+
+// Layout of the keys on the device, roughly as they are placed on it.
+var layout = []position{
+	{device.BD, 1, 0}, {device.L1, 2, 0}, {device.L2, 3, 0}, {device.L3, 4, 0}, {device.L4, 5, 0},
+	{device.M1, 1.5, 1}, {device.M2, 2.5, 1}, {device.M3, 3.5, 1}, {device.MR, 4.5, 1},
+	{device.G1, 0, 2}, {device.G2, 1, 2}, {device.G3, 2, 2}, {device.G4, 3, 2}, {device.G5, 4, 2}, {device.G6, 5, 2}, {device.G7, 6, 2},
+	{device.G8, 0, 3}, {device.G9, 1, 3}, {device.G10, 2, 3}, {device.G11, 3, 3}, {device.G12, 4, 3}, {device.G13, 5, 3}, {device.G14, 6, 3},
+	{device.G15, 1, 4}, {device.G16, 2, 4}, {device.G17, 3, 4}, {device.G18, 4, 4}, {device.G19, 5, 4},
+	{device.G20, 2, 5}, {device.G21, 3, 5}, {device.G22, 4, 5},
+	{device.TOP, 7.5, 3}, {device.LEFT, 6.25, 4}, {device.DOWN, 7.5, 5.25},
+}
+
+// Centre of the stick in the layout, in key sizes.
+const (
+	stickX = 8
+	stickY = 4.5
+)
+
+// Size of the layout, in key sizes.
+const (
+	layoutWidth  = 8.5
+	layoutHeight = 6.25
+)
+
+// Size of a key in images, including the gap to the next key, radius of the
+// stick, and margin around the layout, in pixels.
+const (
+	keyWidth    = 80
+	keyHeight   = 48
+	keyGap      = 6
+	stickRadius = 30
+	margin      = 12
+)
+
+// fit shortens a label to at most n characters.
+func fit(label string, n int) string {
+	runes := []rune(label)
+	if len(runes) <= n {
+		return label
+	}
+	return string(runes[:max(n, 0)])
+}
+
+// drawString draws text in black with its top left corner at x, y.
+func drawString(img draw.Image, face font.Face, x, y int, text string) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+}
+
+// drawRect draws the outline of a rectangle in black.
+func drawRect(img *image.Gray, rect image.Rectangle) {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		img.SetGray(x, rect.Min.Y, color.Gray{})
+		img.SetGray(x, rect.Max.Y-1, color.Gray{})
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		img.SetGray(rect.Min.X, y, color.Gray{})
+		img.SetGray(rect.Max.X-1, y, color.Gray{})
+	}
+}
+
+// drawCircle draws the outline of a circle in black.
+func drawCircle(img *image.Gray, cx, cy, r int) {
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if d := x*x + y*y; d <= r*r && d > (r-1)*(r-1) {
+				img.SetGray(cx+x, cy+y, color.Gray{})
+			}
+		}
+	}
+}
+
+func newWhite(rect image.Rectangle) *image.Gray {
+	img := image.NewGray(rect)
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+// Grid of the G keys on the LCD, in cells of lcdCellWidth x lcdCellHeight
+// pixels.
+const (
+	lcdCellWidth  = 22
+	lcdCellHeight = 10
+)
+
+// RenderLCD draws the G keys and the stick mode on an image the size of the
+// LCD. There is only room for the first three characters of each label.
+func RenderLCD(labels Labels) *image.Gray {
+	img := newWhite(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	face := lcdtext.Builtin
+	advance := face.Advance
+	left := (device.LCDWidth - 7*lcdCellWidth) / 2
+	top := (device.LCDHeight - 4*lcdCellHeight) / 2
+
+	for _, pos := range layout {
+		if pos.key > device.G22 {
+			// only the G keys fit, which have the lowest bits
+			continue
+		}
+		x := left + int(pos.x)*lcdCellWidth
+		y := top + int(pos.y-2)*lcdCellHeight
+		cell := image.Rect(x, y, x+lcdCellWidth-2, y+lcdCellHeight-1)
+		drawRect(img, cell)
+		label := fit(labels.Keys[pos.key], (cell.Dx()-2)/advance)
+		textWidth := len([]rune(label))*advance - 1
+		drawString(img, face, cell.Min.X+(cell.Dx()-textWidth)/2, cell.Min.Y+1, label)
+	}
+
+	// the stick mode goes where the stick is, right of the last row
+	x := left + 5*lcdCellWidth
+	y := top + 3*lcdCellHeight
+	drawCircle(img, x+3, y+4, 3)
+	stick := fit(labels.Stick, (2*lcdCellWidth-10)/advance)
+	drawString(img, face, x+9, y+1, stick)
+	return img
+}
+
+// keyRect returns the rectangle of a key in the layout drawn by [Render].
+func keyRect(pos position) image.Rectangle {
+	x := margin + int(pos.x*keyWidth)
+	y := margin + int(pos.y*keyHeight)
+	return image.Rect(x, y, x+keyWidth-keyGap, y+keyHeight-keyGap)
+}
+
+// stickCircle returns the centre and radius of the stick in the layout drawn
+// by [Render].
+func stickCircle() (int, int, int) {
+	return margin + int(stickX*keyWidth), margin + int(stickY*keyHeight), stickRadius
+}
+
+// Size of the image drawn by [Render].
+func renderSize() (int, int) {
+	return 2*margin + int(layoutWidth*keyWidth), 2*margin + int(layoutHeight*keyHeight)
+}
+
+// Render draws all the keys and the stick with their names and labels.
+func Render(labels Labels) *image.Gray {
+	width, height := renderSize()
+	img := newWhite(image.Rect(0, 0, width, height))
+	face := basicfont.Face7x13
+	advance := face.Advance
+
+	for _, pos := range layout {
+		rect := keyRect(pos)
+		drawRect(img, rect)
+		drawString(img, face, rect.Min.X+4, rect.Min.Y+3, pos.key.String())
+		label := fit(labels.Keys[pos.key], (rect.Dx()-8)/advance)
+		drawString(img, face, rect.Min.X+4, rect.Max.Y-face.Height-3, label)
+	}
+
+	cx, cy, r := stickCircle()
+	drawCircle(img, cx, cy, r)
+	stick := fit(labels.Stick, (2*r-4)/advance)
+	drawString(img, face, cx-len([]rune(stick))*advance/2, cy-face.Height/2, stick)
+	return img
+}
+
+// WriteSVG writes the layout drawn by [Render] as an SVG drawing. Labels
+// aren't shortened.
+func WriteSVG(w io.Writer, labels Labels) error {
+	width, height := renderSize()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n", width, height, width, height)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	for _, pos := range layout {
+		rect := keyRect(pos)
+		fmt.Fprintf(&sb, `<g><rect x="%d" y="%d" width="%d" height="%d" rx="4" fill="none" stroke="black"/>`, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="10">%s</text>`, rect.Min.X+4, rect.Min.Y+13, escape(pos.key.String()))
+		if label := labels.Keys[pos.key]; label != "" {
+			fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="12" font-weight="bold">%s</text>`, rect.Min.X+4, rect.Max.Y-6, escape(label))
+		}
+		sb.WriteString("</g>\n")
+	}
+	cx, cy, r := stickCircle()
+	fmt.Fprintf(&sb, `<g><circle cx="%d" cy="%d" r="%d" fill="none" stroke="black"/>`, cx, cy, r)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="12" font-weight="bold" text-anchor="middle">%s</text></g>`+"\n", cx, cy+4, escape(labels.Stick))
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func escape(text string) string {
+	var sb strings.Builder
+	// writing to a strings.Builder doesn't fail
+	_ = xml.EscapeText(&sb, []byte(text))
+	return sb.String()
+}
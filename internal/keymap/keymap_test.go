@@ -0,0 +1,77 @@
+package keymap_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/keymap"
+	"github.com/stretchr/testify/assert"
+)
+
+// countBlack returns the number of black pixels in a rectangle of an image.
+func countBlack(img *image.Gray, rect image.Rectangle) int {
+	n := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if img.GrayAt(x, y) == (color.Gray{}) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestRenderLCD(t *testing.T) {
+	assert := assert.New(t)
+
+	unbound := keymap.RenderLCD(keymap.Labels{})
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), unbound.Bounds())
+
+	// G1 is the top left key, G22 is on the bottom row
+	labels := keymap.Labels{
+		Keys:  map[device.KeyBit]string{device.G1: "Esc", device.G22: "Space", device.BD: "Menu"},
+		Stick: "joystick",
+	}
+	bound := keymap.RenderLCD(labels)
+	g1 := image.Rect(0, 0, 28, 12)
+	assert.Greater(countBlack(bound, g1), countBlack(unbound, g1))
+	g22 := image.Rect(90, 32, 110, 43)
+	assert.Greater(countBlack(bound, g22), countBlack(unbound, g22))
+	stick := image.Rect(120, 32, 160, 43)
+	assert.Greater(countBlack(bound, stick), countBlack(unbound, stick))
+
+	// labels too long for the LCD are cut, so long labels don't spill over
+	// into the next key
+	cut := keymap.RenderLCD(keymap.Labels{Keys: map[device.KeyBit]string{device.G1: "Esc"}})
+	long := keymap.RenderLCD(keymap.Labels{Keys: map[device.KeyBit]string{device.G1: "Escape"}})
+	assert.Equal(cut, long)
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	unbound := keymap.Render(keymap.Labels{})
+	bound := keymap.Render(keymap.Labels{Keys: map[device.KeyBit]string{device.M1: "Profile"}})
+	assert.Equal(unbound.Bounds(), bound.Bounds())
+	assert.Greater(countBlack(bound, bound.Bounds()), countBlack(unbound, unbound.Bounds()))
+}
+
+func TestWriteSVG(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	labels := keymap.Labels{
+		Keys:  map[device.KeyBit]string{device.G17: "Leftshift", device.L1: "<Page"},
+		Stick: "mouse",
+	}
+	assert.NoError(keymap.WriteSVG(&buf, labels))
+	svg := buf.String()
+	assert.Contains(svg, "<svg ")
+	assert.Contains(svg, ">G17</text>")
+	assert.Contains(svg, ">Leftshift</text>")
+	assert.Contains(svg, ">&lt;Page</text>")
+	assert.Contains(svg, ">mouse</text>")
+}
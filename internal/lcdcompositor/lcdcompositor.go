@@ -0,0 +1,134 @@
+// Package lcdcompositor combines layers of content for the G13 LCD, like the
+// current page, notices, and menus, into the frames that are drawn on it.
+// Frames are only produced when the content changes, and at most at a fixed
+// rate, so that the LCD isn't written to more than necessary.
+package lcdcompositor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"time"
+)
+
+// layer is a single image drawn on the LCD.
+type layer struct {
+	name  string
+	z     int
+	img   image.Image
+	until time.Time
+}
+
+// Compositor keeps the layers of the LCD and draws them, from the lowest to
+// the highest, into frames.
+type Compositor struct {
+	bounds      image.Rectangle
+	minInterval time.Duration
+
+	layers []layer
+
+	// the layers changed since the last frame
+	dirty bool
+
+	// last frame produced, and when (nil and zero if there was none)
+	lastFrame *image.Gray
+	lastTime  time.Time
+}
+
+// New returns a compositor for a display of the given size that produces
+// frames at most once every minInterval.
+func New(width, height int, minInterval time.Duration) *Compositor {
+	return &Compositor{
+		bounds:      image.Rect(0, 0, width, height),
+		minInterval: minInterval,
+	}
+}
+
+// Set shows an image in the layer with the given name, which is drawn over
+// layers with a lower z and under layers with a higher one. Layers with the
+// same z are drawn in the order they were added. The layer is removed at
+// until, or stays until removed if until is zero. Images that are smaller than
+// the display only cover their bounds and images with transparency show the
+// layers under them.
+func (c *Compositor) Set(name string, z int, img image.Image, until time.Time) {
+	c.dirty = true
+	l := layer{name: name, z: z, img: img, until: until}
+	for idx := range c.layers {
+		if c.layers[idx].name == name {
+			c.layers[idx] = l
+			return
+		}
+	}
+	c.layers = append(c.layers, l)
+}
+
+// Remove removes the layer with the given name. It returns false if there was
+// no such layer.
+func (c *Compositor) Remove(name string) bool {
+	idx := slices.IndexFunc(c.layers, func(l layer) bool { return l.name == name })
+	if idx < 0 {
+		return false
+	}
+	c.layers = slices.Delete(c.layers, idx, idx+1)
+	c.dirty = true
+	return true
+}
+
+// Has returns true if there is a layer with the given name.
+func (c *Compositor) Has(name string) bool {
+	return slices.ContainsFunc(c.layers, func(l layer) bool { return l.name == name })
+}
+
+// Invalidate makes the next call to [Compositor.Next] produce a frame even if
+// nothing changed, for example after a frame failed to be drawn.
+func (c *Compositor) Invalidate() {
+	c.dirty = true
+	c.lastFrame = nil
+}
+
+// Next returns the frame to draw on the display at now and true if the layers
+// changed since the last frame and the frame rate allows drawing it. It
+// returns false if there is nothing new to draw, which includes layer changes
+// that produce the same frame as the last one, or if it's too early to draw.
+// Layers that have timed out are removed first.
+func (c *Compositor) Next(now time.Time) (*image.Gray, bool) {
+	c.expire(now)
+	if !c.dirty || (c.lastFrame != nil && now.Sub(c.lastTime) < c.minInterval) {
+		return nil, false
+	}
+	c.dirty = false
+
+	frame := c.Compose()
+	if c.lastFrame != nil && bytes.Equal(frame.Pix, c.lastFrame.Pix) {
+		return nil, false
+	}
+	c.lastFrame = frame
+	c.lastTime = now
+	return frame, true
+}
+
+// Compose draws the current layers over a white background.
+func (c *Compositor) Compose() *image.Gray {
+	frame := image.NewGray(c.bounds)
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	layers := slices.Clone(c.layers)
+	slices.SortStableFunc(layers, func(a, b layer) int { return a.z - b.z })
+	for _, l := range layers {
+		draw.Draw(frame, l.img.Bounds(), l.img, l.img.Bounds().Min, draw.Over)
+	}
+	return frame
+}
+
+// expire removes the layers that have timed out at now.
+func (c *Compositor) expire(now time.Time) {
+	c.layers = slices.DeleteFunc(c.layers, func(l layer) bool {
+		if !l.until.IsZero() && !now.Before(l.until) {
+			c.dirty = true
+			return true
+		}
+		return false
+	})
+}
@@ -0,0 +1,140 @@
+package lcdcompositor_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/lcdcompositor"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	black = color.Gray{}
+	white = color.Gray{Y: 0xff}
+)
+
+// filled returns an image with the given bounds filled with a single colour.
+func filled(rect image.Rectangle, c color.Gray) *image.Gray {
+	img := image.NewGray(rect)
+	for idx := range img.Pix {
+		img.Pix[idx] = c.Y
+	}
+	return img
+}
+
+func TestLayers(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	c := lcdcompositor.New(4, 4, 0)
+
+	// nothing to draw before anything is set
+	_, ok := c.Next(now)
+	assert.False(ok)
+
+	c.Set("background", 0, filled(image.Rect(0, 0, 4, 4), black), time.Time{})
+	c.Set("bar", 10, filled(image.Rect(0, 3, 4, 4), white), time.Time{})
+	frame, ok := c.Next(now)
+	assert.True(ok)
+	assert.Equal(black, frame.GrayAt(0, 2))
+	assert.Equal(white, frame.GrayAt(0, 3))
+
+	// a lower layer added later is still drawn under the higher one, so the
+	// frame doesn't change
+	c.Set("under", 5, filled(image.Rect(0, 2, 4, 4), black), time.Time{})
+	_, ok = c.Next(now)
+	assert.False(ok)
+	assert.Equal(white, c.Compose().GrayAt(0, 3))
+
+	assert.True(c.Has("bar"))
+	assert.True(c.Remove("bar"))
+	assert.False(c.Remove("bar"))
+	assert.False(c.Has("bar"))
+	frame, ok = c.Next(now)
+	assert.True(ok)
+	assert.Equal(black, frame.GrayAt(0, 3))
+
+	// removing everything draws a blank frame
+	c.Remove("background")
+	c.Remove("under")
+	frame, ok = c.Next(now)
+	assert.True(ok)
+	assert.Equal(filled(image.Rect(0, 0, 4, 4), white), frame)
+}
+
+func TestUnchanged(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	c := lcdcompositor.New(2, 2, 0)
+	c.Set("page", 0, filled(image.Rect(0, 0, 2, 2), black), time.Time{})
+	_, ok := c.Next(now)
+	assert.True(ok)
+
+	// nothing changed
+	_, ok = c.Next(now)
+	assert.False(ok)
+
+	// the same content again
+	c.Set("page", 0, filled(image.Rect(0, 0, 2, 2), black), time.Time{})
+	_, ok = c.Next(now)
+	assert.False(ok)
+
+	// unless the last frame has to be drawn again
+	c.Invalidate()
+	_, ok = c.Next(now)
+	assert.True(ok)
+}
+
+func TestFrameRate(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	c := lcdcompositor.New(2, 2, 100*time.Millisecond)
+	c.Set("page", 0, filled(image.Rect(0, 0, 2, 2), black), time.Time{})
+	_, ok := c.Next(now)
+	assert.True(ok)
+
+	// the change is held back until the interval has passed
+	c.Set("page", 0, filled(image.Rect(0, 0, 2, 2), white), time.Time{})
+	_, ok = c.Next(now.Add(50 * time.Millisecond))
+	assert.False(ok)
+	frame, ok := c.Next(now.Add(100 * time.Millisecond))
+	assert.True(ok)
+	assert.Equal(white, frame.GrayAt(0, 0))
+}
+
+func TestTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	c := lcdcompositor.New(2, 2, 0)
+	c.Set("page", 0, filled(image.Rect(0, 0, 2, 2), white), time.Time{})
+	c.Set("notice", 10, filled(image.Rect(0, 0, 2, 2), black), now.Add(time.Second))
+	frame, ok := c.Next(now)
+	assert.True(ok)
+	assert.Equal(black, frame.GrayAt(0, 0))
+
+	_, ok = c.Next(now.Add(500 * time.Millisecond))
+	assert.False(ok)
+	frame, ok = c.Next(now.Add(time.Second))
+	assert.True(ok)
+	assert.Equal(white, frame.GrayAt(0, 0))
+	assert.False(c.Has("notice"))
+}
+
+func TestTransparency(t *testing.T) {
+	assert := assert.New(t)
+
+	c := lcdcompositor.New(2, 1, 0)
+	c.Set("page", 0, filled(image.Rect(0, 0, 2, 1), black), time.Time{})
+	overlay := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	overlay.Set(1, 0, color.White)
+	c.Set("overlay", 1, overlay, time.Time{})
+
+	frame := c.Compose()
+	assert.Equal(black, frame.GrayAt(0, 0))
+	assert.Equal(white, frame.GrayAt(1, 0))
+}
@@ -0,0 +1,189 @@
+package lcdimage
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// ConversionMode defines how the shades of an image are reduced to the black
+// and white pixels of the LCD.
+type ConversionMode int
+
+const (
+	// ConvertThreshold turns on pixels darker than the threshold.
+	ConvertThreshold ConversionMode = iota
+
+	// ConvertFloydSteinberg applies Floyd–Steinberg error diffusion
+	// dithering, which preserves the most detail in photos.
+	ConvertFloydSteinberg
+
+	// ConvertAtkinson applies Atkinson dithering, which diffuses only part of
+	// the error and produces higher contrast than Floyd–Steinberg.
+	ConvertAtkinson
+
+	// ConvertBayer applies ordered dithering with a 4x4 Bayer matrix, which
+	// produces a regular pattern.
+	ConvertBayer
+)
+
+// DefaultThreshold is the luminance below which pixels are turned on when no
+// threshold is configured.
+const DefaultThreshold = 128
+
+var conversionModeNames = map[ConversionMode]string{
+	ConvertThreshold:      "threshold",
+	ConvertFloydSteinberg: "floyd-steinberg",
+	ConvertAtkinson:       "atkinson",
+	ConvertBayer:          "bayer",
+}
+
+func (m ConversionMode) String() string {
+	if name, ok := conversionModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("ConversionMode(%d)", int(m))
+}
+
+// ParseConversionMode returns the conversion mode with the given name. An
+// empty name returns [ConvertThreshold].
+func ParseConversionMode(name string) (ConversionMode, error) {
+	if name == "" {
+		return ConvertThreshold, nil
+	}
+	for mode, modeName := range conversionModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown image conversion mode: %s", name)
+}
+
+// Conversion describes how an image is converted to black and white.
+type Conversion struct {
+	Mode ConversionMode
+
+	// Luminance (0-255) below which pixels are turned on. For the dithering
+	// modes, it shifts the balance between black and white pixels.
+	Threshold uint8
+
+	// Invert swaps black and white after conversion.
+	Invert bool
+}
+
+// DefaultConversion returns the conversion used when nothing is configured.
+func DefaultConversion() Conversion {
+	return Conversion{Mode: ConvertThreshold, Threshold: DefaultThreshold}
+}
+
+// bayer4 is the 4x4 Bayer threshold matrix.
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// diffusion is a single entry of an error diffusion kernel: the offset of the
+// neighbouring pixel and the fraction of the error it receives.
+type diffusion struct {
+	dx, dy int
+	weight float64
+}
+
+var (
+	floydSteinberg = []diffusion{
+		{1, 0, 7.0 / 16},
+		{-1, 1, 3.0 / 16},
+		{0, 1, 5.0 / 16},
+		{1, 1, 1.0 / 16},
+	}
+
+	atkinson = []diffusion{
+		{1, 0, 1.0 / 8},
+		{2, 0, 1.0 / 8},
+		{-1, 1, 1.0 / 8},
+		{0, 1, 1.0 / 8},
+		{1, 1, 1.0 / 8},
+		{0, 2, 1.0 / 8},
+	}
+)
+
+// Apply returns a black and white copy of img. Black pixels are the ones that
+// are turned on when the image is drawn on the LCD. Transparent areas of the
+// image are treated as white.
+func (c Conversion) Apply(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	lum := luminance(img)
+	threshold := float64(c.Threshold)
+
+	out := image.NewGray(bounds)
+	set := func(x, y int, on bool) {
+		if on == c.Invert {
+			out.Pix[y*out.Stride+x] = 0xff
+		}
+	}
+
+	var kernel []diffusion
+	switch c.Mode {
+	case ConvertFloydSteinberg:
+		kernel = floydSteinberg
+	case ConvertAtkinson:
+		kernel = atkinson
+	}
+
+	for y := range h {
+		for x := range w {
+			v := lum[y*w+x]
+			switch c.Mode {
+			case ConvertBayer:
+				// shift the threshold by the matrix value, centred on the
+				// configured threshold
+				offset := (bayer4[y%4][x%4]+0.5)/16 - 0.5
+				set(x, y, v < threshold+offset*255)
+			case ConvertFloydSteinberg, ConvertAtkinson:
+				on := v < threshold
+				set(x, y, on)
+				quantised := 255.0
+				if on {
+					quantised = 0
+				}
+				diff := v - quantised
+				for _, d := range kernel {
+					nx, ny := x+d.dx, y+d.dy
+					if nx < 0 || nx >= w || ny >= h {
+						continue
+					}
+					lum[ny*w+nx] += diff * d.weight
+				}
+			default:
+				set(x, y, v < threshold)
+			}
+		}
+	}
+	return out
+}
+
+// luminance returns the luminance (0-255) of each pixel of img, row by row,
+// with transparent pixels blended onto white.
+func luminance(img image.Image) []float64 {
+	bounds := img.Bounds()
+	lum := make([]float64, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// colours are alpha-premultiplied, so blending onto white adds
+			// the missing alpha to each channel
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			gray := color.Gray16Model.Convert(color.RGBA64{
+				R: uint16(r + white),
+				G: uint16(g + white),
+				B: uint16(b + white),
+				A: 0xffff,
+			}).(color.Gray16)
+			lum = append(lum, float64(gray.Y)/0x101)
+		}
+	}
+	return lum
+}
@@ -0,0 +1,132 @@
+package lcdimage_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/stretchr/testify/assert"
+)
+
+var allConversionModes = []lcdimage.ConversionMode{
+	lcdimage.ConvertThreshold,
+	lcdimage.ConvertFloydSteinberg,
+	lcdimage.ConvertAtkinson,
+	lcdimage.ConvertBayer,
+}
+
+// uniformImage returns an image of the given size filled with a single shade
+// of grey.
+func uniformImage(w, h int, shade uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for idx := range img.Pix {
+		img.Pix[idx] = shade
+	}
+	return img
+}
+
+// blackRatio returns the fraction of black pixels in img.
+func blackRatio(img *image.Gray) float64 {
+	black := 0
+	for _, v := range img.Pix {
+		if v == 0 {
+			black++
+		}
+	}
+	return float64(black) / float64(len(img.Pix))
+}
+
+func TestParseConversionMode(t *testing.T) {
+	assert := assert.New(t)
+	for _, mode := range allConversionModes {
+		parsed, err := lcdimage.ParseConversionMode(mode.String())
+		assert.NoError(err)
+		assert.Equal(mode, parsed)
+	}
+
+	mode, err := lcdimage.ParseConversionMode("")
+	assert.NoError(err)
+	assert.Equal(lcdimage.ConvertThreshold, mode)
+
+	_, err = lcdimage.ParseConversionMode("halftone")
+	assert.EqualError(err, "unknown image conversion mode: halftone")
+}
+
+func TestConversionBlackAndWhite(t *testing.T) {
+	// pure black and white images are unchanged by every mode
+	for _, mode := range allConversionModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			assert := assert.New(t)
+			conv := lcdimage.DefaultConversion()
+			conv.Mode = mode
+
+			assert.Equal(1.0, blackRatio(conv.Apply(uniformImage(40, 20, 0))))
+			assert.Equal(0.0, blackRatio(conv.Apply(uniformImage(40, 20, 0xff))))
+
+			conv.Invert = true
+			assert.Equal(0.0, blackRatio(conv.Apply(uniformImage(40, 20, 0))))
+			assert.Equal(1.0, blackRatio(conv.Apply(uniformImage(40, 20, 0xff))))
+		})
+	}
+}
+
+func TestConversionThreshold(t *testing.T) {
+	assert := assert.New(t)
+	img := image.NewGray(image.Rect(5, 5, 8, 6))
+	img.Pix = []uint8{10, 100, 200}
+
+	out := lcdimage.DefaultConversion().Apply(img)
+	assert.Equal(img.Bounds(), out.Bounds())
+	assert.Equal([]uint8{0, 0, 0xff}, out.Pix)
+
+	out = lcdimage.Conversion{Threshold: 50}.Apply(img)
+	assert.Equal([]uint8{0, 0xff, 0xff}, out.Pix)
+
+	out = lcdimage.Conversion{Threshold: 50, Invert: true}.Apply(img)
+	assert.Equal([]uint8{0xff, 0, 0}, out.Pix)
+}
+
+func TestConversionDither(t *testing.T) {
+	type testCase struct {
+		shade    uint8
+		expected float64
+	}
+	// dithered images should have about as many black pixels as the shade
+	// calls for
+	testCases := []testCase{
+		{shade: 64, expected: 0.75},
+		{shade: 128, expected: 0.5},
+		{shade: 191, expected: 0.25},
+	}
+	for _, mode := range []lcdimage.ConversionMode{lcdimage.ConvertFloydSteinberg, lcdimage.ConvertBayer} {
+		t.Run(mode.String(), func(t *testing.T) {
+			assert := assert.New(t)
+			conv := lcdimage.DefaultConversion()
+			conv.Mode = mode
+			for _, tc := range testCases {
+				ratio := blackRatio(conv.Apply(uniformImage(64, 64, tc.shade)))
+				assert.InDelta(tc.expected, ratio, 0.05, "shade %d", tc.shade)
+			}
+		})
+	}
+
+	t.Run("atkinson", func(t *testing.T) {
+		assert := assert.New(t)
+		// Atkinson dithering loses some of the error, so only check that it
+		// produces a mix of black and white pixels
+		conv := lcdimage.Conversion{Mode: lcdimage.ConvertAtkinson, Threshold: lcdimage.DefaultThreshold}
+		ratio := blackRatio(conv.Apply(uniformImage(64, 64, 128)))
+		assert.Greater(ratio, 0.3)
+		assert.Less(ratio, 0.7)
+	})
+}
+
+func TestConversionTransparent(t *testing.T) {
+	assert := assert.New(t)
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(1, 0, color.RGBA{0, 0, 0, 0xff})
+
+	out := lcdimage.DefaultConversion().Apply(img)
+	assert.Equal([]uint8{0xff, 0}, out.Pix)
+}
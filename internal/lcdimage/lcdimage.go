@@ -0,0 +1,144 @@
+// Package lcdimage loads image files and prepares them for the G13 LCD.
+//
+// Images can be in any of the BMP, PNG, GIF, JPEG, or netpbm (PBM, PGM, PPM)
+// formats and of any size. They are fitted to the LCD according to a
+// [ScaleMode].
+package lcdimage
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF format
+	_ "image/jpeg" // register JPEG format
+	_ "image/png"  // register PNG format
+	"io"
+	"os"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	_ "golang.org/x/image/bmp" // register BMP format
+	"golang.org/x/image/draw"
+)
+
+// ScaleMode defines how an image that doesn't match the size of the LCD is
+// fitted to it.
+type ScaleMode int
+
+const (
+	// ScaleFit scales the image, preserving its aspect ratio, so that the
+	// whole image fits on the LCD. Any remaining space is left blank.
+	ScaleFit ScaleMode = iota
+
+	// ScaleFill scales the image, preserving its aspect ratio, so that it
+	// covers the whole LCD. Any overflow is cropped equally on both sides.
+	ScaleFill
+
+	// ScaleCrop shows the image at its original size, aligned to the top left
+	// corner of the LCD. Any overflow is cropped on the right and bottom.
+	ScaleCrop
+
+	// ScaleCenter shows the image at its original size, centred on the LCD.
+	ScaleCenter
+)
+
+// DefaultScaleMode is used when no scale mode is configured.
+const DefaultScaleMode = ScaleFit
+
+var scaleModeNames = map[ScaleMode]string{
+	ScaleFit:    "fit",
+	ScaleFill:   "fill",
+	ScaleCrop:   "crop",
+	ScaleCenter: "center",
+}
+
+func (m ScaleMode) String() string {
+	if name, ok := scaleModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("ScaleMode(%d)", int(m))
+}
+
+// ParseScaleMode returns the scale mode with the given name. An empty name
+// returns the [DefaultScaleMode].
+func ParseScaleMode(name string) (ScaleMode, error) {
+	if name == "" {
+		return DefaultScaleMode, nil
+	}
+	for mode, modeName := range scaleModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown image scale mode: %s", name)
+}
+
+// Decode reads an image in any of the supported formats.
+func Decode(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Load reads the image file at the given path.
+func Load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	img, err := Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image file %q: %w", path, err)
+	}
+	return img, nil
+}
+
+// Fit returns a copy of img with the size of the LCD, with the image placed on
+// a white background according to the scale mode. Transparent areas of the
+// image are also white.
+func Fit(img image.Image, mode ScaleMode) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+
+	src := img.Bounds()
+	if src.Empty() {
+		return dst
+	}
+	lcd := dst.Bounds()
+	srcW, srcH := src.Dx(), src.Dy()
+
+	switch mode {
+	case ScaleCrop:
+		draw.Draw(dst, lcd, img, src.Min, draw.Over)
+		return dst
+	case ScaleCenter:
+		offset := image.Pt((lcd.Dx()-srcW)/2, (lcd.Dy()-srcH)/2)
+		draw.Draw(dst, src.Sub(src.Min).Add(offset), img, src.Min, draw.Over)
+		return dst
+	}
+
+	// the scaled size is the largest (fit) or smallest (fill) size with the
+	// aspect ratio of the image that covers the LCD in one dimension
+	scaleX := float64(lcd.Dx()) / float64(srcW)
+	scaleY := float64(lcd.Dy()) / float64(srcH)
+	scale := min(scaleX, scaleY)
+	if mode == ScaleFill {
+		scale = max(scaleX, scaleY)
+	}
+	w := max(1, int(float64(srcW)*scale+0.5))
+	h := max(1, int(float64(srcH)*scale+0.5))
+	target := image.Rect(0, 0, w, h).Add(image.Pt((lcd.Dx()-w)/2, (lcd.Dy()-h)/2))
+
+	if w == srcW && h == srcH {
+		draw.Draw(dst, target, img, src.Min, draw.Over)
+		return dst
+	}
+
+	// nearest neighbour keeps pixel art sharp when enlarging, while shrinking
+	// needs to average pixels to not lose detail entirely
+	var scaler draw.Scaler = draw.NearestNeighbor
+	if scale < 1 {
+		scaler = draw.ApproxBiLinear
+	}
+	scaler.Scale(dst, target, img, src, draw.Over, nil)
+	return dst
+}
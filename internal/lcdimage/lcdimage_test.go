@@ -0,0 +1,194 @@
+package lcdimage_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeNetpbm(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	type testCase struct {
+		data     []byte
+		expected [][]color.RGBA
+	}
+
+	testCases := map[string]testCase{
+		"pbm-plain": {
+			data:     []byte("P1\n# comment\n3 2\n1 0 1\n010\n"),
+			expected: [][]color.RGBA{{black, white, black}, {white, black, white}},
+		},
+		"pbm-raw": {
+			data:     append([]byte("P4 3 2\n"), 0b10100000, 0b01000000),
+			expected: [][]color.RGBA{{black, white, black}, {white, black, white}},
+		},
+		"pgm-plain": {
+			data:     []byte("P2 2 1 15 0 15"),
+			expected: [][]color.RGBA{{black, white}},
+		},
+		"pgm-raw-16bit": {
+			data:     append([]byte("P5 2 1 65535\n"), 0xff, 0xff, 0, 0),
+			expected: [][]color.RGBA{{white, black}},
+		},
+		"ppm-plain": {
+			data:     []byte("P3 2 1 255\n255 0 0  0 0 255\n"),
+			expected: [][]color.RGBA{{{0xff, 0, 0, 0xff}, {0, 0, 0xff, 0xff}}},
+		},
+		"ppm-raw": {
+			data:     append([]byte("P6 1 2 255\n"), 1, 2, 3, 4, 5, 6),
+			expected: [][]color.RGBA{{{1, 2, 3, 0xff}}, {{4, 5, 6, 0xff}}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			img, err := lcdimage.Decode(bytes.NewReader(tc.data))
+			assert.NoError(err)
+			assert.Equal(image.Rect(0, 0, len(tc.expected[0]), len(tc.expected)), img.Bounds())
+			for y, row := range tc.expected {
+				for x, c := range row {
+					assert.Equal(c, color.RGBAModel.Convert(img.At(x, y)), "pixel %d,%d", x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeNetpbmErrors(t *testing.T) {
+	testCases := map[string]string{
+		"P1 0 2\n":            "netpbm: invalid image size 0x2",
+		"P2 1 1 0\n0":         "netpbm: invalid maximum value 0",
+		"P2 1 1 10\n11":       "netpbm: sample value 11 exceeds maximum 10",
+		"P1 2 1\n12":          "netpbm: invalid bitmap value '2'",
+		"P5 2 2 255\n\x00":    "unexpected EOF",
+		"P3 x 1 255\n0 0 0\n": "netpbm: expected a number",
+
+		// the size is checked before anything is allocated
+		"P5 16777216 16777216 255 ": "netpbm: image size 16777216x16777216 too large",
+		"P6 4096 4097 255\n":        "netpbm: image size 4096x4097 too large",
+		"P6 4096 4096 255\n\x00":    "unexpected EOF",
+		"P2 4096 4096 255\n0 0 0":   "unexpected EOF",
+	}
+
+	for data, expectedErr := range testCases {
+		_, err := lcdimage.Decode(bytes.NewReader([]byte(data)))
+		assert.EqualError(t, err, expectedErr, "%q", data)
+	}
+}
+
+func TestParseScaleMode(t *testing.T) {
+	assert := assert.New(t)
+	for _, mode := range []lcdimage.ScaleMode{lcdimage.ScaleFit, lcdimage.ScaleFill, lcdimage.ScaleCrop, lcdimage.ScaleCenter} {
+		parsed, err := lcdimage.ParseScaleMode(mode.String())
+		assert.NoError(err)
+		assert.Equal(mode, parsed)
+	}
+
+	mode, err := lcdimage.ParseScaleMode("")
+	assert.NoError(err)
+	assert.Equal(lcdimage.DefaultScaleMode, mode)
+
+	_, err = lcdimage.ParseScaleMode("stretch")
+	assert.EqualError(err, "unknown image scale mode: stretch")
+}
+
+// blackImage returns a black image with the given size.
+func blackImage(w, h int) image.Image {
+	return image.NewGray(image.Rect(0, 0, w, h))
+}
+
+// isBlack returns true if the pixel at x, y of img is black.
+func isBlack(img image.Image, x, y int) bool {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return r == 0 && g == 0 && b == 0
+}
+
+func TestFit(t *testing.T) {
+	lcd := image.Rect(0, 0, device.LCDWidth, device.LCDHeight)
+
+	t.Run("fit", func(t *testing.T) {
+		assert := assert.New(t)
+		// twice the height of the LCD and square: scaled to 43x43 and centred
+		img := lcdimage.Fit(blackImage(86, 86), lcdimage.ScaleFit)
+		assert.Equal(lcd, img.Bounds())
+		assert.True(isBlack(img, 80, 0))
+		assert.True(isBlack(img, 80, 42))
+		assert.True(isBlack(img, 59, 21))
+		assert.True(isBlack(img, 100, 21))
+		assert.False(isBlack(img, 57, 21))
+		assert.False(isBlack(img, 102, 21))
+	})
+
+	t.Run("fill", func(t *testing.T) {
+		assert := assert.New(t)
+		img := lcdimage.Fit(blackImage(86, 86), lcdimage.ScaleFill)
+		assert.Equal(lcd, img.Bounds())
+		assert.True(isBlack(img, 0, 0))
+		assert.True(isBlack(img, 159, 42))
+	})
+
+	t.Run("crop", func(t *testing.T) {
+		assert := assert.New(t)
+		img := lcdimage.Fit(blackImage(10, 500), lcdimage.ScaleCrop)
+		assert.Equal(lcd, img.Bounds())
+		assert.True(isBlack(img, 0, 0))
+		assert.True(isBlack(img, 9, 42))
+		assert.False(isBlack(img, 10, 0))
+	})
+
+	t.Run("center", func(t *testing.T) {
+		assert := assert.New(t)
+		img := lcdimage.Fit(blackImage(10, 10), lcdimage.ScaleCenter)
+		assert.Equal(lcd, img.Bounds())
+		assert.True(isBlack(img, 75, 16))
+		assert.True(isBlack(img, 84, 25))
+		assert.False(isBlack(img, 74, 16))
+		assert.False(isBlack(img, 85, 25))
+		assert.False(isBlack(img, 0, 0))
+	})
+
+	t.Run("exact-size", func(t *testing.T) {
+		assert := assert.New(t)
+		src := image.NewGray(lcd)
+		src.SetGray(3, 4, color.Gray{Y: 0xff})
+		for _, mode := range []lcdimage.ScaleMode{lcdimage.ScaleFit, lcdimage.ScaleFill, lcdimage.ScaleCrop, lcdimage.ScaleCenter} {
+			img := lcdimage.Fit(src, mode)
+			assert.False(isBlack(img, 3, 4), mode.String())
+			assert.True(isBlack(img, 4, 4), mode.String())
+		}
+	})
+
+	t.Run("transparent", func(t *testing.T) {
+		assert := assert.New(t)
+		img := lcdimage.Fit(image.NewRGBA(lcd), lcdimage.ScaleFit)
+		assert.False(isBlack(img, 0, 0))
+	})
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	fp, err := os.Create(path)
+	assert.NoError(err)
+	assert.NoError(png.Encode(fp, blackImage(20, 10)))
+	assert.NoError(fp.Close())
+
+	img, err := lcdimage.Load(path)
+	assert.NoError(err)
+	assert.Equal(image.Rect(0, 0, 20, 10), img.Bounds())
+
+	_, err = lcdimage.Load(filepath.Join(t.TempDir(), "nope.png"))
+	assert.ErrorContains(err, "failed to open image file")
+}
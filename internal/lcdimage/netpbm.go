@@ -0,0 +1,326 @@
+package lcdimage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Decoding of the netpbm formats: PBM (P1, P4), PGM (P2, P5), and PPM (P3,
+// P6). Samples with a maximum value other than 255 are rescaled to 8 bits.
+
+func init() {
+	image.RegisterFormat("pbm", "P1", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pbm", "P4", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pgm", "P2", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pgm", "P5", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P3", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P6", decodeNetpbm, decodeNetpbmConfig)
+}
+
+// Largest decoded image accepted, in bytes. The header alone sets the size, so
+// without a limit a few bytes of input could ask for terabytes.
+const maxNetpbmBytes = 64 << 20
+
+type netpbmHeader struct {
+	magic  byte // the digit after the 'P'
+	width  int
+	height int
+	maxVal int
+}
+
+func (h netpbmHeader) bitmap() bool {
+	return h.magic == '1' || h.magic == '4'
+}
+
+func (h netpbmHeader) colour() bool {
+	return h.magic == '3' || h.magic == '6'
+}
+
+func (h netpbmHeader) plain() bool {
+	return h.magic <= '3'
+}
+
+// imageBytes returns the size of the decoded image.
+func (h netpbmHeader) imageBytes() int {
+	if h.colour() {
+		return h.width * h.height * 4
+	}
+	return h.width * h.height
+}
+
+// minRasterBytes returns the least input that can hold the raster. Plain
+// samples need at least a digit and a separator each, except bitmap digits
+// which don't need separators.
+func (h netpbmHeader) minRasterBytes() int {
+	samples := h.width * h.height
+	if h.colour() {
+		samples *= 3
+	}
+	switch {
+	case h.magic == '1':
+		return samples
+	case h.plain():
+		return 2*samples - 1
+	case h.bitmap():
+		return (h.width + 7) / 8 * h.height
+	case h.maxVal > 255:
+		return 2 * samples
+	default:
+		return samples
+	}
+}
+
+func (h netpbmHeader) colorModel() color.Model {
+	if h.colour() {
+		return color.RGBAModel
+	}
+	return color.GrayModel
+}
+
+type netpbmReader struct {
+	*bufio.Reader
+}
+
+// skipSpace skips whitespace and comments. Comments start with '#' and run to
+// the end of the line.
+func (r netpbmReader) skipSpace() error {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == '#':
+			if _, err := r.ReadString('\n'); err != nil {
+				return err
+			}
+		case isSpace(c):
+		default:
+			return r.UnreadByte()
+		}
+	}
+}
+
+// readInt reads a decimal number, skipping any whitespace and comments before
+// it.
+func (r netpbmReader) readInt() (int, error) {
+	if err := r.skipSpace(); err != nil {
+		return 0, err
+	}
+	n, digits := 0, 0
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF && digits > 0 {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if c < '0' || c > '9' {
+			if err := r.UnreadByte(); err != nil {
+				return 0, err
+			}
+			break
+		}
+		n = n*10 + int(c-'0')
+		if n > 1<<24 {
+			return 0, errors.New("netpbm: number too large")
+		}
+		digits++
+	}
+	if digits == 0 {
+		return 0, errors.New("netpbm: expected a number")
+	}
+	return n, nil
+}
+
+func (r netpbmReader) readHeader() (netpbmHeader, error) {
+	var h netpbmHeader
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return h, err
+	}
+	if magic[0] != 'P' || magic[1] < '1' || magic[1] > '6' {
+		return h, fmt.Errorf("netpbm: unsupported format %q", magic)
+	}
+	h.magic = magic[1]
+
+	var err error
+	if h.width, err = r.readInt(); err != nil {
+		return h, err
+	}
+	if h.height, err = r.readInt(); err != nil {
+		return h, err
+	}
+	if h.width <= 0 || h.height <= 0 {
+		return h, fmt.Errorf("netpbm: invalid image size %dx%d", h.width, h.height)
+	}
+	if h.imageBytes() > maxNetpbmBytes {
+		return h, fmt.Errorf("netpbm: image size %dx%d too large", h.width, h.height)
+	}
+	h.maxVal = 1
+	if !h.bitmap() {
+		if h.maxVal, err = r.readInt(); err != nil {
+			return h, err
+		}
+		if h.maxVal <= 0 || h.maxVal > 65535 {
+			return h, fmt.Errorf("netpbm: invalid maximum value %d", h.maxVal)
+		}
+	}
+
+	if !h.plain() {
+		// a single whitespace character separates the header from the
+		// raster
+		c, err := r.ReadByte()
+		if err != nil {
+			return h, err
+		}
+		if !isSpace(c) {
+			return h, errors.New("netpbm: missing whitespace after header")
+		}
+	}
+	return h, nil
+}
+
+// readSample reads a single sample and scales it to 8 bits.
+func (r netpbmReader) readSample(h netpbmHeader) (uint8, error) {
+	var v int
+	switch {
+	case h.plain():
+		var err error
+		if v, err = r.readInt(); err != nil {
+			return 0, err
+		}
+	case h.maxVal > 255:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		v = int(b[0])<<8 | int(b[1])
+	default:
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v = int(b)
+	}
+	if v > h.maxVal {
+		return 0, fmt.Errorf("netpbm: sample value %d exceeds maximum %d", v, h.maxVal)
+	}
+	return uint8((v*255 + h.maxVal/2) / h.maxVal), nil
+}
+
+// readBit reads a single plain PBM pixel. Digits don't need to be separated by
+// whitespace.
+func (r netpbmReader) readBit() (bool, error) {
+	if err := r.skipSpace(); err != nil {
+		return false, err
+	}
+	c, err := r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case '0':
+		return false, nil
+	case '1':
+		return true, nil
+	}
+	return false, fmt.Errorf("netpbm: invalid bitmap value %q", c)
+}
+
+func decodeNetpbmConfig(r io.Reader) (image.Config, error) {
+	h, err := netpbmReader{bufio.NewReader(r)}.readHeader()
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: h.colorModel(), Width: h.width, Height: h.height}, nil
+}
+
+func decodeNetpbm(r io.Reader) (image.Image, error) {
+	pr := netpbmReader{bufio.NewReader(r)}
+	h, err := pr.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	// read as much of the raster as it needs at least before allocating the
+	// image, so that a header can't allocate more than the input could fill
+	raster, err := io.ReadAll(io.LimitReader(pr, int64(h.minRasterBytes())))
+	if err != nil {
+		return nil, err
+	}
+	if len(raster) < h.minRasterBytes() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	pr = netpbmReader{bufio.NewReader(io.MultiReader(bytes.NewReader(raster), pr))}
+	bounds := image.Rect(0, 0, h.width, h.height)
+
+	switch {
+	case h.bitmap():
+		// in bitmaps, 1 is black
+		img := image.NewGray(bounds)
+		row := make([]byte, (h.width+7)/8)
+		for y := range h.height {
+			if !h.plain() {
+				if _, err := io.ReadFull(pr, row); err != nil {
+					return nil, unexpectedEOF(err)
+				}
+			}
+			for x := range h.width {
+				var black bool
+				if h.plain() {
+					if black, err = pr.readBit(); err != nil {
+						return nil, unexpectedEOF(err)
+					}
+				} else {
+					black = row[x/8]&(0x80>>(x%8)) != 0
+				}
+				if !black {
+					img.Pix[y*img.Stride+x] = 0xff
+				}
+			}
+		}
+		return img, nil
+	case h.colour():
+		img := image.NewRGBA(bounds)
+		for y := range h.height {
+			for x := range h.width {
+				idx := y*img.Stride + x*4
+				for c := range 3 {
+					if img.Pix[idx+c], err = pr.readSample(h); err != nil {
+						return nil, unexpectedEOF(err)
+					}
+				}
+				img.Pix[idx+3] = 0xff
+			}
+		}
+		return img, nil
+	default:
+		img := image.NewGray(bounds)
+		for y := range h.height {
+			for x := range h.width {
+				if img.Pix[y*img.Stride+x], err = pr.readSample(h); err != nil {
+					return nil, unexpectedEOF(err)
+				}
+			}
+		}
+		return img, nil
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package lcdpage
+
+import (
+	"image"
+	"sync/atomic"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+)
+
+// Default formats of the clock page, as [time.Time.Format] layouts.
+const (
+	DefaultTimeFormat = "15:04:05"
+	DefaultDateFormat = "Mon 2 Jan 2006"
+)
+
+// Clock is a page showing the current time. Selecting it switches between
+// showing the time and the date.
+type Clock struct {
+	name       string
+	timeFormat string
+	dateFormat string
+	style      lcdtext.Style
+
+	// showing the date instead of the time; may be read by a playback
+	// goroutine that is still stopping when the page is selected
+	showDate atomic.Bool
+}
+
+// NewClock returns a clock page. Empty formats use the defaults.
+func NewClock(name, timeFormat, dateFormat string, style lcdtext.Style) *Clock {
+	if timeFormat == "" {
+		timeFormat = DefaultTimeFormat
+	}
+	if dateFormat == "" {
+		dateFormat = DefaultDateFormat
+	}
+	return &Clock{
+		name:       name,
+		timeFormat: timeFormat,
+		dateFormat: dateFormat,
+		style:      style,
+	}
+}
+
+func (c *Clock) Name() string {
+	return c.name
+}
+
+// Select switches between showing the time and the date.
+func (c *Clock) Select() {
+	c.showDate.Store(!c.showDate.Load())
+}
+
+// Render returns the image of the clock at the given time.
+func (c *Clock) Render(now time.Time) image.Image {
+	format := c.timeFormat
+	if c.showDate.Load() {
+		format = c.dateFormat
+	}
+	return lcdtext.Render([]string{now.Format(format)}, c.style)
+}
+
+func (c *Clock) Play(stop <-chan struct{}) <-chan image.Image {
+	images := make(chan image.Image)
+	go func() {
+		defer close(images)
+		for {
+			now := time.Now()
+			select {
+			case images <- c.Render(now):
+			case <-stop:
+				return
+			}
+			// update at the start of the next second
+			timer := time.NewTimer(now.Truncate(time.Second).Add(time.Second).Sub(time.Now()))
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return images
+}
@@ -0,0 +1,38 @@
+package lcdpage
+
+import (
+	"image"
+
+	"github.com/achilleas-k/gg13/internal/keymap"
+)
+
+// Keymap is a page showing what the G keys and the stick are bound to.
+type Keymap struct {
+	name   string
+	labels func() keymap.Labels
+}
+
+// NewKeymap returns a key binding page. The labels are read each time the
+// page starts playing, so a page that is playing is restarted to show changes
+// to the bindings, like a new stick mode.
+func NewKeymap(name string, labels func() keymap.Labels) *Keymap {
+	return &Keymap{name: name, labels: labels}
+}
+
+func (k *Keymap) Name() string {
+	return k.name
+}
+
+func (k *Keymap) Play(stop <-chan struct{}) <-chan image.Image {
+	// read the labels in the caller's goroutine, where the bindings change
+	img := keymap.RenderLCD(k.labels())
+	images := make(chan image.Image)
+	go func() {
+		defer close(images)
+		select {
+		case images <- img:
+		case <-stop:
+		}
+	}()
+	return images
+}
@@ -0,0 +1,100 @@
+// Package lcdpage implements pages of content for the G13 LCD, like images,
+// animations, text, and a clock, that are switched between with the keys
+// under the LCD.
+package lcdpage
+
+import (
+	"image"
+
+	"github.com/achilleas-k/gg13/internal/animation"
+)
+
+// Page is a single screen of content for the LCD.
+type Page interface {
+	// Name identifies the page.
+	Name() string
+
+	// Play sends the images of the page to the returned channel, starting
+	// with the current image, whenever the content changes, until stop is
+	// closed or there are no more changes. The channel is closed when
+	// playback stops.
+	Play(stop <-chan struct{}) <-chan image.Image
+}
+
+// Selecter is implemented by pages that react to the select key. Playback of
+// the page is stopped before Select is called and restarted afterwards to show
+// the result.
+type Selecter interface {
+	Select()
+}
+
+// animated is a page showing an animation. Static images are animations with
+// a single frame.
+type animated struct {
+	name string
+	anim *animation.Animation
+}
+
+// NewAnimated returns a page that plays an animation.
+func NewAnimated(name string, anim *animation.Animation) Page {
+	return &animated{name: name, anim: anim}
+}
+
+// NewStatic returns a page that shows a single image.
+func NewStatic(name string, img image.Image) Page {
+	return NewAnimated(name, &animation.Animation{
+		Frames: []animation.Frame{{Image: img}},
+	})
+}
+
+func (p *animated) Name() string {
+	return p.name
+}
+
+func (p *animated) Play(stop <-chan struct{}) <-chan image.Image {
+	return animation.Play(p.anim, stop)
+}
+
+// Pager keeps track of the page that is shown out of a list of pages.
+type Pager struct {
+	pages   []Page
+	current int
+}
+
+// NewPager returns a [Pager] for the given pages, starting at the first one.
+func NewPager(pages []Page) *Pager {
+	return &Pager{pages: pages}
+}
+
+// Len returns the number of pages.
+func (p *Pager) Len() int {
+	return len(p.pages)
+}
+
+// Current returns the page that is shown, or nil if there are no pages.
+func (p *Pager) Current() Page {
+	if len(p.pages) == 0 {
+		return nil
+	}
+	return p.pages[p.current]
+}
+
+// Next moves to the next page, wrapping around after the last one, and returns
+// it.
+func (p *Pager) Next() Page {
+	return p.move(1)
+}
+
+// Previous moves to the previous page, wrapping around before the first one,
+// and returns it.
+func (p *Pager) Previous() Page {
+	return p.move(-1)
+}
+
+func (p *Pager) move(delta int) Page {
+	if len(p.pages) == 0 {
+		return nil
+	}
+	p.current = (p.current + delta + len(p.pages)) % len(p.pages)
+	return p.pages[p.current]
+}
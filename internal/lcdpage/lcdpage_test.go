@@ -0,0 +1,339 @@
+package lcdpage_test
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/keymap"
+	"github.com/achilleas-k/gg13/internal/lcdpage"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/achilleas-k/gg13/internal/mpris"
+	"github.com/achilleas-k/gg13/internal/sysmon"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPages(names ...string) []lcdpage.Page {
+	pages := make([]lcdpage.Page, 0, len(names))
+	for _, name := range names {
+		pages = append(pages, lcdpage.NewStatic(name, image.NewGray(image.Rect(0, 0, 1, 1))))
+	}
+	return pages
+}
+
+func TestPager(t *testing.T) {
+	assert := assert.New(t)
+
+	pager := lcdpage.NewPager(testPages("one", "two", "three"))
+	assert.Equal(3, pager.Len())
+	assert.Equal("one", pager.Current().Name())
+	assert.Equal("two", pager.Next().Name())
+	assert.Equal("three", pager.Next().Name())
+	assert.Equal("one", pager.Next().Name())
+	assert.Equal("three", pager.Previous().Name())
+	assert.Equal("three", pager.Current().Name())
+
+	empty := lcdpage.NewPager(nil)
+	assert.Equal(0, empty.Len())
+	assert.Nil(empty.Current())
+	assert.Nil(empty.Next())
+	assert.Nil(empty.Previous())
+}
+
+func TestStatic(t *testing.T) {
+	assert := assert.New(t)
+
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	page := lcdpage.NewStatic("static", img)
+	var images []image.Image
+	for frame := range page.Play(make(chan struct{})) {
+		images = append(images, frame)
+	}
+	assert.Equal([]image.Image{img}, images)
+}
+
+func TestClock(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := lcdpage.NewClock("clock", "", "", lcdtext.Style{})
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	assert.Equal(lcdtext.Render([]string{"13:14:15"}, lcdtext.Style{}), clock.Render(now))
+
+	clock.Select()
+	assert.Equal(lcdtext.Render([]string{"Thu 29 Feb 2024"}, lcdtext.Style{}), clock.Render(now))
+	clock.Select()
+	assert.Equal(lcdtext.Render([]string{"13:14:15"}, lcdtext.Style{}), clock.Render(now))
+
+	custom := lcdpage.NewClock("custom", "3:04 PM", "2006-01-02", lcdtext.Style{Align: lcdtext.AlignCenter})
+	assert.Equal(lcdtext.Render([]string{"1:14 PM"}, lcdtext.Style{Align: lcdtext.AlignCenter}), custom.Render(now))
+
+	// playback sends the current time straight away and stops when asked
+	stop := make(chan struct{})
+	images := clock.Play(stop)
+	select {
+	case img := <-images:
+		assert.NotNil(img)
+	case <-time.After(time.Second):
+		assert.Fail("no image from clock")
+	}
+	close(stop)
+	for range images {
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert := assert.New(t)
+	for value, expected := range map[float64]string{
+		0:                  "0",
+		512:                "512",
+		1024:               "1K",
+		1536:               "1.5K",
+		10 * 1024:          "10K",
+		24 * 1024 * 1024:   "24M",
+		3.25 * (1 << 30):   "3.2G",
+		2048 * (1 << 60):   "2048E",
+		float64(1 << 60):   "1E",
+		-1:                 "0",
+		1023.9 * (1 << 10): "1024K",
+	} {
+		assert.Equal(expected, lcdpage.FormatBytes(value), "%v", value)
+	}
+}
+
+// blackPixels counts the black pixels of an area of an image.
+func blackPixels(img image.Image, area image.Rectangle) int {
+	count := 0
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r == 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestMonitor(t *testing.T) {
+	assert := assert.New(t)
+
+	widgets := []lcdpage.Widget{
+		{Type: lcdpage.WidgetCPU},
+		{Type: lcdpage.WidgetMemory},
+		{Type: lcdpage.WidgetClock, Format: "15:04", Area: image.Rect(130, 35, 160, 43)},
+	}
+	monitor := lcdpage.NewMonitor("monitor", widgets, 0, t.TempDir(), nil)
+	assert.Equal("monitor", monitor.Name())
+
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	sample := sysmon.Sample{
+		Time:   now,
+		CPU:    []float64{0.5, 1, 0},
+		Memory: &sysmon.Memory{Total: 16 << 30, Available: 4 << 30},
+	}
+	img := monitor.Render(sample, nil)
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+
+	// the two widgets without an area are stacked in the middle rows, with
+	// nothing above them
+	top := (device.LCDHeight - 2*lcdpage.WidgetRowHeight) / 2
+	assert.Zero(blackPixels(img, image.Rect(0, 0, 130, top)))
+	assert.NotZero(blackPixels(img, image.Rect(0, top, device.LCDWidth, top+lcdpage.WidgetRowHeight)))
+	assert.NotZero(blackPixels(img, image.Rect(0, top+lcdpage.WidgetRowHeight, device.LCDWidth, top+2*lcdpage.WidgetRowHeight)))
+	assert.NotZero(blackPixels(img, image.Rect(130, 35, 160, 43)))
+
+	// the busy core has a full bar at the right end of its row, the idle
+	// one only its baseline
+	idle := blackPixels(img, image.Rect(device.LCDWidth-2, top, device.LCDWidth, top+lcdpage.WidgetRowHeight))
+	assert.LessOrEqual(idle, 2)
+
+	// changing the usage changes the image
+	sample.CPU = []float64{0.5, 0, 1}
+	assert.NotEqual(img, monitor.Render(sample, nil))
+
+	// unavailable statistics are still drawn
+	empty := monitor.Render(sysmon.Sample{Time: now}, nil)
+	assert.NotZero(blackPixels(empty, image.Rect(0, top, device.LCDWidth, top+lcdpage.WidgetRowHeight)))
+
+	// playback reads from the root straight away and stops when asked
+	stop := make(chan struct{})
+	images := monitor.Play(stop)
+	select {
+	case img := <-images:
+		assert.NotNil(img)
+	case <-time.After(time.Second):
+		assert.Fail("no image from monitor")
+	}
+	close(stop)
+	for range images {
+	}
+}
+
+func TestMonitorMedia(t *testing.T) {
+	assert := assert.New(t)
+
+	widgets := []lcdpage.Widget{
+		{Type: lcdpage.WidgetMedia, Area: image.Rect(0, 0, device.LCDWidth, lcdpage.WidgetRowHeight)},
+		{Type: lcdpage.WidgetMedia, Area: image.Rect(0, 16, device.LCDWidth, 40)},
+	}
+	monitor := lcdpage.NewMonitor("media", widgets, 0, t.TempDir(), nil)
+	sample := sysmon.Sample{Time: time.Now()}
+	status := &mpris.Status{
+		Title:    "Song",
+		Artists:  []string{"Artist"},
+		Length:   2 * time.Minute,
+		Position: time.Minute,
+		Playing:  true,
+	}
+	img := monitor.Render(sample, status)
+
+	// the single row ends with a progress bar that is half filled
+	row := image.Rect(device.LCDWidth-30, 0, device.LCDWidth, lcdpage.WidgetRowHeight)
+	full := blackPixels(img, row)
+	assert.NotZero(full)
+	// the larger area has the title, artist, and progress on separate rows
+	for idx := 0; idx < 3; idx++ {
+		y := 16 + idx*lcdpage.WidgetRowHeight
+		assert.NotZero(blackPixels(img, image.Rect(0, y, device.LCDWidth, y+lcdpage.WidgetRowHeight)))
+	}
+
+	// the bar follows the position
+	status.Position = 0
+	assert.Less(blackPixels(monitor.Render(sample, status), row), full)
+
+	// the bar is left out for tracks of unknown length, and there is
+	// something to show when nothing is playing
+	status.Length = 0
+	assert.Zero(blackPixels(monitor.Render(sample, status), row))
+	assert.NotZero(blackPixels(monitor.Render(sample, nil), image.Rect(0, 0, device.LCDWidth, lcdpage.WidgetRowHeight)))
+}
+
+func TestParseWidgetType(t *testing.T) {
+	assert := assert.New(t)
+	for _, name := range []string{"clock", "cpu", "memory", "load", "network", "disk", "temperature", "media"} {
+		wt, err := lcdpage.ParseWidgetType(name)
+		assert.NoError(err)
+		assert.Equal(name, wt.String())
+	}
+	_, err := lcdpage.ParseWidgetType("gpu")
+	assert.EqualError(err, `unknown widget type: "gpu"`)
+}
+
+func TestKeymap(t *testing.T) {
+	assert := assert.New(t)
+
+	labels := keymap.Labels{Keys: map[device.KeyBit]string{device.G1: "Esc"}, Stick: "mouse"}
+	page := lcdpage.NewKeymap("keys", func() keymap.Labels { return labels })
+	assert.Equal("keys", page.Name())
+
+	stop := make(chan struct{})
+	frames := page.Play(stop)
+	// the labels are read when playback starts
+	labels.Stick = "keys"
+	assert.Equal(keymap.RenderLCD(keymap.Labels{Keys: labels.Keys, Stick: "mouse"}), <-frames)
+	_, ok := <-frames
+	assert.False(ok)
+	close(stop)
+}
+
+// lastImage returns the last image of a page that finishes playing.
+func lastImage(t *testing.T, images <-chan image.Image) image.Image {
+	t.Helper()
+	var last image.Image
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case img, ok := <-images:
+			if !ok {
+				return last
+			}
+			last = img
+		case <-timeout:
+			t.Fatal("page didn't finish playing")
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	t.Run("stream", func(t *testing.T) {
+		assert := assert.New(t)
+		page := lcdpage.NewTerminal("term", []string{"sh", "-c", "printf 'one\ntwo\n'; echo err >&2"}, "", 0)
+		assert.Equal("term", page.Name())
+
+		stop := make(chan struct{})
+		defer close(stop)
+		assert.Equal(lcdpage.RenderTerminal([]string{"one", "two", "err", "", ""}), lastImage(t, page.Play(stop)))
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		assert.NoError(os.WriteFile(filepath.Join(dir, "marker"), nil, 0o600))
+		page := lcdpage.NewTerminal("term", []string{"ls"}, dir, 0)
+		stop := make(chan struct{})
+		defer close(stop)
+		assert.Equal(lcdpage.RenderTerminal([]string{"marker", "", "", "", ""}), lastImage(t, page.Play(stop)))
+	})
+
+	t.Run("repeat", func(t *testing.T) {
+		assert := assert.New(t)
+		count := filepath.Join(t.TempDir(), "count")
+		page := lcdpage.NewTerminal("term", []string{"sh", "-c", "echo x >> " + count + "; wc -l < " + count}, "", time.Millisecond)
+
+		// each run replaces the output of the one before
+		stop := make(chan struct{})
+		images := page.Play(stop)
+		assert.Equal(lcdpage.RenderTerminal([]string{"1", "", "", "", ""}), <-images)
+		assert.Equal(lcdpage.RenderTerminal([]string{"2", "", "", "", ""}), <-images)
+		close(stop)
+		for range images {
+		}
+	})
+
+	t.Run("repeat-long-output", func(t *testing.T) {
+		assert := assert.New(t)
+		page := lcdpage.NewTerminal("term", []string{"seq", "100000"}, "", time.Hour)
+
+		// only the last lines of the output are shown
+		stop := make(chan struct{})
+		images := page.Play(stop)
+		assert.Equal(lcdpage.RenderTerminal([]string{"99997", "99998", "99999", "100000", ""}), <-images)
+		close(stop)
+		for range images {
+		}
+	})
+
+	t.Run("repeat-error", func(t *testing.T) {
+		assert := assert.New(t)
+		page := lcdpage.NewTerminal("term", []string{"false"}, "", time.Hour)
+		stop := make(chan struct{})
+		images := page.Play(stop)
+		assert.Equal(lcdpage.RenderTerminal([]string{"exit status 1", "", "", "", ""}), <-images)
+		close(stop)
+		for range images {
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		assert := assert.New(t)
+		page := lcdpage.NewTerminal("term", []string{"sh", "-c", "echo started; sleep 60 & sleep 60"}, "", 0)
+		stop := make(chan struct{})
+		images := page.Play(stop)
+		<-images
+		assert.Equal(lcdpage.RenderTerminal([]string{"started", "", "", "", ""}), <-images)
+
+		// the command and the processes it started are killed
+		close(stop)
+		assert.Nil(lastImage(t, images))
+	})
+
+	t.Run("missing-command", func(t *testing.T) {
+		assert := assert.New(t)
+		page := lcdpage.NewTerminal("term", []string{"/nonexistent/command"}, "", 0)
+		stop := make(chan struct{})
+		defer close(stop)
+		assert.NotEqual(lcdpage.RenderTerminal(nil), lastImage(t, page.Play(stop)))
+	})
+}
@@ -0,0 +1,451 @@
+package lcdpage
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/achilleas-k/gg13/internal/mpris"
+	"github.com/achilleas-k/gg13/internal/sysmon"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultMonitorInterval is how often a monitor page is updated when no
+// interval is configured.
+const DefaultMonitorInterval = time.Second
+
+// Height of the rows that widgets without an area are stacked in. Five rows
+// fit on the LCD.
+const WidgetRowHeight = 8
+
+// Space between the label of a widget and its graph.
+const widgetGap = 2
+
+// WidgetType is the statistic a widget shows.
+type WidgetType int
+
+const (
+	WidgetClock WidgetType = iota
+	WidgetCPU
+	WidgetMemory
+	WidgetLoad
+	WidgetNetwork
+	WidgetDisk
+	WidgetTemperature
+	WidgetMedia
+)
+
+var widgetTypeNames = map[WidgetType]string{
+	WidgetClock:       "clock",
+	WidgetCPU:         "cpu",
+	WidgetMemory:      "memory",
+	WidgetLoad:        "load",
+	WidgetNetwork:     "network",
+	WidgetDisk:        "disk",
+	WidgetTemperature: "temperature",
+	WidgetMedia:       "media",
+}
+
+// Labels drawn before the values of widgets without a configured label.
+var widgetLabels = map[WidgetType]string{
+	WidgetCPU:         "CPU",
+	WidgetMemory:      "MEM",
+	WidgetLoad:        "LOAD",
+	WidgetNetwork:     "NET",
+	WidgetDisk:        "DISK",
+	WidgetTemperature: "TEMP",
+}
+
+func (t WidgetType) String() string {
+	if name, ok := widgetTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("WidgetType(%d)", int(t))
+}
+
+// ParseWidgetType returns the widget type with the given name.
+func ParseWidgetType(name string) (WidgetType, error) {
+	for wt, wtName := range widgetTypeNames {
+		if wtName == name {
+			return wt, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown widget type: %q", name)
+}
+
+// Widget is a single statistic shown on a monitor page.
+type Widget struct {
+	Type WidgetType
+
+	// Area of the LCD the widget is drawn in. Widgets with an empty area are
+	// stacked in rows of [WidgetRowHeight], centred vertically.
+	Area image.Rectangle
+
+	// Label drawn before the value (empty for the default of the type).
+	// Clock and media widgets have no label by default.
+	Label string
+
+	// Network interface, block device, or temperature sensor ("chip" or
+	// "chip/label") to show. Empty shows all interfaces except loopback, all
+	// whole disks, or the first sensor.
+	Source string
+
+	// Time format of clock widgets, as a [time.Time.Format] layout (empty for
+	// [DefaultTimeFormat]).
+	Format string
+}
+
+func (w Widget) label() string {
+	if w.Label != "" {
+		return w.Label
+	}
+	return widgetLabels[w.Type]
+}
+
+// Monitor is a page of widgets showing system statistics, read from /proc and
+// /sys, and the track played by a media player, that is updated at a fixed
+// interval.
+type Monitor struct {
+	name     string
+	widgets  []Widget
+	interval time.Duration
+	media    *mpris.Client
+
+	// the sampler keeps counters between samples and may be used by a
+	// playback goroutine that is still stopping when playback restarts
+	mutex   sync.Mutex
+	sampler *sysmon.Sampler
+
+	// number of playback goroutines running, so the connection of the media
+	// client is closed when the last one stops
+	playing int
+}
+
+// NewMonitor returns a monitor page that reads statistics from the /proc and
+// /sys trees under root (empty for "/"), and the track played by the player
+// of a media client (nil if there are no media widgets). A zero interval uses
+// [DefaultMonitorInterval].
+func NewMonitor(name string, widgets []Widget, interval time.Duration, root string, media *mpris.Client) *Monitor {
+	if interval <= 0 {
+		interval = DefaultMonitorInterval
+	}
+	widgets = append([]Widget(nil), widgets...)
+	var stacked []int
+	for idx, widget := range widgets {
+		if widget.Area.Empty() {
+			stacked = append(stacked, idx)
+		}
+	}
+	top := max(0, (device.LCDHeight-len(stacked)*WidgetRowHeight)/2)
+	for row, idx := range stacked {
+		y := top + row*WidgetRowHeight
+		widgets[idx].Area = image.Rect(0, y, device.LCDWidth, y+WidgetRowHeight)
+	}
+
+	return &Monitor{
+		name:     name,
+		widgets:  widgets,
+		interval: interval,
+		media:    media,
+		sampler:  sysmon.NewSampler(root),
+	}
+}
+
+func (m *Monitor) Name() string {
+	return m.name
+}
+
+// sample reads the statistics. Statistics that can't be read are shown as
+// unavailable.
+func (m *Monitor) sample(now time.Time) sysmon.Sample {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	sample, _ := m.sampler.Sample(now)
+	return sample
+}
+
+// mediaStatus reads the track being played. It returns nil if there is no
+// media client or no player.
+func (m *Monitor) mediaStatus() *mpris.Status {
+	if m.media == nil {
+		return nil
+	}
+	status, err := m.media.Status()
+	if err != nil {
+		return nil
+	}
+	return &status
+}
+
+func (m *Monitor) Play(stop <-chan struct{}) <-chan image.Image {
+	images := make(chan image.Image)
+	m.mutex.Lock()
+	m.playing++
+	m.mutex.Unlock()
+	go func() {
+		defer close(images)
+		defer m.stopped()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case images <- m.Render(m.sample(time.Now()), m.mediaStatus()):
+			case <-stop:
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return images
+}
+
+// stopped closes the connection of the media client, if any, when the last
+// playback goroutine stops.
+func (m *Monitor) stopped() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.playing--
+	if m.playing == 0 && m.media != nil {
+		_ = m.media.Close()
+	}
+}
+
+// Render draws the widgets for a sample of the statistics and the track being
+// played (nil if none is).
+func (m *Monitor) Render(sample sysmon.Sample, media *mpris.Status) image.Image {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, widget := range m.widgets {
+		dst := img.SubImage(widget.Area.Intersect(img.Bounds())).(*image.Gray)
+		if dst.Bounds().Empty() {
+			continue
+		}
+		if widget.Type == WidgetMedia {
+			renderMedia(dst, widget, media)
+			continue
+		}
+		renderWidget(dst, widget, sample)
+	}
+	return img
+}
+
+func renderWidget(dst *image.Gray, widget Widget, sample sysmon.Sample) {
+	label := widget.label()
+	unavailable := func() {
+		drawWidgetText(dst, label+" n/a")
+	}
+
+	switch widget.Type {
+	case WidgetClock:
+		format := widget.Format
+		if format == "" {
+			format = DefaultTimeFormat
+		}
+		text := sample.Time.Format(format)
+		if widget.Label != "" {
+			text = widget.Label + " " + text
+		}
+		drawWidgetText(dst, text)
+	case WidgetCPU:
+		if len(sample.CPU) == 0 {
+			unavailable()
+			return
+		}
+		x := drawWidgetText(dst, fmt.Sprintf("%s %3.0f%%", label, sample.CPU[0]*100))
+		graph := dst.Bounds()
+		graph.Min.X = x + widgetGap
+		cores := sample.CPU[1:]
+		if len(cores) == 0 || graph.Dx() < 2*len(cores) {
+			// not enough room for a bar per core
+			cores = sample.CPU[:1]
+		}
+		drawBars(dst, graph, cores)
+	case WidgetMemory:
+		if sample.Memory == nil {
+			unavailable()
+			return
+		}
+		mem := sample.Memory
+		x := drawWidgetText(dst, fmt.Sprintf("%s %s/%s", label, FormatBytes(float64(mem.Used())), FormatBytes(float64(mem.Total))))
+		graph := dst.Bounds()
+		graph.Min.X = x + widgetGap
+		var used float64
+		if mem.Total > 0 {
+			used = float64(mem.Used()) / float64(mem.Total)
+		}
+		drawMeter(dst, graph, used)
+	case WidgetLoad:
+		if sample.Load == nil {
+			unavailable()
+			return
+		}
+		drawWidgetText(dst, fmt.Sprintf("%s %.2f %.2f %.2f", label, sample.Load[0], sample.Load[1], sample.Load[2]))
+	case WidgetNetwork:
+		rate, ok := sample.NetworkRate(widget.Source)
+		if !ok {
+			unavailable()
+			return
+		}
+		drawWidgetText(dst, fmt.Sprintf("%s v%s ^%s", label, FormatBytes(rate.In), FormatBytes(rate.Out)))
+	case WidgetDisk:
+		rate, ok := sample.DiskRate(widget.Source)
+		if !ok {
+			unavailable()
+			return
+		}
+		drawWidgetText(dst, fmt.Sprintf("%s R%s W%s", label, FormatBytes(rate.In), FormatBytes(rate.Out)))
+	case WidgetTemperature:
+		temp, ok := sample.Temperature(widget.Source)
+		if !ok {
+			unavailable()
+			return
+		}
+		drawWidgetText(dst, fmt.Sprintf("%s %.0fC", label, temp.Celsius))
+	}
+}
+
+// renderMedia draws the title and artist of a track with a bar showing the
+// progress through it. Areas at least three rows high get a row each for the
+// title, the artist, and the progress with the position and length of the
+// track; smaller areas fit everything on a single row.
+func renderMedia(dst *image.Gray, widget Widget, media *mpris.Status) {
+	prefix := ""
+	if widget.Label != "" {
+		prefix = widget.Label + " "
+	}
+	if media == nil {
+		drawWidgetText(dst, prefix+"no media")
+		return
+	}
+	state := "||"
+	if media.Playing {
+		state = ">"
+	}
+
+	area := dst.Bounds()
+	if area.Dy() < 3*WidgetRowHeight {
+		text := prefix + state + " " + media.Title
+		if artist := media.Artist(); artist != "" {
+			text += " - " + artist
+		}
+		textArea := area
+		if media.Length > 0 {
+			meter := area
+			meter.Min.X = max(area.Min.X, area.Max.X-mediaMeterWidth)
+			drawMeter(dst, meter, media.Progress())
+			textArea.Max.X = meter.Min.X - widgetGap
+		}
+		drawWidgetText(dst.SubImage(textArea).(*image.Gray), text)
+		return
+	}
+
+	row := func(idx int) *image.Gray {
+		y := area.Min.Y + idx*WidgetRowHeight
+		return dst.SubImage(image.Rect(area.Min.X, y, area.Max.X, y+WidgetRowHeight)).(*image.Gray)
+	}
+	drawWidgetText(row(0), prefix+state+" "+media.Title)
+	drawWidgetText(row(1), media.Artist())
+	progress := row(2)
+	x := drawWidgetText(progress, formatTrackTime(media.Position)+"/"+formatTrackTime(media.Length))
+	meter := progress.Bounds()
+	meter.Min.X = x + widgetGap
+	drawMeter(progress, meter, media.Progress())
+}
+
+// Width of the progress bar of media widgets on a single row.
+const mediaMeterWidth = 30
+
+// formatTrackTime formats a position in a track as minutes and seconds, or
+// hours, minutes, and seconds for long tracks.
+func formatTrackTime(d time.Duration) string {
+	seconds := int(max(0, d) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// drawWidgetText draws a line of text with the built-in font at the left of
+// the area of a widget, centred vertically, and returns the x coordinate of
+// its end.
+func drawWidgetText(dst *image.Gray, text string) int {
+	area := dst.Bounds()
+	face := lcdtext.Builtin
+	metrics := face.Metrics()
+	baseline := area.Min.Y + max(0, (area.Dy()-metrics.Height.Ceil())/2) + metrics.Ascent.Ceil()
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(area.Min.X, baseline),
+	}
+	drawer.DrawString(text)
+	return drawer.Dot.X.Ceil()
+}
+
+// drawBars draws a vertical bar for each value, from 0 to 1, filling the area
+// from the bottom.
+func drawBars(dst *image.Gray, area image.Rectangle, values []float64) {
+	if area.Dx() < len(values) || area.Dy() < 1 {
+		return
+	}
+	width := max(1, (area.Dx()+1)/len(values)-1)
+	for idx, value := range values {
+		x := area.Min.X + idx*(width+1)
+		height := max(1, int(clamp01(value)*float64(area.Dy())+0.5))
+		draw.Draw(dst, image.Rect(x, area.Max.Y-height, x+width, area.Max.Y), image.Black, image.Point{}, draw.Src)
+	}
+}
+
+// drawMeter draws an outlined horizontal bar, filled from the left by a value
+// from 0 to 1.
+func drawMeter(dst *image.Gray, area image.Rectangle, value float64) {
+	if area.Dx() < 3 || area.Dy() < 3 {
+		return
+	}
+	// leave a pixel above and below, the same as the text
+	if area.Dy() > 4 {
+		area.Min.Y++
+		area.Max.Y--
+	}
+	black := image.Black
+	draw.Draw(dst, area, black, image.Point{}, draw.Src)
+	inner := area.Inset(1)
+	draw.Draw(dst, inner, image.White, image.Point{}, draw.Src)
+	filled := inner
+	filled.Max.X = inner.Min.X + int(clamp01(value)*float64(inner.Dx())+0.5)
+	draw.Draw(dst, filled, black, image.Point{}, draw.Src)
+}
+
+func clamp01(v float64) float64 {
+	return min(1, max(0, v))
+}
+
+// FormatBytes formats an amount of bytes compactly, with binary unit prefixes
+// and at most one decimal: for example 512, 1.5K, 24M, 3.2G.
+func FormatBytes(value float64) string {
+	const units = "KMGTPE"
+	if value < 1024 {
+		return fmt.Sprintf("%.0f", max(0, value))
+	}
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	text := fmt.Sprintf("%.0f", value)
+	if value < 10 {
+		text = strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0")
+	}
+	return text + units[unit:unit+1]
+}
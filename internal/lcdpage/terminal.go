@@ -0,0 +1,230 @@
+package lcdpage
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/achilleas-k/gg13/internal/vt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Size of the screen of terminal pages, in characters of the built-in font.
+var (
+	TerminalColumns = device.LCDWidth / lcdtext.Builtin.Advance
+	TerminalRows    = device.LCDHeight / lcdtext.Builtin.Height
+)
+
+// How long a stopped command has to exit after it's killed, before its output
+// is abandoned.
+const terminalWaitDelay = time.Second
+
+// Terminal is a page showing the last lines of the output of a command, which
+// is either run repeatedly or kept running.
+type Terminal struct {
+	name     string
+	command  []string
+	dir      string
+	interval time.Duration
+}
+
+// NewTerminal returns a terminal page that runs a command in a directory
+// (empty for the current directory). With an interval, the command is run
+// again that long after each run finishes, and the screen shows the output of
+// the last run. Without one, the command is run once and its output is shown
+// as it's written. The command is killed, with any processes it started, when
+// playback stops.
+func NewTerminal(name string, command []string, dir string, interval time.Duration) *Terminal {
+	return &Terminal{
+		name:     name,
+		command:  append([]string(nil), command...),
+		dir:      dir,
+		interval: interval,
+	}
+}
+
+func (t *Terminal) Name() string {
+	return t.name
+}
+
+// newCmd returns the command to run, in a process group of its own that is
+// killed when the context is done.
+func (t *Terminal) newCmd(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, t.command[0], t.command[1:]...)
+	cmd.Dir = t.dir
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = terminalWaitDelay
+	return cmd
+}
+
+func (t *Terminal) Play(stop <-chan struct{}) <-chan image.Image {
+	images := make(chan image.Image)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		defer close(images)
+		defer cancel()
+		if t.interval > 0 {
+			t.repeat(ctx, images)
+		} else {
+			t.stream(ctx, images)
+		}
+	}()
+	return images
+}
+
+// sendScreen sends an image of the screen. It returns false if playback
+// stopped.
+func sendScreen(ctx context.Context, images chan<- image.Image, screen *vt.Screen) bool {
+	select {
+	case images <- RenderTerminal(screen.Lines()):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// repeat runs the command after each interval until playback stops.
+func (t *Terminal) repeat(ctx context.Context, images chan<- image.Image) {
+	for {
+		// the output goes straight to the screen, which keeps only the
+		// lines it shows, so commands with a lot of output don't use up
+		// memory
+		screen := vt.New(TerminalColumns, TerminalRows)
+		output := &countingWriter{w: screen}
+		cmd := t.newCmd(ctx)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && output.n == 0 {
+			fmt.Fprintf(screen, "%s", err)
+		}
+		if !sendScreen(ctx, images, screen) {
+			return
+		}
+
+		timer := time.NewTimer(t.interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// stream runs the command once and shows its output as it's written.
+func (t *Terminal) stream(ctx context.Context, images chan<- image.Image) {
+	screen := vt.New(TerminalColumns, TerminalRows)
+	if !sendScreen(ctx, images, screen) {
+		return
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintf(screen, "%s", err)
+		sendScreen(ctx, images, screen)
+		return
+	}
+	defer reader.Close()
+	cmd := t.newCmd(ctx)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	err = cmd.Start()
+	writer.Close()
+	if err != nil {
+		fmt.Fprintf(screen, "%s", err)
+		sendScreen(ctx, images, screen)
+		return
+	}
+	defer func() {
+		_ = cmd.Wait()
+	}()
+
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 4096)
+		for {
+			n, err := reader.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- append([]byte(nil), buf[:n]...):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				// the command exited; its output stays on the screen
+				return
+			}
+			_, _ = screen.Write(chunk)
+			if !sendScreen(ctx, images, screen) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RenderTerminal draws lines of a terminal screen with the built-in font,
+// from the top left of the LCD.
+func RenderTerminal(lines []string) image.Image {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	face := lcdtext.Builtin
+	top := (device.LCDHeight - TerminalRows*face.Height) / 2
+	for idx, line := range lines {
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  image.Black,
+			Face: face,
+			Dot:  fixed.P(0, top+idx*face.Height+face.Ascent),
+		}
+		drawer.DrawString(line)
+	}
+	return img
+}
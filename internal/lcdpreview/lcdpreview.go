@@ -0,0 +1,151 @@
+// Package lcdpreview draws images of the G13 LCD for viewing without the
+// device: as text for terminals and as PNG images.
+package lcdpreview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Style is the set of characters that pixels are drawn with in a terminal.
+type Style int
+
+const (
+	// StyleHalfBlock draws two pixels, one above the other, per character
+	// with the block elements ▀, ▄, and █.
+	StyleHalfBlock Style = iota
+
+	// StyleBraille draws 2x4 pixels per character with braille patterns,
+	// which is more compact but depends on the font.
+	StyleBraille
+)
+
+var styleNames = map[Style]string{
+	StyleHalfBlock: "halfblock",
+	StyleBraille:   "braille",
+}
+
+func (s Style) String() string {
+	if name, ok := styleNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Style(%d)", int(s))
+}
+
+// ParseStyle returns the style with the given name. An empty name returns
+// [StyleHalfBlock].
+func ParseStyle(name string) (Style, error) {
+	if name == "" {
+		return StyleHalfBlock, nil
+	}
+	for style, styleName := range styleNames {
+		if styleName == name {
+			return style, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown preview style: %s", name)
+}
+
+// on returns true if the pixel at x, y is turned on (dark). Pixels outside the
+// image are off.
+func on(img image.Image, x, y int) bool {
+	if !(image.Point{X: x, Y: y}.In(img.Bounds())) {
+		return false
+	}
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 0x80
+}
+
+// Bits of the braille pattern characters for each dot, by row and column.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Text draws an image as lines of text, with the pixels that are turned on
+// drawn as filled characters, inside a frame showing the edges of the image.
+func Text(img image.Image, style Style) string {
+	bounds := img.Bounds()
+	cellWidth, cellHeight := 1, 2
+	if style == StyleBraille {
+		cellWidth, cellHeight = 2, 4
+	}
+	cols := (bounds.Dx() + cellWidth - 1) / cellWidth
+
+	var sb strings.Builder
+	sb.WriteString("┌" + strings.Repeat("─", cols) + "┐\n")
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += cellHeight {
+		sb.WriteString("│")
+		for x := bounds.Min.X; x < bounds.Max.X; x += cellWidth {
+			if style == StyleBraille {
+				sb.WriteRune(brailleCell(img, x, y))
+			} else {
+				sb.WriteString(halfBlockCell(img, x, y))
+			}
+		}
+		sb.WriteString("│\n")
+	}
+	sb.WriteString("└" + strings.Repeat("─", cols) + "┘\n")
+	return sb.String()
+}
+
+func halfBlockCell(img image.Image, x, y int) string {
+	upper, lower := on(img, x, y), on(img, x, y+1)
+	switch {
+	case upper && lower:
+		return "█"
+	case upper:
+		return "▀"
+	case lower:
+		return "▄"
+	default:
+		return " "
+	}
+}
+
+func brailleCell(img image.Image, x, y int) rune {
+	cell := rune(0x2800)
+	for row := range brailleDots {
+		for col := range brailleDots[row] {
+			if on(img, x+col, y+row) {
+				cell |= brailleDots[row][col]
+			}
+		}
+	}
+	return cell
+}
+
+// MaxScale is the largest scale of images written by [WritePNG], which makes
+// an LCD image 5120x1376 pixels.
+const MaxScale = 32
+
+// CheckScale returns an error if a scale can't be used by [WritePNG].
+func CheckScale(scale int) error {
+	if scale < 1 || scale > MaxScale {
+		return fmt.Errorf("invalid scale %d: must be between 1 and %d", scale, MaxScale)
+	}
+	return nil
+}
+
+// WritePNG writes an image as a black and white PNG, with each pixel scaled up
+// to a square of scale pixels.
+func WritePNG(w io.Writer, img image.Image, scale int) error {
+	if err := CheckScale(scale); err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+	out := image.NewGray(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	for y := range out.Bounds().Dy() {
+		for x := range out.Bounds().Dx() {
+			if !on(img, bounds.Min.X+x/scale, bounds.Min.Y+y/scale) {
+				out.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return png.Encode(w, out)
+}
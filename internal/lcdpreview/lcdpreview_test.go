@@ -0,0 +1,85 @@
+package lcdpreview_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/lcdpreview"
+	"github.com/stretchr/testify/assert"
+)
+
+// testImage returns a 3x5 image with the given pixels turned on (black).
+func testImage(pixels ...image.Point) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 3, 5))
+	for idx := range img.Pix {
+		img.Pix[idx] = 0xff
+	}
+	for _, p := range pixels {
+		img.SetGray(p.X, p.Y, color.Gray{})
+	}
+	return img
+}
+
+func TestHalfBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	img := testImage(image.Pt(0, 0), image.Pt(0, 1), image.Pt(1, 0), image.Pt(2, 3), image.Pt(1, 4))
+	expected := "" +
+		"┌───┐\n" +
+		"│█▀ │\n" +
+		"│  ▄│\n" +
+		"│ ▀ │\n" +
+		"└───┘\n"
+	assert.Equal(expected, lcdpreview.Text(img, lcdpreview.StyleHalfBlock))
+}
+
+func TestBraille(t *testing.T) {
+	assert := assert.New(t)
+
+	img := testImage(image.Pt(0, 0), image.Pt(1, 3), image.Pt(2, 4))
+	expected := "" +
+		"┌──┐\n" +
+		"│⢁⠀│\n" +
+		"│⠀⠁│\n" +
+		"└──┘\n"
+	assert.Equal(expected, lcdpreview.Text(img, lcdpreview.StyleBraille))
+}
+
+func TestParseStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	style, err := lcdpreview.ParseStyle("")
+	assert.NoError(err)
+	assert.Equal(lcdpreview.StyleHalfBlock, style)
+	style, err = lcdpreview.ParseStyle("braille")
+	assert.NoError(err)
+	assert.Equal(lcdpreview.StyleBraille, style)
+	assert.Equal("braille", style.String())
+	_, err = lcdpreview.ParseStyle("ascii")
+	assert.EqualError(err, "unknown preview style: ascii")
+}
+
+func TestWritePNG(t *testing.T) {
+	assert := assert.New(t)
+
+	img := testImage(image.Pt(1, 2))
+	var buf bytes.Buffer
+	assert.NoError(lcdpreview.WritePNG(&buf, img, 2))
+
+	decoded, err := png.Decode(&buf)
+	assert.NoError(err)
+	assert.Equal(image.Rect(0, 0, 6, 10), decoded.Bounds())
+	black := color.GrayModel.Convert(color.Black)
+	for y := range 10 {
+		for x := range 6 {
+			isBlack := color.GrayModel.Convert(decoded.At(x, y)) == black
+			assert.Equal(x/2 == 1 && y/2 == 2, isBlack, "pixel %d,%d", x, y)
+		}
+	}
+
+	assert.EqualError(lcdpreview.WritePNG(&buf, img, 0), "invalid scale 0: must be between 1 and 32")
+	assert.EqualError(lcdpreview.WritePNG(&buf, img, 1<<40), "invalid scale 1099511627776: must be between 1 and 32")
+}
@@ -0,0 +1,386 @@
+package lcdsocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdsocket"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, data string) ([]lcdsocket.Message, error) {
+	t.Helper()
+	r := bufio.NewReader(strings.NewReader(data))
+	var msgs []lcdsocket.Message
+	for {
+		msg, err := lcdsocket.ReadMessage(r)
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestReadText(t *testing.T) {
+	assert := assert.New(t)
+
+	msgs, err := readAll(t, "text\nHello\nWorld\n\n\ntext priority=2 timeout=1500 align=center inverse=true\nBoss fight!")
+	assert.NoError(err)
+	assert.Len(msgs, 2)
+
+	assert.Equal(lcdtext.Render([]string{"Hello", "World"}, lcdtext.Style{}), msgs[0].Image)
+	assert.Equal(0, msgs[0].Priority)
+	assert.Equal(lcdsocket.DefaultTimeout, msgs[0].Timeout)
+
+	assert.Equal(lcdtext.Render([]string{"Boss fight!"}, lcdtext.Style{Align: lcdtext.AlignCenter, Inverse: true}), msgs[1].Image)
+	assert.Equal(2, msgs[1].Priority)
+	assert.Equal(1500*time.Millisecond, msgs[1].Timeout)
+}
+
+func TestReadImage(t *testing.T) {
+	assert := assert.New(t)
+
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for idx := range img.Pix {
+		img.Pix[idx] = 0xff
+	}
+	img.SetGray(1, 1, color.Gray{})
+	var buf bytes.Buffer
+	assert.NoError(png.Encode(&buf, img))
+
+	data := fmt.Sprintf("image size=%d scale=center priority=-1\n%s", buf.Len(), buf.String())
+	msgs, err := readAll(t, data+"clear\n")
+	assert.NoError(err)
+	assert.Len(msgs, 2)
+
+	shown := msgs[0].Image
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), shown.Bounds())
+	assert.Equal(-1, msgs[0].Priority)
+	// centred without scaling
+	x, y := (device.LCDWidth-4)/2+1, (device.LCDHeight-2)/2+1
+	assert.Equal(color.Gray{}, color.GrayModel.Convert(shown.At(x, y)))
+	assert.Equal(color.Gray{Y: 0xff}, color.GrayModel.Convert(shown.At(x-1, y)))
+
+	assert.True(msgs[1].ClearAll)
+}
+
+func TestReadRaw(t *testing.T) {
+	assert := assert.New(t)
+
+	bitmap := make([]byte, lcdsocket.RawSize)
+	// top left and bottom right pixels
+	bitmap[0] = 0x80
+	bitmap[len(bitmap)-1] = 0x01
+
+	msgs, err := readAll(t, "raw timeout=100\n"+string(bitmap)+"clear priority=3\n")
+	assert.NoError(err)
+	assert.Len(msgs, 2)
+
+	img := msgs[0].Image
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+	black := 0
+	for y := range device.LCDHeight {
+		for x := range device.LCDWidth {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y == 0 {
+				black++
+			}
+		}
+	}
+	assert.Equal(2, black)
+	assert.Equal(color.Gray{}, color.GrayModel.Convert(img.At(0, 0)))
+	assert.Equal(color.Gray{}, color.GrayModel.Convert(img.At(device.LCDWidth-1, device.LCDHeight-1)))
+	assert.Equal(100*time.Millisecond, msgs[0].Timeout)
+
+	assert.Nil(msgs[1].Image)
+	assert.False(msgs[1].ClearAll)
+	assert.Equal(3, msgs[1].Priority)
+}
+
+func TestReadMetric(t *testing.T) {
+	assert := assert.New(t)
+
+	msgs, err := readAll(t, "metric value=73.5\nmetric value=-2\n")
+	assert.NoError(err)
+	assert.Len(msgs, 2)
+	assert.Equal(73.5, *msgs[0].Metric)
+	assert.Nil(msgs[0].Image)
+	assert.Equal(-2.0, *msgs[1].Metric)
+
+	// metrics don't clear the LCD
+	var queue lcdsocket.Queue
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	img := testImage(1)
+	queue.Handle(lcdsocket.Message{Image: img, Timeout: time.Second}, now)
+	queue.Handle(msgs[0], now)
+	assert.Equal(img, queue.Current())
+}
+
+func TestReadErrors(t *testing.T) {
+	type testCase struct {
+		data        string
+		expectedErr string
+	}
+
+	testCases := map[string]testCase{
+		"unknown-command": {
+			data:        "draw\n",
+			expectedErr: `unknown command: "draw"`,
+		},
+		"bad-option": {
+			data:        "text priority\nhi\n",
+			expectedErr: `invalid option "priority": must be key=value`,
+		},
+		"bad-priority": {
+			data:        "text priority=high\nhi\n",
+			expectedErr: `invalid priority "high": must be an integer`,
+		},
+		"negative-timeout": {
+			data:        "text timeout=-5\nhi\n",
+			expectedErr: "invalid timeout -5: must not be negative",
+		},
+		"unknown-option": {
+			data:        "raw align=center\n",
+			expectedErr: `unknown option for raw: "align"`,
+		},
+		"huge-timeout": {
+			data:        "text timeout=9223372036854775807\nhi\n",
+			expectedErr: "invalid timeout 9223372036854775807: must be at most 86400000",
+		},
+		"long-header": {
+			data:        "text " + strings.Repeat("x", 5000) + "\nhi\n",
+			expectedErr: "line too long: must be at most 4096 bytes",
+		},
+		"long-line": {
+			data:        "text\n" + strings.Repeat("x", 5000) + "\n",
+			expectedErr: "line too long: must be at most 4096 bytes",
+		},
+		"too-many-lines": {
+			data:        "text\n" + strings.Repeat("hi\n", 65),
+			expectedErr: "too many lines: must be at most 64",
+		},
+		"clear-timeout": {
+			data:        "clear timeout=10\n",
+			expectedErr: `unknown option for clear: "timeout"`,
+		},
+		"bad-align": {
+			data:        "text align=top\nhi\n",
+			expectedErr: "unknown text alignment: top",
+		},
+		"no-image-size": {
+			data:        "image\n",
+			expectedErr: "invalid or missing image size: must be between 0 and 4194304",
+		},
+		"short-image": {
+			data:        "image size=100\nabc",
+			expectedErr: "failed reading image: unexpected EOF",
+		},
+		"bad-image": {
+			data:        "image size=3\nabc",
+			expectedErr: "failed decoding image: image: unknown format",
+		},
+		"huge-image": {
+			data:        "image size=25\nP5 16777216 16777216 255 ",
+			expectedErr: "failed decoding image: netpbm: image size 16777216x16777216 too large",
+		},
+		"large-image": {
+			data:        "image size=17\nP5 1000 1000 255 ",
+			expectedErr: "image size 1000x1000 too large: must be at most 110080 pixels",
+		},
+		"no-metric-value": {
+			data:        "metric\n",
+			expectedErr: "missing metric value",
+		},
+		"bad-metric-value": {
+			data:        "metric value=high\n",
+			expectedErr: `invalid value "high": must be a number`,
+		},
+		"metric-timeout": {
+			data:        "metric value=1 timeout=10\n",
+			expectedErr: "priority and timeout not supported for metric",
+		},
+		"short-raw": {
+			data:        "raw\nabc",
+			expectedErr: "failed reading raw bitmap: unexpected EOF",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := readAll(t, tc.data)
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func testImage(value uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.Pix[0] = value
+	return img
+}
+
+func TestQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	var queue lcdsocket.Queue
+	now := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	assert.Nil(queue.Current())
+	assert.False(queue.Expire(now))
+
+	low, high, newer := testImage(1), testImage(2), testImage(3)
+	queue.Handle(lcdsocket.Message{Image: low, Priority: 0, Timeout: 10 * time.Second}, now)
+	queue.Handle(lcdsocket.Message{Image: high, Priority: 5, Timeout: 2 * time.Second}, now)
+	queue.Handle(lcdsocket.Message{Image: newer, Priority: 0, Timeout: 5 * time.Second}, now)
+	assert.Equal(high, queue.Current())
+
+	// the high priority content expires, leaving the newest low priority one
+	assert.False(queue.Expire(now.Add(time.Second)))
+	assert.True(queue.Expire(now.Add(2 * time.Second)))
+	assert.Equal(newer, queue.Current())
+	assert.True(queue.Expire(now.Add(5 * time.Second)))
+	assert.Equal(low, queue.Current())
+
+	// the oldest content is dropped when the queue is full
+	var full lcdsocket.Queue
+	first := testImage(4)
+	full.Handle(lcdsocket.Message{Image: first, Priority: 9, Timeout: time.Hour}, now)
+	for range 32 {
+		full.Handle(lcdsocket.Message{Image: low, Timeout: time.Hour}, now)
+	}
+	assert.Equal(low, full.Current())
+
+	// clearing by priority
+	queue.Handle(lcdsocket.Message{Image: high, Priority: 5, Timeout: time.Second}, now)
+	queue.Handle(lcdsocket.Message{Priority: 5}, now)
+	assert.Equal(low, queue.Current())
+
+	// clearing everything
+	queue.Handle(lcdsocket.Message{Image: high, Priority: 5, Timeout: time.Second}, now)
+	queue.Handle(lcdsocket.Message{ClearAll: true}, now)
+	assert.Nil(queue.Current())
+}
+
+func receive(t *testing.T, server *lcdsocket.Server) lcdsocket.Result {
+	t.Helper()
+	select {
+	case res := <-server.Results():
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return lcdsocket.Result{}
+	}
+}
+
+func TestSocket(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "lcd.sock")
+	server := lcdsocket.NewServer()
+	assert.NoError(server.ListenSocket(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// a second server can't take over a socket in use
+	assert.EqualError(lcdsocket.NewServer().ListenSocket(path), fmt.Sprintf("socket %q is in use", path))
+
+	conn, err := net.Dial("unix", path)
+	assert.NoError(err)
+	defer conn.Close()
+	replies := bufio.NewReader(conn)
+
+	_, err = io.WriteString(conn, "text priority=1\nHello\n\n")
+	assert.NoError(err)
+	res := receive(t, server)
+	assert.NoError(res.Err)
+	assert.Equal(1, res.Message.Priority)
+	reply, err := replies.ReadString('\n')
+	assert.NoError(err)
+	assert.Equal("ok\n", reply)
+
+	_, err = io.WriteString(conn, "flash\n")
+	assert.NoError(err)
+	res = receive(t, server)
+	assert.EqualError(res.Err, `unknown command: "flash"`)
+	reply, err = replies.ReadString('\n')
+	assert.NoError(err)
+	assert.Equal("error: unknown command: \"flash\"\n", reply)
+	// the connection is closed after an error
+	_, err = replies.ReadString('\n')
+	assert.ErrorIs(err, io.EOF)
+
+	assert.NoError(server.Close())
+	_, err = os.Stat(path)
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestStaleSocket(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "lcd.sock")
+	listener, err := net.Listen("unix", path)
+	assert.NoError(err)
+	// leave the socket file behind
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.NoError(listener.Close())
+
+	server := lcdsocket.NewServer()
+	assert.NoError(server.ListenSocket(path))
+	assert.NoError(server.Close())
+}
+
+func TestFIFO(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "lcd.fifo")
+	server := lcdsocket.NewServer()
+	assert.NoError(server.ListenFIFO(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(os.ModeNamedPipe, info.Mode().Type())
+
+	// one writer after the other
+	for _, data := range []string{"text\none\n\ntext priority=2\ntwo\n\n", "clear\n"} {
+		writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+		assert.NoError(err)
+		_, err = io.WriteString(writer, data)
+		assert.NoError(err)
+		assert.NoError(writer.Close())
+	}
+	res := receive(t, server)
+	assert.NoError(res.Err)
+	assert.Equal(0, res.Message.Priority)
+	res = receive(t, server)
+	assert.NoError(res.Err)
+	assert.Equal(2, res.Message.Priority)
+	res = receive(t, server)
+	assert.NoError(res.Err)
+	assert.True(res.Message.ClearAll)
+
+	// closing removes the FIFO that was created by the server
+	assert.NoError(server.Close())
+	_, err = os.Stat(path)
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// not a FIFO
+	regular := filepath.Join(t.TempDir(), "file")
+	assert.NoError(os.WriteFile(regular, nil, 0o600))
+	assert.EqualError(lcdsocket.NewServer().ListenFIFO(regular), fmt.Sprintf("%q is not a FIFO", regular))
+}
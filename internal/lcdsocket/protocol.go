@@ -0,0 +1,319 @@
+// Package lcdsocket implements a protocol through which other programs push
+// content to the G13 LCD over a UNIX socket or a named pipe (FIFO).
+//
+// Each message is a header line with a command and options, separated by
+// spaces, followed by the content of the command:
+//
+//	text [priority=N] [timeout=MS] [align=left|center|right] [inverse=true]
+//	<lines of text, ending with an empty line or the end of the stream>
+//
+//	image size=BYTES [priority=N] [timeout=MS] [scale=fit|fill|crop|center]
+//	<image file data in any format supported by lcdimage, of at most 110080 pixels>
+//
+//	raw [priority=N] [timeout=MS]
+//	<160x43 bitmap: rows of 20 bytes, most significant bit first, set bits black>
+//
+//	clear [priority=N]
+//
+//	metric value=N
+//
+// Content with a higher priority is shown over content with a lower one, and
+// newer content over older content with the same priority. Content is removed
+// after its timeout, which is [DefaultTimeout] if not given, and the clear
+// command removes all content or the content with the given priority. When no
+// content is left, the LCD shows its normal page again. Timeouts are at most a
+// day, and at most 32 pieces of content are kept, so the oldest is dropped
+// when more are pushed. Lines are at most 4096 bytes long and text is at most
+// 64 lines.
+//
+// The metric command doesn't change the LCD: it sets the value that the
+// backlight follows when its colour is driven by pushed values.
+package lcdsocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdimage"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+)
+
+// DefaultTimeout is how long content is shown when no timeout is given.
+const DefaultTimeout = 5 * time.Second
+
+// RawSize is the size, in bytes, of the content of a raw command.
+const RawSize = device.LCDWidth / 8 * device.LCDHeight
+
+// Limit of the content of an image command, to avoid buffering huge images.
+const maxImageSize = 4 << 20
+
+// Limit of the length of header and text lines, in bytes.
+const maxLineLength = 4096
+
+// Limit of the lines of a text command, which is far more than fit on the LCD.
+const maxTextLines = 64
+
+// Limit of the timeout of content, so that content can't stay forever.
+const maxTimeout = 24 * time.Hour
+
+// Limit of the pixels of a decoded image, to avoid small images that decode
+// to huge ones. It's plenty for images that are scaled down to the LCD.
+const maxImagePixels = 16 * device.LCDWidth * device.LCDHeight
+
+// Message is a single command pushed to the LCD.
+type Message struct {
+	// Image to show, or nil to clear content.
+	Image image.Image
+
+	// Priority of the content to show, or of the content to clear.
+	Priority int
+
+	// How long to show the content for.
+	Timeout time.Duration
+
+	// Clear content of all priorities.
+	ClearAll bool
+
+	// Value for the backlight metric, or nil if the message is for the LCD.
+	Metric *float64
+}
+
+// ReadMessage reads a single message. Empty lines before the header are
+// skipped. It returns [io.EOF] if the stream ends before a header.
+func ReadMessage(r *bufio.Reader) (Message, error) {
+	var header string
+	for header == "" {
+		line, err := readLine(r)
+		if err != nil && (err != io.EOF || line == "") {
+			return Message{}, err
+		}
+		header = strings.TrimSpace(line)
+	}
+
+	fields := strings.Fields(header)
+	command, options := fields[0], map[string]string{}
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return Message{}, fmt.Errorf("invalid option %q: must be key=value", field)
+		}
+		options[key] = value
+	}
+
+	_, hasPriority := options["priority"]
+	_, hasTimeout := options["timeout"]
+	msg := Message{Timeout: DefaultTimeout}
+	opts := optionReader{options: options}
+	msg.Priority = opts.int("priority", 0)
+	if timeout := opts.int("timeout", 0); timeout < 0 {
+		opts.fail(fmt.Errorf("invalid timeout %d: must not be negative", timeout))
+	} else if int64(timeout) > maxTimeout.Milliseconds() {
+		opts.fail(fmt.Errorf("invalid timeout %d: must be at most %d", timeout, maxTimeout.Milliseconds()))
+	} else if timeout > 0 {
+		msg.Timeout = time.Duration(timeout) * time.Millisecond
+	}
+
+	switch command {
+	case "text":
+		align, err := lcdtext.ParseAlign(opts.string("align"))
+		opts.fail(err)
+		inverse := opts.bool("inverse")
+		if err := opts.finish(command); err != nil {
+			return Message{}, err
+		}
+		lines, err := readLines(r)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Image = lcdtext.Render(lines, lcdtext.Style{Align: align, Inverse: inverse})
+	case "image":
+		size := opts.int("size", -1)
+		if size < 0 || size > maxImageSize {
+			opts.fail(fmt.Errorf("invalid or missing image size: must be between 0 and %d", maxImageSize))
+		}
+		scale, err := lcdimage.ParseScaleMode(opts.string("scale"))
+		opts.fail(err)
+		if err := opts.finish(command); err != nil {
+			return Message{}, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return Message{}, fmt.Errorf("failed reading image: %w", err)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return Message{}, fmt.Errorf("failed decoding image: %w", err)
+		}
+		if cfg.Width*cfg.Height > maxImagePixels {
+			return Message{}, fmt.Errorf("image size %dx%d too large: must be at most %d pixels", cfg.Width, cfg.Height, maxImagePixels)
+		}
+		img, err := lcdimage.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Message{}, fmt.Errorf("failed decoding image: %w", err)
+		}
+		msg.Image = lcdimage.DefaultConversion().Apply(lcdimage.Fit(img, scale))
+	case "raw":
+		if err := opts.finish(command); err != nil {
+			return Message{}, err
+		}
+		data := make([]byte, RawSize)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return Message{}, fmt.Errorf("failed reading raw bitmap: %w", err)
+		}
+		msg.Image = decodeRaw(data)
+	case "metric":
+		if hasPriority || hasTimeout {
+			opts.fail(fmt.Errorf("priority and timeout not supported for metric"))
+		}
+		value, ok := opts.float("value")
+		if !ok {
+			opts.fail(fmt.Errorf("missing metric value"))
+		}
+		if err := opts.finish(command); err != nil {
+			return Message{}, err
+		}
+		msg.Metric = &value
+	case "clear":
+		if hasTimeout {
+			opts.fail(fmt.Errorf("unknown option for clear: %q", "timeout"))
+		}
+		msg.ClearAll = !hasPriority
+		if err := opts.finish(command); err != nil {
+			return Message{}, err
+		}
+	default:
+		return Message{}, fmt.Errorf("unknown command: %q", command)
+	}
+	return msg, nil
+}
+
+// readLine reads a line, including the newline, of at most maxLineLength
+// bytes.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLineLength {
+			return "", fmt.Errorf("line too long: must be at most %d bytes", maxLineLength)
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return string(line), err
+		}
+	}
+}
+
+// readLines reads lines of text until an empty line or the end of the stream.
+func readLines(r *bufio.Reader) ([]string, error) {
+	var lines []string
+	for {
+		line, err := readLine(r)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return lines, nil
+		}
+		if len(lines) == maxTextLines {
+			return nil, fmt.Errorf("too many lines: must be at most %d", maxTextLines)
+		}
+		lines = append(lines, line)
+		if err == io.EOF {
+			return lines, nil
+		}
+	}
+}
+
+// decodeRaw converts a raw bitmap to an image.
+func decodeRaw(data []byte) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	stride := device.LCDWidth / 8
+	for y := range device.LCDHeight {
+		for x := range device.LCDWidth {
+			on := data[y*stride+x/8]&(0x80>>(x%8)) != 0
+			if !on {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return img
+}
+
+// optionReader reads the options of a header, collecting errors.
+type optionReader struct {
+	options map[string]string
+	errs    []error
+}
+
+func (o *optionReader) fail(err error) {
+	if err != nil {
+		o.errs = append(o.errs, err)
+	}
+}
+
+func (o *optionReader) string(key string) string {
+	value := o.options[key]
+	delete(o.options, key)
+	return value
+}
+
+func (o *optionReader) int(key string, def int) int {
+	value, ok := o.options[key]
+	if !ok {
+		return def
+	}
+	delete(o.options, key)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		o.fail(fmt.Errorf("invalid %s %q: must be an integer", key, value))
+	}
+	return n
+}
+
+// float reads a number. It returns false if the option isn't set.
+func (o *optionReader) float(key string) (float64, bool) {
+	value, ok := o.options[key]
+	if !ok {
+		return 0, false
+	}
+	delete(o.options, key)
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		o.fail(fmt.Errorf("invalid %s %q: must be a number", key, value))
+	}
+	return f, true
+}
+
+func (o *optionReader) bool(key string) bool {
+	value, ok := o.options[key]
+	if !ok {
+		return false
+	}
+	delete(o.options, key)
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		o.fail(fmt.Errorf("invalid %s %q: must be true or false", key, value))
+	}
+	return b
+}
+
+// finish returns the errors of the options read, and an error for any options
+// that the command doesn't take.
+func (o *optionReader) finish(command string) error {
+	for key := range o.options {
+		o.fail(fmt.Errorf("unknown option for %s: %q", command, key))
+	}
+	o.options = nil
+	return errors.Join(o.errs...)
+}
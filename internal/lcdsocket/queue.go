@@ -0,0 +1,78 @@
+package lcdsocket
+
+import (
+	"image"
+	"time"
+)
+
+// Limit of the content kept in a queue. When it's full, the oldest content is
+// dropped to make room for new content.
+const maxQueueItems = 32
+
+// item is content shown on the LCD until it expires.
+type item struct {
+	image    image.Image
+	priority int
+	expires  time.Time
+}
+
+// Queue keeps the content pushed to the LCD and decides which of it is shown.
+type Queue struct {
+	// items in the order they were pushed
+	items []item
+}
+
+// Handle adds the content of a message to the queue, or removes content for
+// clear messages. Metric messages are ignored.
+func (q *Queue) Handle(msg Message, now time.Time) {
+	if msg.Metric != nil {
+		return
+	}
+	if msg.Image != nil {
+		if len(q.items) == maxQueueItems {
+			clear(q.items[:1])
+			q.items = q.items[1:]
+		}
+		q.items = append(q.items, item{image: msg.Image, priority: msg.Priority, expires: now.Add(msg.Timeout)})
+		return
+	}
+	q.remove(func(it item) bool {
+		return msg.ClearAll || it.priority == msg.Priority
+	})
+}
+
+// Expire removes content whose timeout has passed. It returns true if any
+// content was removed.
+func (q *Queue) Expire(now time.Time) bool {
+	return q.remove(func(it item) bool {
+		return !now.Before(it.expires)
+	})
+}
+
+func (q *Queue) remove(match func(item) bool) bool {
+	kept := q.items[:0]
+	for _, it := range q.items {
+		if !match(it) {
+			kept = append(kept, it)
+		}
+	}
+	removed := len(kept) != len(q.items)
+	clear(q.items[len(kept):])
+	q.items = kept
+	return removed
+}
+
+// Current returns the content to show: the newest content with the highest
+// priority, or nil if there is none.
+func (q *Queue) Current() image.Image {
+	var current *item
+	for idx := range q.items {
+		if current == nil || q.items[idx].priority >= current.priority {
+			current = &q.items[idx]
+		}
+	}
+	if current == nil {
+		return nil
+	}
+	return current.image
+}
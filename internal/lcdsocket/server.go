@@ -0,0 +1,259 @@
+package lcdsocket
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Result is a message received by a [Server], or the error of a message that
+// couldn't be read.
+type Result struct {
+	Message Message
+	Err     error
+}
+
+// Server receives messages from a UNIX socket and a FIFO.
+type Server struct {
+	results chan Result
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mutex    sync.Mutex
+	listener net.Listener
+	conns    map[io.Closer]struct{}
+
+	// FIFO path, whether it was created by the server, and the channel
+	// closed when its reader stops
+	fifoPath    string
+	fifoCreated bool
+	fifoDone    chan struct{}
+}
+
+// NewServer returns a [Server] that isn't receiving from anything yet.
+func NewServer() *Server {
+	return &Server{
+		results: make(chan Result),
+		done:    make(chan struct{}),
+		conns:   make(map[io.Closer]struct{}),
+	}
+}
+
+// Results returns the channel that messages are sent to.
+func (s *Server) Results() <-chan Result {
+	return s.results
+}
+
+// send sends a result to the channel. It returns false if the server was
+// closed.
+func (s *Server) send(res Result) bool {
+	select {
+	case s.results <- res:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// track adds or removes an open connection or file, which is closed when the
+// server is closed. It returns false if the server was already closed.
+func (s *Server) track(c io.Closer, open bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !open {
+		delete(s.conns, c)
+		return true
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+// ListenSocket listens for connections on a UNIX socket at path, replacing a
+// stale socket left at the path. Each message received over a connection is
+// answered with "ok" or "error: <reason>" on a line. Connections are closed
+// after an error.
+func (s *Server) ListenSocket(path string) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return fmt.Errorf("socket %q is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed removing stale socket %q: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed listening on socket %q: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed setting permissions of socket %q: %w", path, err)
+	}
+	s.mutex.Lock()
+	s.listener = listener
+	s.mutex.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				// closed
+				return
+			}
+			if !s.track(conn, true) {
+				conn.Close()
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer s.track(conn, false)
+				defer conn.Close()
+				s.serveConn(conn)
+			}()
+		}
+	}()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := ReadMessage(r)
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			if s.send(Result{Err: err}) {
+				fmt.Fprintf(conn, "error: %s\n", err)
+			}
+			return
+		}
+		if !s.send(Result{Message: msg}) {
+			return
+		}
+		if _, err := io.WriteString(conn, "ok\n"); err != nil {
+			return
+		}
+	}
+}
+
+// ListenFIFO reads messages from a FIFO at path, creating it if it doesn't
+// exist. Messages can be written by any number of writers, one after the
+// other. Writers that overlap write to the same stream, so text should end
+// with an empty line rather than the end of the stream. A message that can't
+// be read is skipped along with the rest of the stream.
+func (s *Server) ListenFIFO(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := syscall.Mkfifo(path, 0o600); err != nil {
+			return fmt.Errorf("failed creating FIFO %q: %w", path, err)
+		}
+		s.fifoCreated = true
+	case err != nil:
+		return fmt.Errorf("failed reading FIFO %q: %w", path, err)
+	case info.Mode().Type() != fs.ModeNamedPipe:
+		return fmt.Errorf("%q is not a FIFO", path)
+	}
+	s.fifoPath = path
+	s.fifoDone = make(chan struct{})
+
+	go func() {
+		defer close(s.fifoDone)
+		for {
+			// blocks until there is a writer
+			file, err := os.OpenFile(path, os.O_RDONLY, 0)
+			if err != nil {
+				s.send(Result{Err: fmt.Errorf("failed opening FIFO %q: %w", path, err)})
+				return
+			}
+			if !s.track(file, true) {
+				file.Close()
+				return
+			}
+			r := bufio.NewReader(file)
+			for {
+				msg, err := ReadMessage(r)
+				if errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
+					break
+				}
+				res := Result{Message: msg, Err: err}
+				if !s.send(res) || err != nil {
+					break
+				}
+			}
+			s.track(file, false)
+			file.Close()
+
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops receiving messages, removing the socket and any FIFO created by
+// the server.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	select {
+	case <-s.done:
+		s.mutex.Unlock()
+		return nil
+	default:
+	}
+	close(s.done)
+	var errs []error
+	if s.listener != nil {
+		errs = append(errs, s.listener.Close())
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	if s.fifoDone != nil {
+		s.stopFIFO()
+		if s.fifoCreated {
+			errs = append(errs, os.Remove(s.fifoPath))
+		}
+	}
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// stopFIFO unblocks the FIFO reader if it's waiting for a writer and waits
+// for it to stop.
+func (s *Server) stopFIFO() {
+	for range 100 {
+		// opening without blocking fails if the reader isn't waiting yet
+		if file, err := os.OpenFile(s.fifoPath, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			file.Close()
+		}
+		select {
+		case <-s.fifoDone:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package lcdtext renders text for the G13 LCD, with a built-in bitmap font or
+// fonts loaded from files.
+package lcdtext
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/animation"
+	"github.com/achilleas-k/gg13/internal/device"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Space between the end of a scrolling line and its repetition.
+	marqueeGap = 24
+
+	// Shortest time between marquee frames. Faster marquees move more than one
+	// pixel per frame instead, to limit how often the LCD is redrawn.
+	minMarqueeDelay = 50 * time.Millisecond
+)
+
+// DefaultMarqueeSpeed is the speed, in pixels per second, at which lines that
+// are too wide for the LCD scroll when no speed is configured.
+const DefaultMarqueeSpeed = 25
+
+// Align is the horizontal alignment of text lines.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+var alignNames = map[Align]string{
+	AlignLeft:   "left",
+	AlignCenter: "center",
+	AlignRight:  "right",
+}
+
+func (a Align) String() string {
+	if name, ok := alignNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Align(%d)", int(a))
+}
+
+// ParseAlign returns the alignment with the given name. An empty name returns
+// [AlignLeft].
+func ParseAlign(name string) (Align, error) {
+	if name == "" {
+		return AlignLeft, nil
+	}
+	for align, alignName := range alignNames {
+		if alignName == name {
+			return align, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown text alignment: %s", name)
+}
+
+// Style defines how text is drawn.
+type Style struct {
+	// Font face for the text (nil for [Builtin]).
+	Face font.Face
+
+	Align Align
+
+	// Inverse draws white text on a black background.
+	Inverse bool
+}
+
+func (s Style) face() font.Face {
+	if s.Face == nil {
+		return Builtin
+	}
+	return s.Face
+}
+
+// Render draws lines of text, centred vertically, as a black and white image
+// for the LCD. Lines that are too wide for the LCD are cut off on the right.
+func Render(lines []string, style Style) *image.Gray {
+	return render(lines, style, 0, 0)
+}
+
+// Marquee returns an animation of lines of text where the lines that are too
+// wide for the LCD scroll from right to left at the given speed, in pixels per
+// second, and repeat forever. If no line is too wide, or the speed is zero, the
+// animation has a single frame, the same as [Render].
+func Marquee(lines []string, style Style, speed float64) *animation.Animation {
+	period := 0
+	for _, line := range lines {
+		if width := lineWidth(style.face(), line); width > device.LCDWidth {
+			period = max(period, width+marqueeGap)
+		}
+	}
+	if period == 0 || speed <= 0 {
+		return &animation.Animation{
+			Frames: []animation.Frame{{Image: Render(lines, style)}},
+		}
+	}
+
+	step := max(1, int(math.Ceil(speed*minMarqueeDelay.Seconds())))
+	delay := time.Duration(float64(step) / speed * float64(time.Second))
+	anim := &animation.Animation{}
+	for offset := 0; offset < period; offset += step {
+		anim.Frames = append(anim.Frames, animation.Frame{
+			Image: render(lines, style, offset, period),
+			Delay: delay,
+		})
+	}
+	return anim
+}
+
+// lineWidth returns the width of a line of text in pixels.
+func lineWidth(face font.Face, line string) int {
+	return font.MeasureString(face, line).Ceil()
+}
+
+// render draws the lines with the lines that are too wide for the LCD scrolled
+// left by offset pixels and repeated every period pixels. A zero period
+// disables scrolling.
+func render(lines []string, style Style, offset, period int) *image.Gray {
+	fg, bg := image.Black, image.White
+	if style.Inverse {
+		fg, bg = bg, fg
+	}
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), bg, image.Point{}, draw.Src)
+
+	face := style.face()
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	ascent := metrics.Ascent.Ceil()
+	top := max(0, (device.LCDHeight-len(lines)*lineHeight)/2)
+
+	for idx, line := range lines {
+		baseline := top + idx*lineHeight + ascent
+		width := lineWidth(face, line)
+
+		var xs []int
+		switch {
+		case width > device.LCDWidth && period > 0:
+			xs = []int{-offset, period - offset}
+		case width > device.LCDWidth:
+			xs = []int{0}
+		case style.Align == AlignCenter:
+			xs = []int{(device.LCDWidth - width) / 2}
+		case style.Align == AlignRight:
+			xs = []int{device.LCDWidth - width}
+		default:
+			xs = []int{0}
+		}
+
+		for _, x := range xs {
+			drawer := font.Drawer{
+				Dst:  img,
+				Src:  fg,
+				Face: face,
+				Dot:  fixed.P(x, baseline),
+			}
+			drawer.DrawString(line)
+		}
+	}
+
+	// fonts loaded from files are anti-aliased
+	for idx, v := range img.Pix {
+		if v < 0x80 {
+			img.Pix[idx] = 0
+		} else {
+			img.Pix[idx] = 0xff
+		}
+	}
+	return img
+}
@@ -0,0 +1,150 @@
+package lcdtext_test
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// inkBounds returns the bounding box of the pixels that don't match the
+// background colour of img (taken from the top left pixel).
+func inkBounds(img *image.Gray) image.Rectangle {
+	bg := img.Pix[0]
+	var bounds image.Rectangle
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			if img.Pix[y*img.Stride+x] != bg {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+func TestParseAlign(t *testing.T) {
+	assert := assert.New(t)
+	for _, align := range []lcdtext.Align{lcdtext.AlignLeft, lcdtext.AlignCenter, lcdtext.AlignRight} {
+		parsed, err := lcdtext.ParseAlign(align.String())
+		assert.NoError(err)
+		assert.Equal(align, parsed)
+	}
+	align, err := lcdtext.ParseAlign("")
+	assert.NoError(err)
+	assert.Equal(lcdtext.AlignLeft, align)
+
+	_, err = lcdtext.ParseAlign("justify")
+	assert.EqualError(err, "unknown text alignment: justify")
+}
+
+func TestRenderBuiltin(t *testing.T) {
+	assert := assert.New(t)
+
+	img := lcdtext.Render([]string{"I"}, lcdtext.Style{})
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+	// a single line of 8 pixels is centred vertically: rows 17-24, with the
+	// glyph in the top 7
+	assert.Equal(image.Rect(1, 17, 4, 24), inkBounds(img))
+	// only black and white
+	for _, v := range img.Pix {
+		assert.True(v == 0 || v == 0xff)
+	}
+
+	// five lines fit
+	img = lcdtext.Render([]string{"A", "B", "C", "D", "E"}, lcdtext.Style{})
+	ink := inkBounds(img)
+	assert.Equal(1, ink.Min.Y)
+	assert.Equal(1+4*8+7, ink.Max.Y)
+
+	// unknown characters are drawn as a box
+	img = lcdtext.Render([]string{"é"}, lcdtext.Style{})
+	assert.Equal(image.Rect(0, 17, 5, 24), inkBounds(img))
+}
+
+func TestRenderAlign(t *testing.T) {
+	assert := assert.New(t)
+	// "HH" is 12 pixels wide including the spacing after the last glyph
+	center := lcdtext.Render([]string{"HH"}, lcdtext.Style{Align: lcdtext.AlignCenter})
+	assert.Equal(74, inkBounds(center).Min.X)
+
+	right := lcdtext.Render([]string{"HH"}, lcdtext.Style{Align: lcdtext.AlignRight})
+	assert.Equal(device.LCDWidth-1, inkBounds(right).Max.X)
+}
+
+func TestRenderInverse(t *testing.T) {
+	assert := assert.New(t)
+	img := lcdtext.Render([]string{"I"}, lcdtext.Style{Inverse: true})
+	assert.Equal(uint8(0), img.Pix[0])
+	assert.Equal(image.Rect(1, 17, 4, 24), inkBounds(img))
+	assert.Equal(uint8(0xff), img.GrayAt(2, 17).Y)
+}
+
+func TestMarquee(t *testing.T) {
+	t.Run("short", func(t *testing.T) {
+		assert := assert.New(t)
+		anim := lcdtext.Marquee([]string{"short"}, lcdtext.Style{}, 25)
+		assert.Len(anim.Frames, 1)
+		assert.Equal(lcdtext.Render([]string{"short"}, lcdtext.Style{}), anim.Frames[0].Image)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		anim := lcdtext.Marquee([]string{strings.Repeat("x", 40)}, lcdtext.Style{}, 0)
+		assert.Len(anim.Frames, 1)
+	})
+
+	t.Run("scrolling", func(t *testing.T) {
+		assert := assert.New(t)
+		// 40 characters of 6 pixels: 240 pixels, plus the gap
+		long := strings.Repeat("x", 40)
+		anim := lcdtext.Marquee([]string{long, "static"}, lcdtext.Style{}, 10)
+		assert.Len(anim.Frames, 240+24)
+		assert.Equal(0, anim.Loops)
+		assert.Equal(lcdtext.Render([]string{long, "static"}, lcdtext.Style{}), anim.Frames[0].Image)
+		assert.NotEqual(anim.Frames[0].Image, anim.Frames[1].Image)
+
+		// fast marquees move several pixels per frame
+		anim = lcdtext.Marquee([]string{long}, lcdtext.Style{}, 100)
+		assert.Len(anim.Frames, (240+24)/5+1)
+		assert.Equal(anim.Frames[0].Delay, anim.Frames[1].Delay)
+		assert.Equal(int64(50), anim.Frames[0].Delay.Milliseconds())
+	})
+}
+
+func TestLoadFont(t *testing.T) {
+	assert := assert.New(t)
+
+	face, err := lcdtext.LoadFont("", 0)
+	assert.NoError(err)
+	assert.Equal(lcdtext.Builtin, face)
+
+	face, err = lcdtext.LoadFont("basic", 0)
+	assert.NoError(err)
+	assert.Equal(basicfont.Face7x13, face)
+
+	path := filepath.Join(t.TempDir(), "font.ttf")
+	assert.NoError(os.WriteFile(path, goregular.TTF, 0o644))
+	face, err = lcdtext.LoadFont(path, 20)
+	assert.NoError(err)
+	img := lcdtext.Render([]string{"Hello"}, lcdtext.Style{Face: face})
+	ink := inkBounds(img)
+	assert.Greater(ink.Dy(), 10)
+	for _, v := range img.Pix {
+		assert.True(v == 0 || v == 0xff)
+	}
+
+	_, err = lcdtext.LoadFont(filepath.Join(t.TempDir(), "nope.ttf"), 0)
+	assert.ErrorContains(err, "failed to read font file")
+
+	notAFont := filepath.Join(t.TempDir(), "font.otf")
+	assert.NoError(os.WriteFile(notAFont, []byte("not a font"), 0o644))
+	_, err = lcdtext.LoadFont(notAFont, 0)
+	assert.ErrorContains(err, "failed to parse font file")
+}
@@ -0,0 +1,150 @@
+// Package menu implements a hierarchical menu drawn on the G13 LCD and
+// navigated one step at a time, with the keys under the LCD or the stick.
+package menu
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Height of each line of the menu, including the title.
+	lineHeight = 8
+
+	// Number of items visible below the title.
+	visibleItems = (device.LCDHeight - lineHeight - 1) / lineHeight
+)
+
+// Item is a single menu entry. Items with sub-items open a submenu when
+// chosen; items without return their value to the caller.
+type Item struct {
+	Label string
+	Value any
+	Items []Item
+}
+
+// level is an open menu or submenu.
+type level struct {
+	title    string
+	items    []Item
+	selected int
+
+	// index of the first visible item
+	top int
+}
+
+// Navigator tracks the state of an open menu.
+type Navigator struct {
+	levels []*level
+}
+
+// Open returns a [Navigator] for a menu with the given title and items, with
+// the first item selected.
+func Open(title string, items []Item) *Navigator {
+	return &Navigator{levels: []*level{{title: title, items: items}}}
+}
+
+func (n *Navigator) current() *level {
+	return n.levels[len(n.levels)-1]
+}
+
+// Up selects the previous item, wrapping around to the last one.
+func (n *Navigator) Up() {
+	n.move(-1)
+}
+
+// Down selects the next item, wrapping around to the first one.
+func (n *Navigator) Down() {
+	n.move(1)
+}
+
+func (n *Navigator) move(delta int) {
+	lvl := n.current()
+	if len(lvl.items) == 0 {
+		return
+	}
+	lvl.selected = (lvl.selected + delta + len(lvl.items)) % len(lvl.items)
+	if lvl.selected < lvl.top {
+		lvl.top = lvl.selected
+	} else if lvl.selected >= lvl.top+visibleItems {
+		lvl.top = lvl.selected - visibleItems + 1
+	}
+}
+
+// Enter chooses the selected item. If it has sub-items, their submenu is
+// opened and nil is returned. Otherwise, the chosen item is returned.
+func (n *Navigator) Enter() *Item {
+	lvl := n.current()
+	if len(lvl.items) == 0 {
+		return nil
+	}
+	item := &lvl.items[lvl.selected]
+	if len(item.Items) > 0 {
+		n.levels = append(n.levels, &level{title: item.Label, items: item.Items})
+		return nil
+	}
+	return item
+}
+
+// Back returns to the parent menu. It returns false if the top level menu is
+// open, in which case the menu should be closed.
+func (n *Navigator) Back() bool {
+	if len(n.levels) == 1 {
+		return false
+	}
+	n.levels = n.levels[:len(n.levels)-1]
+	return true
+}
+
+// Selected returns the selected item of the open menu, or nil if it has no
+// items.
+func (n *Navigator) Selected() *Item {
+	lvl := n.current()
+	if len(lvl.items) == 0 {
+		return nil
+	}
+	return &lvl.items[lvl.selected]
+}
+
+// Render draws the open menu as an image for the LCD: the title, underlined,
+// followed by the visible items with the selected item highlighted. Items with
+// sub-items are marked with an arrow.
+func (n *Navigator) Render() image.Image {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	lvl := n.current()
+	face := lcdtext.Builtin
+	drawText := func(text string, x, row int, src image.Image) {
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  src,
+			Face: face,
+			Dot:  fixed.P(x, row+face.Ascent),
+		}
+		drawer.DrawString(text)
+	}
+
+	drawText(lvl.title, 1, 0, image.Black)
+	draw.Draw(img, image.Rect(0, lineHeight, device.LCDWidth, lineHeight+1), image.Black, image.Point{}, draw.Src)
+
+	for idx := lvl.top; idx < len(lvl.items) && idx < lvl.top+visibleItems; idx++ {
+		item := lvl.items[idx]
+		row := lineHeight + 2 + (idx-lvl.top)*lineHeight
+		src := image.Image(image.Black)
+		if idx == lvl.selected {
+			draw.Draw(img, image.Rect(0, row-1, device.LCDWidth, row+lineHeight-1), image.Black, image.Point{}, draw.Src)
+			src = image.White
+		}
+		drawText(item.Label, 3, row, src)
+		if len(item.Items) > 0 {
+			drawText(">", device.LCDWidth-8, row, src)
+		}
+	}
+	return img
+}
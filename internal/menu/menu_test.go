@@ -0,0 +1,110 @@
+package menu_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/menu"
+	"github.com/stretchr/testify/assert"
+)
+
+func testItems() []menu.Item {
+	return []menu.Item{
+		{Label: "one", Value: 1},
+		{Label: "sub", Items: []menu.Item{
+			{Label: "two", Value: 2},
+			{Label: "three", Value: 3},
+		}},
+		{Label: "four", Value: 4},
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	assert := assert.New(t)
+
+	nav := menu.Open("Menu", testItems())
+	assert.Equal("one", nav.Selected().Label)
+
+	nav.Up()
+	assert.Equal("four", nav.Selected().Label)
+	nav.Down()
+	assert.Equal("one", nav.Selected().Label)
+	nav.Down()
+	assert.Equal("sub", nav.Selected().Label)
+
+	// submenu
+	assert.Nil(nav.Enter())
+	assert.Equal("two", nav.Selected().Label)
+	nav.Down()
+	item := nav.Enter()
+	assert.NotNil(item)
+	assert.Equal(3, item.Value)
+
+	assert.True(nav.Back())
+	assert.Equal("sub", nav.Selected().Label)
+	assert.False(nav.Back())
+	assert.Equal("sub", nav.Selected().Label)
+
+	nav.Down()
+	item = nav.Enter()
+	assert.NotNil(item)
+	assert.Equal(4, item.Value)
+}
+
+func TestEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	nav := menu.Open("Empty", nil)
+	nav.Up()
+	nav.Down()
+	assert.Nil(nav.Selected())
+	assert.Nil(nav.Enter())
+	assert.False(nav.Back())
+	assert.NotNil(nav.Render())
+}
+
+// selectedRow returns the first row of the image below the title that is
+// highlighted (black at its left edge).
+func selectedRow(img image.Image) int {
+	for y := 10; y < img.Bounds().Dy(); y++ {
+		if color.GrayModel.Convert(img.At(0, y)).(color.Gray).Y == 0 {
+			return y
+		}
+	}
+	return -1
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	items := make([]menu.Item, 8)
+	for idx := range items {
+		items[idx] = menu.Item{Label: "item", Value: idx}
+	}
+	nav := menu.Open("Menu", items)
+
+	img := nav.Render()
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+
+	// the highlight moves down with the selection until the last visible
+	// item, then the items scroll
+	first := selectedRow(img)
+	nav.Down()
+	second := selectedRow(nav.Render())
+	assert.Greater(second, first)
+
+	var rows []int
+	for range 6 {
+		nav.Down()
+		rows = append(rows, selectedRow(nav.Render()))
+	}
+	assert.Equal(rows[len(rows)-1], rows[len(rows)-2])
+	assert.Equal(7, nav.Selected().Value)
+
+	// wrapping around to the first item scrolls back to the top
+	nav.Down()
+	assert.Equal(0, nav.Selected().Value)
+	assert.Equal(first, selectedRow(nav.Render()))
+}
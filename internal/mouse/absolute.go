@@ -0,0 +1,77 @@
+package mouse
+
+import (
+	"fmt"
+
+	"github.com/achilleas-k/gg13/internal/uinputdev"
+)
+
+// Resolution of the absolute pointer axes. The compositor scales the range to
+// the screen size.
+const absoluteMax = 0xffff
+
+type AbsolutePointer interface {
+	Close() error
+	MoveTo(x, y float32) error
+}
+
+type UinputAbsolutePointer struct {
+	dev *uinputdev.Device
+}
+
+// NewAbsolute returns an [AbsolutePointer] instance backed by a virtual uinput
+// absolute pointer (like a tablet or touchscreen in mouse emulation),
+// initialised with the provided name.
+func NewAbsolute(name string) (AbsolutePointer, error) {
+	axis := uinputdev.AbsInfo{Min: 0, Max: absoluteMax}
+	dev, err := uinputdev.Create(uinputdev.DefaultPath, uinputdev.Spec{
+		Name:    name,
+		Bus:     uinputdev.BusUSB,
+		Vendor:  0x4711,
+		Product: 0x0818,
+		Version: 1,
+		// a button is required for the device to be recognised as a pointer
+		Keys: []uint16{uinputdev.BtnLeft},
+		Abs: map[uint16]uinputdev.AbsInfo{
+			uinputdev.AbsX: axis,
+			uinputdev.AbsY: axis,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UinputAbsolutePointer{
+		dev: dev,
+	}, nil
+}
+
+func (vap *UinputAbsolutePointer) Close() error {
+	if !vap.hasPointer() {
+		// just do nothing
+		return nil
+	}
+	return vap.dev.Close()
+}
+
+// MoveTo moves the pointer to the given position, where 0, 0 is the top left
+// and 1, 1 the bottom right corner of the screen.
+func (vap *UinputAbsolutePointer) MoveTo(x, y float32) error {
+	if !vap.hasPointer() {
+		return fmt.Errorf("move before initialising absolute pointer")
+	}
+	if err := vap.dev.Emit(uinputdev.EvAbs, uinputdev.AbsX, scaleAbsolute(x)); err != nil {
+		return err
+	}
+	if err := vap.dev.Emit(uinputdev.EvAbs, uinputdev.AbsY, scaleAbsolute(y)); err != nil {
+		return err
+	}
+	return vap.dev.Sync()
+}
+
+func scaleAbsolute(v float32) int32 {
+	return int32(min(max(v, 0), 1) * absoluteMax)
+}
+
+func (vap *UinputAbsolutePointer) hasPointer() bool {
+	return vap.dev != nil
+}
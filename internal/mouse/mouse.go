@@ -0,0 +1,136 @@
+package mouse
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/achilleas-k/gg13/internal/uinputdev"
+)
+
+type Mouse interface {
+	Close() error
+	Move(x, y float32) error
+	Scroll(horizontal, vertical float32) error
+}
+
+type UinputMouse struct {
+	dev *uinputdev.Device
+
+	// sub-pixel pointer movement carried over between calls to Move
+	moveRemainder [2]float64
+
+	// sub-unit scroll amounts carried over between calls to Scroll, in
+	// high-resolution units (1/120 of a detent)
+	hiResRemainder [2]float64
+
+	// high-resolution units accumulated towards the next legacy wheel detent
+	detentRemainder [2]int32
+}
+
+// New returns a [Mouse] instance backed by a virtual uinput mouse with
+// high-resolution wheels, initialised with the provided name.
+func New(name string) (Mouse, error) {
+	dev, err := uinputdev.Create(uinputdev.DefaultPath, uinputdev.Spec{
+		Name:    name,
+		Bus:     uinputdev.BusUSB,
+		Vendor:  0x4711,
+		Product: 0x0817,
+		Version: 1,
+		Keys:    []uint16{uinputdev.BtnLeft, uinputdev.BtnRight, uinputdev.BtnMiddle},
+		Rels: []uint16{
+			uinputdev.RelX, uinputdev.RelY,
+			uinputdev.RelWheel, uinputdev.RelHWheel,
+			uinputdev.RelWheelHiRes, uinputdev.RelHWheelHiRes,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UinputMouse{
+		dev: dev,
+	}, nil
+}
+
+func (vm *UinputMouse) Close() error {
+	if !vm.hasMouse() {
+		// just do nothing
+		return nil
+	}
+	return vm.dev.Close()
+}
+
+// Move moves the pointer by the given amounts, in pixels. Fractional amounts
+// accumulate until they add up to whole pixels.
+func (vm *UinputMouse) Move(x, y float32) error {
+	if !vm.hasMouse() {
+		return fmt.Errorf("move before initialising mouse")
+	}
+
+	dx, remX := math.Modf(float64(x) + vm.moveRemainder[0])
+	dy, remY := math.Modf(float64(y) + vm.moveRemainder[1])
+	vm.moveRemainder = [2]float64{remX, remY}
+	if dx == 0 && dy == 0 {
+		return nil
+	}
+
+	if dx != 0 {
+		if err := vm.dev.Emit(uinputdev.EvRel, uinputdev.RelX, int32(dx)); err != nil {
+			return err
+		}
+	}
+	if dy != 0 {
+		if err := vm.dev.Emit(uinputdev.EvRel, uinputdev.RelY, int32(dy)); err != nil {
+			return err
+		}
+	}
+	return vm.dev.Sync()
+}
+
+// Scroll moves the horizontal and vertical wheels by the given amounts, in
+// detents (notches). Fractional amounts are sent as high-resolution wheel
+// events and accumulate into regular wheel events for applications that don't
+// support them. Positive values scroll right and up respectively.
+func (vm *UinputMouse) Scroll(horizontal, vertical float32) error {
+	if !vm.hasMouse() {
+		return fmt.Errorf("scroll before initialising mouse")
+	}
+
+	axes := [2]struct {
+		amount float32
+		hiRes  uint16
+		legacy uint16
+	}{
+		{horizontal, uinputdev.RelHWheelHiRes, uinputdev.RelHWheel},
+		{vertical, uinputdev.RelWheelHiRes, uinputdev.RelWheel},
+	}
+
+	emitted := false
+	for idx, axis := range axes {
+		hiRes, remainder := math.Modf(float64(axis.amount)*uinputdev.WheelHiResPerDetent + vm.hiResRemainder[idx])
+		vm.hiResRemainder[idx] = remainder
+		if hiRes == 0 {
+			continue
+		}
+
+		if err := vm.dev.Emit(uinputdev.EvRel, axis.hiRes, int32(hiRes)); err != nil {
+			return err
+		}
+		vm.detentRemainder[idx] += int32(hiRes)
+		if detents := vm.detentRemainder[idx] / uinputdev.WheelHiResPerDetent; detents != 0 {
+			if err := vm.dev.Emit(uinputdev.EvRel, axis.legacy, detents); err != nil {
+				return err
+			}
+			vm.detentRemainder[idx] -= detents * uinputdev.WheelHiResPerDetent
+		}
+		emitted = true
+	}
+
+	if !emitted {
+		return nil
+	}
+	return vm.dev.Sync()
+}
+
+func (vm *UinputMouse) hasMouse() bool {
+	return vm.dev != nil
+}
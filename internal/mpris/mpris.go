@@ -0,0 +1,251 @@
+// Package mpris reads the track played by desktop media players and controls
+// playback through the MPRIS D-Bus interface.
+package mpris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	busNamePrefix   = "org.mpris.MediaPlayer2."
+	objectPath      = "/org/mpris/MediaPlayer2"
+	playerInterface = "org.mpris.MediaPlayer2.Player"
+)
+
+// How long a call to a player may take before it's abandoned.
+const callTimeout = 2 * time.Second
+
+// ErrNoPlayer is returned when no media player is running on the bus.
+var ErrNoPlayer = errors.New("no media player found")
+
+// Status is the track a player is playing.
+type Status struct {
+	// Name of the player on the bus, without the MPRIS prefix.
+	Player string
+
+	Title   string
+	Artists []string
+
+	// Length of the track (zero if unknown) and the playback position in
+	// it.
+	Length   time.Duration
+	Position time.Duration
+
+	// Playing is false when playback is paused or stopped.
+	Playing bool
+}
+
+// Artist returns the artists of the track, separated by commas.
+func (s Status) Artist() string {
+	return strings.Join(s.Artists, ", ")
+}
+
+// Progress returns how far playback is through the track, from 0 to 1. It's
+// 0 if the length of the track is unknown.
+func (s Status) Progress() float64 {
+	if s.Length <= 0 {
+		return 0
+	}
+	return min(1, max(0, float64(s.Position)/float64(s.Length)))
+}
+
+// Client talks to the media players on a bus. It connects when it's first
+// used and reconnects when the connection is lost, so it can be created
+// before the bus is available. A client may be used from multiple
+// goroutines.
+type Client struct {
+	address string
+	player  string
+
+	mutex sync.Mutex
+	conn  *dbus.Conn
+}
+
+// NewClient returns a client for the players on the bus at an address (empty
+// for the session bus). If player is set, only players whose bus name starts
+// with it after the MPRIS prefix, like "spotify" or "vlc", are used;
+// otherwise the first player that is playing is used, or the first player if
+// none is.
+func NewClient(address, player string) *Client {
+	return &Client{address: address, player: player}
+}
+
+// connection returns the connection to the bus, connecting if needed.
+func (c *Client) connection() (*dbus.Conn, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conn != nil && c.conn.Connected() {
+		return c.conn, nil
+	}
+
+	var conn *dbus.Conn
+	var err error
+	if c.address == "" {
+		conn, err = dbus.ConnectSessionBus()
+	} else {
+		conn, err = dbus.Connect(c.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to bus: %w", err)
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// Close disconnects from the bus.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// players returns the bus names of the players that may be used, sorted.
+func (c *Client) players(ctx context.Context, conn *dbus.Conn) ([]string, error) {
+	var names []string
+	if err := conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return nil, fmt.Errorf("failed listing bus names: %w", err)
+	}
+	var players []string
+	for _, name := range names {
+		if strings.HasPrefix(name, busNamePrefix+c.player) {
+			players = append(players, name)
+		}
+	}
+	sort.Strings(players)
+	return players, nil
+}
+
+// properties returns the properties of the player interface of a player.
+func properties(ctx context.Context, conn *dbus.Conn, name string) (map[string]dbus.Variant, error) {
+	var props map[string]dbus.Variant
+	err := conn.Object(name, objectPath).CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, playerInterface).Store(&props)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading properties of %s: %w", name, err)
+	}
+	return props, nil
+}
+
+// find returns the bus name and properties of the player to use.
+func (c *Client) find(ctx context.Context, conn *dbus.Conn) (string, map[string]dbus.Variant, error) {
+	players, err := c.players(ctx, conn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var firstName string
+	var firstProps map[string]dbus.Variant
+	for _, name := range players {
+		props, err := properties(ctx, conn, name)
+		if err != nil {
+			// the player may have quit since the names were listed
+			continue
+		}
+		if status, _ := props["PlaybackStatus"].Value().(string); status == "Playing" {
+			return name, props, nil
+		}
+		if firstProps == nil {
+			firstName, firstProps = name, props
+		}
+	}
+	if firstProps == nil {
+		return "", nil, ErrNoPlayer
+	}
+	return firstName, firstProps, nil
+}
+
+// Status returns the track of the player. It returns [ErrNoPlayer] if no
+// player is running.
+func (c *Client) Status() (Status, error) {
+	conn, err := c.connection()
+	if err != nil {
+		return Status{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	name, props, err := c.find(ctx, conn)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Player: strings.TrimPrefix(name, busNamePrefix)}
+	playback, _ := props["PlaybackStatus"].Value().(string)
+	status.Playing = playback == "Playing"
+	status.Position = microseconds(props["Position"].Value())
+
+	metadata, _ := props["Metadata"].Value().(map[string]dbus.Variant)
+	status.Title, _ = metadata["xesam:title"].Value().(string)
+	status.Artists, _ = metadata["xesam:artist"].Value().([]string)
+	status.Length = microseconds(metadata["mpris:length"].Value())
+	return status, nil
+}
+
+// microseconds converts a time in microseconds, which players send as
+// different integer types, to a duration. Anything else is zero.
+func microseconds(value any) time.Duration {
+	switch v := value.(type) {
+	case int64:
+		return time.Duration(v) * time.Microsecond
+	case uint64:
+		return time.Duration(v) * time.Microsecond
+	case int32:
+		return time.Duration(v) * time.Microsecond
+	case uint32:
+		return time.Duration(v) * time.Microsecond
+	}
+	return 0
+}
+
+// call calls a method of the player interface on the player.
+func (c *Client) call(method string, args ...any) error {
+	conn, err := c.connection()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	name, _, err := c.find(ctx, conn)
+	if err != nil {
+		return err
+	}
+	call := conn.Object(name, objectPath).CallWithContext(ctx, playerInterface+"."+method, 0, args...)
+	if call.Err != nil {
+		return fmt.Errorf("failed calling %s on %s: %w", method, name, call.Err)
+	}
+	return nil
+}
+
+// PlayPause pauses playback if the player is playing and starts it
+// otherwise.
+func (c *Client) PlayPause() error {
+	return c.call("PlayPause")
+}
+
+// Next skips to the next track.
+func (c *Client) Next() error {
+	return c.call("Next")
+}
+
+// Previous skips to the previous track.
+func (c *Client) Previous() error {
+	return c.call("Previous")
+}
+
+// Seek moves the playback position forwards by an offset, or backwards if
+// it's negative.
+func (c *Client) Seek(offset time.Duration) error {
+	return c.call("Seek", offset.Microseconds())
+}
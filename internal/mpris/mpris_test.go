@@ -0,0 +1,226 @@
+package mpris_test
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/mpris"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/stretchr/testify/assert"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*"/>
+    <allow receive_sender="*"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startBus starts a private bus and returns its address. The test is skipped
+// if dbus-daemon isn't installed.
+func startBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+
+	tmpdir := t.TempDir()
+	cfgPath := filepath.Join(tmpdir, "bus.conf")
+	assert.NoError(t, os.WriteFile(cfgPath, []byte(strings.ReplaceAll(busConfig, "%s", tmpdir)), 0o600))
+
+	cmd := exec.Command(daemon, "--config-file="+cfgPath, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	assert.NoError(t, err)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed starting dbus-daemon: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed reading bus address: %s", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// fakePlayer is a media player on a bus with a single track, which records
+// the methods called on it.
+type fakePlayer struct {
+	props *prop.Properties
+
+	mutex sync.Mutex
+	calls []string
+}
+
+// startPlayer exports a fake player with a name on the bus at an address.
+func startPlayer(t *testing.T, address, name, title, status string) *fakePlayer {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	player := &fakePlayer{}
+	// Seek is exported under another name, as vet expects a Seek method to
+	// be an io.Seeker
+	methods := map[string]string{"SeekBy": "Seek"}
+	assert.NoError(t, conn.ExportWithMap(player, methods, "/org/mpris/MediaPlayer2", "org.mpris.MediaPlayer2.Player"))
+	player.props, err = prop.Export(conn, "/org/mpris/MediaPlayer2", prop.Map{
+		"org.mpris.MediaPlayer2.Player": {
+			"PlaybackStatus": {Value: status, Emit: prop.EmitTrue},
+			"Position":       {Value: int64(30_000_000), Emit: prop.EmitFalse},
+			"Metadata": {Value: map[string]dbus.Variant{
+				"xesam:title":  dbus.MakeVariant(title),
+				"xesam:artist": dbus.MakeVariant([]string{"Artist", "Guest"}),
+				"mpris:length": dbus.MakeVariant(int64(120_000_000)),
+			}, Emit: prop.EmitTrue},
+		},
+	})
+	assert.NoError(t, err)
+
+	reply, err := conn.RequestName("org.mpris.MediaPlayer2."+name, dbus.NameFlagDoNotQueue)
+	assert.NoError(t, err)
+	assert.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+	return player
+}
+
+func (p *fakePlayer) record(call string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.calls = append(p.calls, call)
+}
+
+func (p *fakePlayer) Calls() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+func (p *fakePlayer) PlayPause() *dbus.Error {
+	p.record("PlayPause")
+	if p.props.GetMust("org.mpris.MediaPlayer2.Player", "PlaybackStatus") == "Playing" {
+		p.props.SetMust("org.mpris.MediaPlayer2.Player", "PlaybackStatus", "Paused")
+	} else {
+		p.props.SetMust("org.mpris.MediaPlayer2.Player", "PlaybackStatus", "Playing")
+	}
+	return nil
+}
+
+func (p *fakePlayer) Next() *dbus.Error {
+	p.record("Next")
+	return nil
+}
+
+func (p *fakePlayer) Previous() *dbus.Error {
+	p.record("Previous")
+	return nil
+}
+
+func (p *fakePlayer) SeekBy(offset int64) *dbus.Error {
+	p.record("Seek")
+	position := p.props.GetMust("org.mpris.MediaPlayer2.Player", "Position").(int64)
+	p.props.SetMust("org.mpris.MediaPlayer2.Player", "Position", position+offset)
+	return nil
+}
+
+func TestStatus(t *testing.T) {
+	assert := assert.New(t)
+	address := startBus(t)
+
+	client := mpris.NewClient(address, "")
+	defer client.Close()
+	_, err := client.Status()
+	assert.ErrorIs(err, mpris.ErrNoPlayer)
+
+	startPlayer(t, address, "paused", "Paused Song", "Paused")
+	status, err := client.Status()
+	assert.NoError(err)
+	assert.Equal("paused", status.Player)
+	assert.False(status.Playing)
+
+	// a player that is playing is preferred
+	startPlayer(t, address, "playing", "Playing Song", "Playing")
+	status, err = client.Status()
+	assert.NoError(err)
+	assert.Equal(mpris.Status{
+		Player:   "playing",
+		Title:    "Playing Song",
+		Artists:  []string{"Artist", "Guest"},
+		Length:   2 * time.Minute,
+		Position: 30 * time.Second,
+		Playing:  true,
+	}, status)
+	assert.Equal("Artist, Guest", status.Artist())
+	assert.Equal(0.25, status.Progress())
+
+	// unless another one is chosen
+	chosen := mpris.NewClient(address, "paus")
+	defer chosen.Close()
+	status, err = chosen.Status()
+	assert.NoError(err)
+	assert.Equal("Paused Song", status.Title)
+
+	missing := mpris.NewClient(address, "missing")
+	defer missing.Close()
+	_, err = missing.Status()
+	assert.ErrorIs(err, mpris.ErrNoPlayer)
+}
+
+func TestControl(t *testing.T) {
+	assert := assert.New(t)
+	address := startBus(t)
+	player := startPlayer(t, address, "test", "Song", "Playing")
+
+	client := mpris.NewClient(address, "")
+	defer client.Close()
+	assert.NoError(client.PlayPause())
+	assert.NoError(client.Next())
+	assert.NoError(client.Previous())
+	assert.NoError(client.Seek(-10 * time.Second))
+	assert.Equal([]string{"PlayPause", "Next", "Previous", "Seek"}, player.Calls())
+
+	status, err := client.Status()
+	assert.NoError(err)
+	assert.False(status.Playing)
+	assert.Equal(20*time.Second, status.Position)
+}
+
+func TestReconnect(t *testing.T) {
+	assert := assert.New(t)
+	address := startBus(t)
+	startPlayer(t, address, "test", "Song", "Playing")
+
+	client := mpris.NewClient(address, "")
+	_, err := client.Status()
+	assert.NoError(err)
+	assert.NoError(client.Close())
+
+	// the client connects again after it's closed
+	_, err = client.Status()
+	assert.NoError(err)
+	assert.NoError(client.Close())
+}
+
+func TestProgress(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(0.0, mpris.Status{Position: time.Second}.Progress())
+	assert.Equal(1.0, mpris.Status{Position: 2 * time.Second, Length: time.Second}.Progress())
+	assert.Equal(0.5, mpris.Status{Position: time.Second, Length: 2 * time.Second}.Progress())
+}
@@ -0,0 +1,195 @@
+// Package notify listens for desktop notifications sent through the
+// org.freedesktop.Notifications D-Bus interface, by monitoring the messages
+// sent to the notification server on the bus.
+package notify
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	notificationsInterface = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+)
+
+// Notification is a single desktop notification.
+type Notification struct {
+	App     string
+	Summary string
+	Body    string
+}
+
+// Parse returns the notification sent by a call to the Notify method of the
+// notifications interface. It returns false if the message is anything else.
+func Parse(msg *dbus.Message) (Notification, bool) {
+	if msg.Type != dbus.TypeMethodCall {
+		return Notification{}, false
+	}
+	iface, _ := msg.Headers[dbus.FieldInterface].Value().(string)
+	member, _ := msg.Headers[dbus.FieldMember].Value().(string)
+	if iface != notificationsInterface || member != "Notify" {
+		return Notification{}, false
+	}
+
+	// app_name, replaces_id, app_icon, summary, body, actions, hints,
+	// expire_timeout
+	var n Notification
+	if len(msg.Body) < 5 {
+		return Notification{}, false
+	}
+	var ok bool
+	if n.App, ok = msg.Body[0].(string); !ok {
+		return Notification{}, false
+	}
+	if n.Summary, ok = msg.Body[3].(string); !ok {
+		return Notification{}, false
+	}
+	if n.Body, ok = msg.Body[4].(string); !ok {
+		return Notification{}, false
+	}
+	return n, true
+}
+
+// Render draws a notification for the LCD with the built-in font: the
+// application name, inverted, at the top, followed by the summary and the
+// first lines of the body. Lines that are too wide are cut off.
+func (n Notification) Render() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	face := lcdtext.Builtin
+	lineHeight := face.Height + 1
+	header := image.Rect(0, 0, device.LCDWidth, lineHeight)
+	draw.Draw(img, header, image.Black, image.Point{}, draw.Src)
+	drawLine(img, image.White, 0, n.App)
+
+	lines := []string{n.Summary}
+	for _, line := range strings.Split(n.Body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	for idx, line := range lines {
+		top := (idx + 1) * lineHeight
+		if top+face.Height > device.LCDHeight {
+			break
+		}
+		drawLine(img, image.Black, top+1, line)
+	}
+	return img
+}
+
+// drawLine draws a line of text with the built-in font, with its top at y.
+func drawLine(img draw.Image, src image.Image, y int, text string) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  src,
+		Face: lcdtext.Builtin,
+		Dot:  fixed.P(1, y+lcdtext.Builtin.Ascent),
+	}
+	drawer.DrawString(text)
+}
+
+// Filter selects notifications by application name. Names are compared
+// without case.
+type Filter struct {
+	// Applications whose notifications are shown. All are shown if empty.
+	Allow []string
+
+	// Applications whose notifications are never shown.
+	Deny []string
+}
+
+// Allows returns true if notifications from app pass the filter.
+func (f Filter) Allows(app string) bool {
+	contains := func(names []string) bool {
+		for _, name := range names {
+			if strings.EqualFold(name, app) {
+				return true
+			}
+		}
+		return false
+	}
+	if contains(f.Deny) {
+		return false
+	}
+	return len(f.Allow) == 0 || contains(f.Allow)
+}
+
+// Listener receives the notifications sent on a bus.
+type Listener struct {
+	conn          *dbus.Conn
+	notifications chan Notification
+	done          chan struct{}
+}
+
+// Listen starts monitoring a bus for notifications. An empty address connects
+// to the session bus.
+func Listen(address string) (*Listener, error) {
+	var conn *dbus.Conn
+	var err error
+	if address == "" {
+		conn, err = dbus.ConnectSessionBus()
+	} else {
+		conn, err = dbus.Connect(address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to bus: %w", err)
+	}
+
+	rules := []string{
+		fmt.Sprintf("type='method_call',interface='%s',member='Notify',path='%s'", notificationsInterface, notificationsPath),
+	}
+	call := conn.BusObject().Call("org.freedesktop.DBus.Monitoring.BecomeMonitor", 0, rules, uint32(0))
+	if call.Err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed monitoring bus: %w", call.Err)
+	}
+
+	// messages that arrive while the channel is full are dropped
+	messages := make(chan *dbus.Message, 16)
+	conn.Eavesdrop(messages)
+
+	l := &Listener{
+		conn:          conn,
+		notifications: make(chan Notification),
+		done:          make(chan struct{}),
+	}
+	go func() {
+		defer close(l.notifications)
+		// closed when the connection is closed
+		for msg := range messages {
+			n, ok := Parse(msg)
+			if !ok {
+				continue
+			}
+			select {
+			case l.notifications <- n:
+			case <-l.done:
+				return
+			}
+		}
+	}()
+	return l, nil
+}
+
+// Notifications returns the channel that notifications are sent to. It's
+// closed when the listener is closed or the connection to the bus is lost.
+func (l *Listener) Notifications() <-chan Notification {
+	return l.notifications
+}
+
+// Close stops listening and disconnects from the bus.
+func (l *Listener) Close() error {
+	close(l.done)
+	return l.conn.Close()
+}
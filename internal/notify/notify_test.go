@@ -0,0 +1,160 @@
+package notify_test
+
+import (
+	"bufio"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/notify"
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startBus starts a private bus and returns its address. The test is skipped
+// if dbus-daemon isn't installed.
+func startBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+
+	tmpdir := t.TempDir()
+	cfgPath := filepath.Join(tmpdir, "bus.conf")
+	assert.NoError(t, os.WriteFile(cfgPath, []byte(strings.ReplaceAll(busConfig, "%s", tmpdir)), 0o600))
+
+	cmd := exec.Command(daemon, "--config-file="+cfgPath, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	assert.NoError(t, err)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed starting dbus-daemon: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed reading bus address: %s", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// sendNotification calls the Notify method on a bus, as a program showing a
+// notification would.
+func sendNotification(t *testing.T, address, app, summary, body string) {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	obj := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.Call("org.freedesktop.Notifications.Notify", dbus.FlagNoReplyExpected,
+		app, uint32(0), "", summary, body, []string{}, map[string]dbus.Variant{}, int32(-1))
+	assert.NoError(t, call.Err)
+}
+
+func TestListener(t *testing.T) {
+	assert := assert.New(t)
+	address := startBus(t)
+
+	// a notification server for the calls to go to
+	server, err := dbus.Connect(address)
+	assert.NoError(err)
+	defer server.Close()
+	reply, err := server.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
+	assert.NoError(err)
+	assert.Equal(dbus.RequestNameReplyPrimaryOwner, reply)
+
+	listener, err := notify.Listen(address)
+	assert.NoError(err)
+
+	sendNotification(t, address, "build", "Build finished", "All tests passed")
+	select {
+	case n := <-listener.Notifications():
+		assert.Equal(notify.Notification{App: "build", Summary: "Build finished", Body: "All tests passed"}, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+	}
+
+	assert.NoError(listener.Close())
+	_, ok := <-listener.Notifications()
+	assert.False(ok)
+}
+
+func TestListenError(t *testing.T) {
+	_, err := notify.Listen("unix:path=" + filepath.Join(t.TempDir(), "none"))
+	assert.ErrorContains(t, err, "failed connecting to bus: ")
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := &dbus.Message{
+		Type: dbus.TypeMethodCall,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldInterface: dbus.MakeVariant("org.freedesktop.Notifications"),
+			dbus.FieldMember:    dbus.MakeVariant("Notify"),
+		},
+		Body: []interface{}{"chat", uint32(0), "", "New message", "hi", []string{}, map[string]dbus.Variant{}, int32(-1)},
+	}
+	n, ok := notify.Parse(msg)
+	assert.True(ok)
+	assert.Equal(notify.Notification{App: "chat", Summary: "New message", Body: "hi"}, n)
+
+	msg.Headers[dbus.FieldMember] = dbus.MakeVariant("CloseNotification")
+	_, ok = notify.Parse(msg)
+	assert.False(ok)
+
+	msg.Headers[dbus.FieldMember] = dbus.MakeVariant("Notify")
+	msg.Body = []interface{}{"chat"}
+	_, ok = notify.Parse(msg)
+	assert.False(ok)
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	all := notify.Filter{}
+	assert.True(all.Allows("anything"))
+
+	deny := notify.Filter{Deny: []string{"Spotify"}}
+	assert.False(deny.Allows("spotify"))
+	assert.True(deny.Allows("discord"))
+
+	allow := notify.Filter{Allow: []string{"discord", "build"}, Deny: []string{"build"}}
+	assert.True(allow.Allows("Discord"))
+	assert.False(allow.Allows("build"))
+	assert.False(allow.Allows("spotify"))
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	img := notify.Notification{App: "chat", Summary: "New message", Body: "one\n\ntwo"}.Render()
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+	// the header is inverted
+	assert.Equal(uint8(0), img.GrayAt(device.LCDWidth-1, 0).Y)
+	assert.Equal(uint8(0xff), img.GrayAt(device.LCDWidth-1, device.LCDHeight-1).Y)
+}
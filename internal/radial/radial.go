@@ -0,0 +1,180 @@
+// Package radial implements radial selection menus: while a trigger key is
+// held, the direction of the thumb stick selects one of the menu's items,
+// arranged in a circle, and releasing the trigger fires the selected item.
+package radial
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Distance from the centre that the stick must be moved before an item
+	// is selected. Releasing the trigger with the stick inside the deadzone
+	// cancels the menu.
+	deadzone = 48
+
+	// Radius of the menu circle drawn on the LCD.
+	radius = 20
+)
+
+// Item is a single menu entry.
+type Item struct {
+	// Label shown on the LCD when the item is selected.
+	Label string
+
+	// Keyboard keys pressed together when the item fires.
+	Keys []int
+}
+
+// Menu is a set of items arranged in a circle, opened with a trigger key. The
+// first item is at the top and the rest follow clockwise.
+type Menu struct {
+	Trigger device.KeyBit
+	Items   []Item
+}
+
+// Selection returns the index of the item selected by the given stick
+// position, or -1 if the stick is in the deadzone.
+func (m *Menu) Selection(x, y uint8) int {
+	if len(m.Items) == 0 {
+		return -1
+	}
+	dx := float64(x) - 127
+	dy := float64(y) - 127
+	if math.Hypot(dx, dy) < deadzone {
+		return -1
+	}
+
+	sector := 2 * math.Pi / float64(len(m.Items))
+	// angle measured clockwise from the top (the stick's y axis grows
+	// downwards)
+	angle := math.Atan2(dx, -dy)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return int(math.Floor((angle+sector/2)/sector)) % len(m.Items)
+}
+
+// Render draws the menu with the given item selected as an image for the
+// LCD. The circle is drawn on the left with the selected sector filled in and
+// the label of the selected item is written next to it.
+func (m *Menu) Render(selected int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	cx, cy := radius+1, device.LCDHeight/2
+	nItems := len(m.Items)
+	sector := 2 * math.Pi / float64(nItems)
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			dx, dy := float64(x-cx), float64(y-cy)
+			dist := math.Hypot(dx, dy)
+			if dist > radius+0.5 {
+				continue
+			}
+			if dist > radius-0.5 {
+				// outline
+				img.SetGray(x, y, color.Gray{})
+				continue
+			}
+
+			angle := math.Atan2(dx, -dy)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			pos := (angle + sector/2) / sector
+			idx := int(math.Floor(pos)) % nItems
+			if idx == selected && dist > 3 {
+				img.SetGray(x, y, color.Gray{})
+				continue
+			}
+
+			// spokes between sectors
+			if nItems > 1 {
+				_, frac := math.Modf(pos)
+				if arc := math.Min(frac, 1-frac) * sector * dist; arc < 0.5 {
+					img.SetGray(x, y, color.Gray{})
+				}
+			}
+		}
+	}
+
+	label := "(cancel)"
+	if selected >= 0 && selected < nItems {
+		label = m.Items[selected].Label
+	}
+	face := lcdtext.Builtin
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(2*radius+8, cy+face.Ascent/2),
+	}
+	drawer.DrawString(label)
+	return img
+}
+
+// Tracker follows the state of a set of menus across device inputs.
+type Tracker struct {
+	menus []*Menu
+
+	// currently open menu (nil if none is open) and its selected item
+	active   *Menu
+	selected int
+}
+
+// NewTracker returns a [Tracker] for the given menus.
+func NewTracker(menus []*Menu) *Tracker {
+	return &Tracker{
+		menus:    menus,
+		selected: -1,
+	}
+}
+
+// Update processes a device input and returns the resulting state change:
+// changed is true if a menu was opened or closed or its selection changed, and
+// fired is the item selected when the trigger was released (nil if the menu
+// was cancelled or no menu was closed).
+func (t *Tracker) Update(input uint64) (changed bool, fired *Item) {
+	if t.active == nil {
+		for _, menu := range t.menus {
+			if menu.Trigger.Uint64()&input != 0 {
+				t.active = menu
+				t.selected = menu.Selection(device.StickPosition(input))
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if t.active.Trigger.Uint64()&input == 0 {
+		// trigger released
+		if t.selected >= 0 {
+			fired = &t.active.Items[t.selected]
+		}
+		t.active = nil
+		t.selected = -1
+		return true, fired
+	}
+
+	selected := t.active.Selection(device.StickPosition(input))
+	if selected == t.selected {
+		return false, nil
+	}
+	t.selected = selected
+	return true, nil
+}
+
+// Active returns the open menu and its selected item, or nil if no menu is
+// open.
+func (t *Tracker) Active() (*Menu, int) {
+	return t.active, t.selected
+}
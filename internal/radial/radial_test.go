@@ -0,0 +1,117 @@
+package radial_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/radial"
+	"github.com/stretchr/testify/assert"
+)
+
+func stickInput(x, y uint8) uint64 {
+	return uint64(x)<<8 | uint64(y)<<16
+}
+
+func testMenu(nItems int) *radial.Menu {
+	menu := &radial.Menu{Trigger: device.G22}
+	for idx := range nItems {
+		menu.Items = append(menu.Items, radial.Item{Label: string(rune('A' + idx)), Keys: []int{idx + 1}})
+	}
+	return menu
+}
+
+func TestSelection(t *testing.T) {
+	type testCase struct {
+		nItems   int
+		x, y     uint8
+		expected int
+	}
+
+	testCases := map[string]testCase{
+		"centre":           {nItems: 4, x: 127, y: 127, expected: -1},
+		"resting-offset":   {nItems: 4, x: 120, y: 112, expected: -1},
+		"4-up":             {nItems: 4, x: 127, y: 0, expected: 0},
+		"4-right":          {nItems: 4, x: 255, y: 127, expected: 1},
+		"4-down":           {nItems: 4, x: 127, y: 255, expected: 2},
+		"4-left":           {nItems: 4, x: 0, y: 127, expected: 3},
+		"4-up-slight-left": {nItems: 4, x: 100, y: 0, expected: 0},
+		"8-up-right":       {nItems: 8, x: 255, y: 0, expected: 1},
+		"8-down-left":      {nItems: 8, x: 0, y: 255, expected: 5},
+		"8-up-left":        {nItems: 8, x: 0, y: 0, expected: 7},
+		"1-anywhere":       {nItems: 1, x: 0, y: 200, expected: 0},
+		"empty":            {nItems: 0, x: 0, y: 0, expected: -1},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			menu := testMenu(tc.nItems)
+			assert.Equal(t, tc.expected, menu.Selection(tc.x, tc.y))
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	assert := assert.New(t)
+
+	menu := testMenu(4)
+	tracker := radial.NewTracker([]*radial.Menu{menu})
+	trigger := device.G22.Uint64()
+
+	changed, fired := tracker.Update(stickInput(127, 127))
+	assert.False(changed)
+	assert.Nil(fired)
+	active, _ := tracker.Active()
+	assert.Nil(active)
+
+	// open the menu with the stick centred
+	changed, fired = tracker.Update(trigger | stickInput(127, 127))
+	assert.True(changed)
+	assert.Nil(fired)
+	active, selected := tracker.Active()
+	assert.Equal(menu, active)
+	assert.Equal(-1, selected)
+
+	// select an item
+	changed, _ = tracker.Update(trigger | stickInput(255, 127))
+	assert.True(changed)
+	_, selected = tracker.Active()
+	assert.Equal(1, selected)
+
+	// move within the same sector
+	changed, _ = tracker.Update(trigger | stickInput(250, 140))
+	assert.False(changed)
+
+	// release the trigger
+	changed, fired = tracker.Update(stickInput(255, 127))
+	assert.True(changed)
+	assert.Equal(&menu.Items[1], fired)
+	active, _ = tracker.Active()
+	assert.Nil(active)
+
+	// open and cancel
+	tracker.Update(trigger | stickInput(0, 127))
+	tracker.Update(trigger | stickInput(127, 127))
+	changed, fired = tracker.Update(stickInput(127, 127))
+	assert.True(changed)
+	assert.Nil(fired)
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	menu := testMenu(4)
+	img := menu.Render(0)
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+
+	isBlack := func(img image.Image, x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return r+g+b == 0
+	}
+
+	// a point inside the top sector is filled when it's selected and empty
+	// otherwise
+	assert.True(isBlack(img, 21, 10))
+	assert.False(isBlack(menu.Render(2), 21, 10))
+	assert.True(isBlack(menu.Render(2), 21, 32))
+}
@@ -0,0 +1,156 @@
+// Package stick provides processing of the thumb stick position before it's
+// mapped to any output.
+package stick
+
+import (
+	"math"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+)
+
+const (
+	// Nominal centre of each stick axis.
+	centre = 127
+
+	// Samples within this distance of the estimated resting position are
+	// considered idle and are used for recentering.
+	idleRadius = 12
+
+	// The first sample is taken as the initial resting position if it's within
+	// this distance of the nominal centre, since the stick is most likely not
+	// being touched when the driver starts.
+	initialRadius = 32
+
+	// Time constant for tracking the resting position. Recentering should be
+	// slow enough to not interfere with deliberate small movements.
+	recenterTimeConstant = 2 * time.Second
+
+	// Largest time step used for smoothing and recentering. Longer gaps
+	// between samples (e.g. the stick not being touched) are clamped so that
+	// a single sample after a long pause doesn't move the estimates by too
+	// much.
+	maxStep = 100 * time.Millisecond
+)
+
+// Settings configures a [Filter]. The zero value disables all filtering.
+type Settings struct {
+	// Recenter enables tracking the resting position of the stick while it's
+	// idle and compensating for any offset from the nominal centre.
+	Recenter bool
+
+	// Smoothing is the time constant of the low-pass filter applied to the
+	// stick position. Zero disables smoothing.
+	Smoothing time.Duration
+
+	// Jitter is the largest change in position (on either axis) that is
+	// ignored. Positions within this distance of the centre snap to the
+	// centre.
+	Jitter uint8
+}
+
+// Enabled returns true if any filtering is configured.
+func (s Settings) Enabled() bool {
+	return s.Recenter || s.Smoothing > 0 || s.Jitter > 0
+}
+
+// Continuous returns true if the filter output changes over time even if the
+// input doesn't, meaning it should be updated periodically and not only when
+// the device reports a change.
+func (s Settings) Continuous() bool {
+	return s.Recenter || s.Smoothing > 0
+}
+
+// Filter processes stick positions decoded from device input.
+type Filter struct {
+	settings Settings
+
+	// estimated resting position
+	rest [2]float64
+
+	// low-pass filtered position
+	smoothed [2]float64
+
+	// last output position
+	out [2]uint8
+
+	// time of the last sample (zero before the first sample)
+	last time.Time
+}
+
+// NewFilter returns a [Filter] with the given settings.
+func NewFilter(settings Settings) *Filter {
+	return &Filter{
+		settings: settings,
+		rest:     [2]float64{centre, centre},
+		smoothed: [2]float64{centre, centre},
+		out:      [2]uint8{centre, centre},
+	}
+}
+
+// Apply filters the stick position in the given device input, sampled at the
+// given time, and returns the input with the stick position replaced by the
+// filtered position. Key states are not modified.
+func (f *Filter) Apply(input uint64, now time.Time) uint64 {
+	if !f.settings.Enabled() {
+		return input
+	}
+
+	first := f.last.IsZero()
+	var step time.Duration
+	if !first {
+		step = min(now.Sub(f.last), maxStep)
+	}
+	f.last = now
+
+	x, y := device.StickPosition(input)
+	for axis, raw := range [2]uint8{x, y} {
+		pos := float64(raw)
+
+		if f.settings.Recenter {
+			if first && math.Abs(pos-centre) <= initialRadius {
+				f.rest[axis] = pos
+			} else if math.Abs(pos-f.rest[axis]) <= idleRadius {
+				f.rest[axis] += alpha(step, recenterTimeConstant) * (pos - f.rest[axis])
+			}
+			pos -= f.rest[axis] - centre
+		}
+
+		if f.settings.Smoothing > 0 {
+			if first {
+				f.smoothed[axis] = pos
+			} else {
+				f.smoothed[axis] += alpha(step, f.settings.Smoothing) * (pos - f.smoothed[axis])
+			}
+			pos = f.smoothed[axis]
+		}
+
+		out := uint8(math.Round(math.Max(0, math.Min(255, pos))))
+		if jitter := int(f.settings.Jitter); jitter > 0 {
+			if absDiff(out, centre) <= jitter {
+				out = centre
+			} else if absDiff(out, f.out[axis]) <= jitter {
+				out = f.out[axis]
+			}
+		}
+		f.out[axis] = out
+	}
+
+	return input&^(device.XMask|device.YMask) | uint64(f.out[0])<<8 | uint64(f.out[1])<<16
+}
+
+// alpha returns the weight of a new sample for an exponential moving average
+// with the given time constant after the given time step.
+func alpha(step, timeConstant time.Duration) float64 {
+	if step <= 0 {
+		return 0
+	}
+	return 1 - math.Exp(-float64(step)/float64(timeConstant))
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
@@ -0,0 +1,137 @@
+package stick_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/stick"
+	"github.com/stretchr/testify/assert"
+)
+
+func stickInput(x, y uint8) uint64 {
+	return uint64(x)<<8 | uint64(y)<<16
+}
+
+func TestFilterDisabled(t *testing.T) {
+	filter := stick.NewFilter(stick.Settings{})
+	input := stickInput(3, 250) | device.G1.Uint64()
+	assert.Equal(t, input, filter.Apply(input, time.Now()))
+}
+
+func TestFilterKeepsKeys(t *testing.T) {
+	filter := stick.NewFilter(stick.Settings{Recenter: true, Smoothing: time.Second, Jitter: 3})
+	keys := device.G1.Uint64() | device.BD.Uint64()
+	out := filter.Apply(stickInput(130, 120)|keys, time.Now())
+	assert.Equal(t, keys, out&^(device.XMask|device.YMask))
+}
+
+func TestFilterJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := stick.NewFilter(stick.Settings{Jitter: 3})
+	now := time.Now()
+
+	type step struct {
+		x, y       uint8
+		expX, expY uint8
+	}
+	steps := []step{
+		{x: 129, y: 124, expX: 127, expY: 127}, // near centre snaps to centre
+		{x: 200, y: 60, expX: 200, expY: 60},
+		{x: 202, y: 57, expX: 200, expY: 60}, // within jitter of last output
+		{x: 198, y: 55, expX: 200, expY: 55},
+		{x: 196, y: 53, expX: 196, expY: 55},
+		{x: 126, y: 130, expX: 127, expY: 127},
+	}
+	for idx, s := range steps {
+		x, y := device.StickPosition(filter.Apply(stickInput(s.x, s.y), now))
+		assert.Equal(s.expX, x, "step %d", idx)
+		assert.Equal(s.expY, y, "step %d", idx)
+	}
+}
+
+func TestFilterRecenter(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := stick.NewFilter(stick.Settings{Recenter: true})
+	now := time.Now()
+
+	// resting position off centre (as recorded from a real device) is taken
+	// as the initial resting position
+	x, y := device.StickPosition(filter.Apply(stickInput(120, 112), now))
+	assert.Equal(uint8(127), x)
+	assert.Equal(uint8(127), y)
+
+	// deflection is relative to the resting position
+	now = now.Add(20 * time.Millisecond)
+	x, y = device.StickPosition(filter.Apply(stickInput(220, 12), now))
+	assert.Equal(uint8(227), x)
+	assert.Equal(uint8(27), y)
+
+	// the resting position drifts slowly and is tracked while idle
+	for range 500 {
+		now = now.Add(20 * time.Millisecond)
+		x, y = device.StickPosition(filter.Apply(stickInput(124, 118), now))
+	}
+	assert.Equal(uint8(127), x)
+	assert.Equal(uint8(127), y)
+}
+
+func TestFilterRecenterIgnoresDeflection(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := stick.NewFilter(stick.Settings{Recenter: true})
+	now := time.Now()
+	filter.Apply(stickInput(127, 127), now)
+
+	// holding the stick deflected doesn't move the resting position
+	for range 500 {
+		now = now.Add(20 * time.Millisecond)
+		filter.Apply(stickInput(255, 0), now)
+	}
+	now = now.Add(20 * time.Millisecond)
+	x, y := device.StickPosition(filter.Apply(stickInput(127, 127), now))
+	assert.Equal(uint8(127), x)
+	assert.Equal(uint8(127), y)
+}
+
+func TestFilterSmoothing(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := stick.NewFilter(stick.Settings{Smoothing: 100 * time.Millisecond})
+	now := time.Now()
+	filter.Apply(stickInput(127, 127), now)
+
+	// a sudden jump is followed gradually
+	now = now.Add(20 * time.Millisecond)
+	x, _ := device.StickPosition(filter.Apply(stickInput(255, 127), now))
+	assert.Greater(x, uint8(127))
+	assert.Less(x, uint8(255))
+
+	prev := x
+	for range 10 {
+		now = now.Add(20 * time.Millisecond)
+		x, _ = device.StickPosition(filter.Apply(stickInput(255, 127), now))
+		assert.GreaterOrEqual(x, prev)
+		prev = x
+	}
+
+	// and converges when the input stays put
+	for range 100 {
+		now = now.Add(20 * time.Millisecond)
+		x, _ = device.StickPosition(filter.Apply(stickInput(255, 127), now))
+	}
+	assert.Equal(uint8(255), x)
+}
+
+func TestSettings(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(stick.Settings{}.Enabled())
+	assert.False(stick.Settings{}.Continuous())
+	assert.True(stick.Settings{Jitter: 2}.Enabled())
+	assert.False(stick.Settings{Jitter: 2}.Continuous())
+	assert.True(stick.Settings{Recenter: true}.Continuous())
+	assert.True(stick.Settings{Smoothing: time.Millisecond}.Continuous())
+}
@@ -0,0 +1,268 @@
+package sysmon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Rate is the throughput, in bytes per second, of data received and sent by
+// a network interface or read and written by a disk.
+type Rate struct {
+	In  float64
+	Out float64
+}
+
+func (r Rate) add(other Rate) Rate {
+	return Rate{In: r.In + other.In, Out: r.Out + other.Out}
+}
+
+// Sample holds the system statistics at a point in time. Usage and throughput
+// are averages since the previous sample. Statistics that couldn't be read are
+// nil.
+type Sample struct {
+	Time time.Time
+
+	// CPU usage, from 0 to 1, of all CPUs combined, followed by the usage of
+	// each CPU.
+	CPU []float64
+
+	Memory *Memory
+
+	// 1, 5, and 15 minute load averages.
+	Load *[3]float64
+
+	// Throughput of each network interface and each block device.
+	Network map[string]Rate
+	Disks   map[string]Rate
+
+	Temperatures []Temperature
+	Batteries    []Battery
+
+	// block devices that are whole disks rather than partitions
+	wholeDisks map[string]bool
+}
+
+// NetworkRate returns the throughput of a network interface. An empty name
+// returns the combined throughput of all interfaces except loopback.
+func (s Sample) NetworkRate(iface string) (Rate, bool) {
+	if s.Network == nil {
+		return Rate{}, false
+	}
+	if iface != "" {
+		rate, ok := s.Network[iface]
+		return rate, ok
+	}
+	var total Rate
+	for name, rate := range s.Network {
+		if name != "lo" {
+			total = total.add(rate)
+		}
+	}
+	return total, true
+}
+
+// DiskRate returns the throughput of a block device. An empty name returns the
+// combined throughput of all whole disks.
+func (s Sample) DiskRate(disk string) (Rate, bool) {
+	if s.Disks == nil {
+		return Rate{}, false
+	}
+	if disk != "" {
+		rate, ok := s.Disks[disk]
+		return rate, ok
+	}
+	var total Rate
+	for name, rate := range s.Disks {
+		if s.wholeDisks[name] {
+			total = total.add(rate)
+		}
+	}
+	return total, true
+}
+
+// Temperature returns the reading of a temperature sensor, identified by its
+// chip name or by "chip/label". An empty name returns the first sensor.
+func (s Sample) Temperature(sensor string) (Temperature, bool) {
+	for _, temp := range s.Temperatures {
+		if sensor == "" || sensor == temp.Chip || sensor == temp.Name() {
+			return temp, true
+		}
+	}
+	return Temperature{}, false
+}
+
+// Battery returns the charge of a battery, identified by its name. An empty
+// name returns the first battery.
+func (s Sample) Battery(name string) (Battery, bool) {
+	for _, battery := range s.Batteries {
+		if name == "" || name == battery.Name {
+			return battery, true
+		}
+	}
+	return Battery{}, false
+}
+
+// Metric is a statistic that can be read from a sample as a single number.
+type Metric int
+
+const (
+	// CPU usage of all CPUs combined, in percent.
+	MetricCPU Metric = iota
+	// Reading of a temperature sensor, in degrees Celsius.
+	MetricTemperature
+	// Charge of a battery, in percent.
+	MetricBattery
+)
+
+var metricNames = map[Metric]string{
+	MetricCPU:         "cpu",
+	MetricTemperature: "temperature",
+	MetricBattery:     "battery",
+}
+
+func (m Metric) String() string {
+	if name, ok := metricNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Metric(%d)", int(m))
+}
+
+// ParseMetric returns the metric with a name: cpu, temperature, or battery.
+func ParseMetric(name string) (Metric, error) {
+	for metric, metricName := range metricNames {
+		if metricName == name {
+			return metric, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown metric: %q", name)
+}
+
+// Value returns the value of a metric. The source is the temperature sensor
+// or battery (empty for the first one), and is ignored for the CPU usage. It
+// returns false if the metric isn't in the sample.
+func (s Sample) Value(metric Metric, source string) (float64, bool) {
+	switch metric {
+	case MetricCPU:
+		if len(s.CPU) == 0 {
+			return 0, false
+		}
+		return s.CPU[0] * 100, true
+	case MetricTemperature:
+		temp, ok := s.Temperature(source)
+		return temp.Celsius, ok
+	case MetricBattery:
+		battery, ok := s.Battery(source)
+		return battery.Percent, ok
+	}
+	return 0, false
+}
+
+// Sampler takes samples of the system statistics, keeping the counters of the
+// previous sample to compute usage and throughput.
+type Sampler struct {
+	reader Reader
+
+	prevTime    time.Time
+	prevCPU     []CPUTimes
+	prevNetwork map[string]Transfer
+	prevDisks   map[string]Transfer
+}
+
+// NewSampler returns a [Sampler] reading from the /proc and /sys trees under
+// root (empty for "/").
+func NewSampler(root string) *Sampler {
+	return &Sampler{reader: Reader{Root: root}}
+}
+
+// Sample reads the system statistics. The first sample reports the CPU usage
+// since boot and zero throughput. Statistics that can't be read are left nil
+// in the sample and their errors are returned together.
+func (s *Sampler) Sample(now time.Time) (Sample, error) {
+	sample := Sample{Time: now}
+	elapsed := now.Sub(s.prevTime).Seconds()
+	if s.prevTime.IsZero() {
+		elapsed = 0
+	}
+
+	var errs []error
+	cpus, err := s.reader.CPU()
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		sample.CPU = make([]float64, len(cpus))
+		for idx, times := range cpus {
+			if len(s.prevCPU) == len(cpus) {
+				prev := s.prevCPU[idx]
+				if times.Busy >= prev.Busy && times.Total >= prev.Total {
+					times = CPUTimes{Busy: times.Busy - prev.Busy, Total: times.Total - prev.Total}
+				}
+			}
+			if times.Total > 0 {
+				sample.CPU[idx] = float64(times.Busy) / float64(times.Total)
+			}
+		}
+	}
+	s.prevCPU = cpus
+
+	if mem, err := s.reader.Memory(); err != nil {
+		errs = append(errs, err)
+	} else {
+		sample.Memory = &mem
+	}
+
+	if load, err := s.reader.Load(); err != nil {
+		errs = append(errs, err)
+	} else {
+		sample.Load = &load
+	}
+
+	network, err := s.reader.Network()
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		sample.Network = rates(s.prevNetwork, network, elapsed)
+	}
+	s.prevNetwork = network
+
+	disks, err := s.reader.Disks()
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		sample.Disks = rates(s.prevDisks, disks, elapsed)
+		sample.wholeDisks = make(map[string]bool, len(disks))
+		for name := range disks {
+			sample.wholeDisks[name] = s.reader.IsWholeDisk(name)
+		}
+	}
+	s.prevDisks = disks
+
+	if sample.Temperatures, err = s.reader.Temperatures(); err != nil {
+		errs = append(errs, err)
+	}
+	if sample.Batteries, err = s.reader.Batteries(); err != nil {
+		errs = append(errs, err)
+	}
+
+	s.prevTime = now
+	return sample, errors.Join(errs...)
+}
+
+// rates returns the throughput of each counter from its previous value over
+// the elapsed seconds. Counters without a previous value, or that were reset,
+// have zero throughput.
+func rates(prev, cur map[string]Transfer, elapsed float64) map[string]Rate {
+	result := make(map[string]Rate, len(cur))
+	for name, counters := range cur {
+		prevCounters, ok := prev[name]
+		if !ok || elapsed <= 0 || counters.In < prevCounters.In || counters.Out < prevCounters.Out {
+			result[name] = Rate{}
+			continue
+		}
+		result[name] = Rate{
+			In:  float64(counters.In-prevCounters.In) / elapsed,
+			Out: float64(counters.Out-prevCounters.Out) / elapsed,
+		}
+	}
+	return result
+}
@@ -0,0 +1,340 @@
+// Package sysmon reads system statistics, like CPU usage, memory, network
+// throughput, temperatures, and battery levels, from /proc and /sys.
+package sysmon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Size of the sectors counted in /proc/diskstats, regardless of the sector
+// size of the device.
+const diskSectorSize = 512
+
+// Reader reads statistics from the /proc and /sys trees under a root
+// directory.
+type Reader struct {
+	// Root of the file system that /proc and /sys are read from (empty for
+	// "/"). Overriding it allows reading from a fixture tree.
+	Root string
+}
+
+func (r Reader) path(elem ...string) string {
+	root := r.Root
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// CPUTimes are the times, in clock ticks, that a CPU spent busy and in total.
+type CPUTimes struct {
+	Busy  uint64
+	Total uint64
+}
+
+// CPU returns the times of all CPUs combined, followed by the times of each
+// CPU, from /proc/stat.
+func (r Reader) CPU() ([]CPUTimes, error) {
+	file, err := os.Open(r.path("proc", "stat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cpus []CPUTimes
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("invalid cpu line in /proc/stat: %q", scanner.Text())
+		}
+
+		// user nice system idle iowait irq softirq steal; guest times are
+		// already counted in user and nice
+		var times CPUTimes
+		for idx, field := range fields[1:min(len(fields), 9)] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu line in /proc/stat: %q: %w", scanner.Text(), err)
+			}
+			times.Total += value
+			if idx != 3 && idx != 4 {
+				times.Busy += value
+			}
+		}
+		cpus = append(cpus, times)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cpus) == 0 {
+		return nil, fmt.Errorf("no cpu lines in /proc/stat")
+	}
+	return cpus, nil
+}
+
+// Memory is the amount of memory, in bytes, in total and available for
+// starting new programs.
+type Memory struct {
+	Total     uint64
+	Available uint64
+}
+
+// Used returns the amount of memory that is not available.
+func (m Memory) Used() uint64 {
+	if m.Available > m.Total {
+		return 0
+	}
+	return m.Total - m.Available
+}
+
+// Memory returns the memory usage from /proc/meminfo.
+func (r Reader) Memory() (Memory, error) {
+	file, err := os.Open(r.path("proc", "meminfo"))
+	if err != nil {
+		return Memory{}, err
+	}
+	defer file.Close()
+
+	var mem Memory
+	var haveTotal, haveAvailable bool
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var dest *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			dest, haveTotal = &mem.Total, true
+		case "MemAvailable:":
+			dest, haveAvailable = &mem.Available, true
+		default:
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return Memory{}, fmt.Errorf("invalid line in /proc/meminfo: %q: %w", scanner.Text(), err)
+		}
+		// values are in KiB
+		*dest = value * 1024
+	}
+	if err := scanner.Err(); err != nil {
+		return Memory{}, err
+	}
+	if !haveTotal || !haveAvailable {
+		return Memory{}, fmt.Errorf("MemTotal or MemAvailable missing from /proc/meminfo")
+	}
+	return mem, nil
+}
+
+// Load returns the 1, 5, and 15 minute load averages from /proc/loadavg.
+func (r Reader) Load() ([3]float64, error) {
+	data, err := os.ReadFile(r.path("proc", "loadavg"))
+	if err != nil {
+		return [3]float64{}, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return [3]float64{}, fmt.Errorf("invalid /proc/loadavg: %q", data)
+	}
+	var load [3]float64
+	for idx := range load {
+		load[idx], err = strconv.ParseFloat(fields[idx], 64)
+		if err != nil {
+			return [3]float64{}, fmt.Errorf("invalid /proc/loadavg: %q: %w", data, err)
+		}
+	}
+	return load, nil
+}
+
+// Transfer is an amount of data, in bytes, received and sent by a network
+// interface or read and written by a disk.
+type Transfer struct {
+	In  uint64
+	Out uint64
+}
+
+// Network returns the bytes received and sent by each network interface from
+// /proc/net/dev.
+func (r Reader) Network() (map[string]Transfer, error) {
+	file, err := os.Open(r.path("proc", "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ifaces := make(map[string]Transfer)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			// header
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("invalid line in /proc/net/dev: %q", scanner.Text())
+		}
+		rx, rxErr := strconv.ParseUint(fields[0], 10, 64)
+		tx, txErr := strconv.ParseUint(fields[8], 10, 64)
+		if err := errors.Join(rxErr, txErr); err != nil {
+			return nil, fmt.Errorf("invalid line in /proc/net/dev: %q: %w", scanner.Text(), err)
+		}
+		ifaces[strings.TrimSpace(name)] = Transfer{In: rx, Out: tx}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// Disks returns the bytes read and written by each block device from
+// /proc/diskstats, including partitions.
+func (r Reader) Disks() (map[string]Transfer, error) {
+	file, err := os.Open(r.path("proc", "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	disks := make(map[string]Transfer)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		read, readErr := strconv.ParseUint(fields[5], 10, 64)
+		written, writeErr := strconv.ParseUint(fields[9], 10, 64)
+		if err := errors.Join(readErr, writeErr); err != nil {
+			return nil, fmt.Errorf("invalid line in /proc/diskstats: %q: %w", scanner.Text(), err)
+		}
+		disks[fields[2]] = Transfer{In: read * diskSectorSize, Out: written * diskSectorSize}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+// IsWholeDisk returns true if the block device with the given name is a whole
+// disk rather than a partition, according to /sys/block. Loop and RAM devices
+// aren't counted as disks.
+func (r Reader) IsWholeDisk(name string) bool {
+	if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+		return false
+	}
+	_, err := os.Stat(r.path("sys", "block", name))
+	return err == nil
+}
+
+// Temperature is a reading of a hwmon temperature sensor.
+type Temperature struct {
+	// Name of the chip the sensor belongs to, like "coretemp".
+	Chip string
+
+	// Label of the sensor, or its input name (like "temp1") if it has no
+	// label.
+	Label string
+
+	Celsius float64
+}
+
+// Name identifies the sensor as "chip/label".
+func (t Temperature) Name() string {
+	return t.Chip + "/" + t.Label
+}
+
+// Temperatures returns the readings of all hwmon temperature sensors from
+// /sys/class/hwmon, sorted by chip and sensor.
+func (r Reader) Temperatures() ([]Temperature, error) {
+	chips, err := filepath.Glob(r.path("sys", "class", "hwmon", "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(chips)
+
+	var temps []Temperature
+	for _, chipDir := range chips {
+		chip := filepath.Base(chipDir)
+		if name, err := os.ReadFile(filepath.Join(chipDir, "name")); err == nil {
+			chip = strings.TrimSpace(string(name))
+		}
+
+		inputs, err := filepath.Glob(filepath.Join(chipDir, "temp*_input"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				// sensors can fail to read when the device is asleep
+				continue
+			}
+			milli, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid temperature in %s: %w", input, err)
+			}
+
+			sensor := strings.TrimSuffix(filepath.Base(input), "_input")
+			label := sensor
+			if data, err := os.ReadFile(filepath.Join(chipDir, sensor+"_label")); err == nil {
+				label = strings.TrimSpace(string(data))
+			}
+			temps = append(temps, Temperature{Chip: chip, Label: label, Celsius: float64(milli) / 1000})
+		}
+	}
+	return temps, nil
+}
+
+// Battery is the charge of a battery from /sys/class/power_supply.
+type Battery struct {
+	// Name of the power supply, like "BAT0".
+	Name string
+
+	// Charge, from 0 to 100.
+	Percent float64
+}
+
+// Batteries returns the charge of all batteries, sorted by name. Power
+// supplies that aren't batteries, like AC adapters, are skipped.
+func (r Reader) Batteries() ([]Battery, error) {
+	supplies, err := filepath.Glob(r.path("sys", "class", "power_supply", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(supplies)
+
+	var batteries []Battery
+	for _, supplyDir := range supplies {
+		kind, err := os.ReadFile(filepath.Join(supplyDir, "type"))
+		if err != nil || strings.TrimSpace(string(kind)) != "Battery" {
+			continue
+		}
+		capacity := filepath.Join(supplyDir, "capacity")
+		data, err := os.ReadFile(capacity)
+		if err != nil {
+			// batteries can be present without reporting their charge
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid battery capacity in %s: %w", capacity, err)
+		}
+		batteries = append(batteries, Battery{Name: filepath.Base(supplyDir), Percent: percent})
+	}
+	return batteries, nil
+}
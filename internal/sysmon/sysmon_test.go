@@ -0,0 +1,212 @@
+package sysmon_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/sysmon"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixtureRoot = "testdata/root"
+
+func TestReader(t *testing.T) {
+	assert := assert.New(t)
+	reader := sysmon.Reader{Root: fixtureRoot}
+
+	cpus, err := reader.CPU()
+	assert.NoError(err)
+	assert.Equal([]sysmon.CPUTimes{
+		{Busy: 5000, Total: 10000},
+		{Busy: 3500, Total: 5000},
+		{Busy: 1500, Total: 5000},
+	}, cpus)
+
+	mem, err := reader.Memory()
+	assert.NoError(err)
+	assert.Equal(sysmon.Memory{Total: 16384000 * 1024, Available: 4096000 * 1024}, mem)
+	assert.Equal(uint64(12288000*1024), mem.Used())
+
+	load, err := reader.Load()
+	assert.NoError(err)
+	assert.Equal([3]float64{0.52, 0.48, 0.40}, load)
+
+	network, err := reader.Network()
+	assert.NoError(err)
+	assert.Equal(map[string]sysmon.Transfer{
+		"lo":    {In: 500000, Out: 500000},
+		"eth0":  {In: 2000000, Out: 1000000},
+		"wlan0": {In: 100000, Out: 50000},
+	}, network)
+
+	disks, err := reader.Disks()
+	assert.NoError(err)
+	assert.Len(disks, 4)
+	assert.Equal(sysmon.Transfer{In: 20000 * 512, Out: 40000 * 512}, disks["nvme0n1"])
+	assert.True(reader.IsWholeDisk("nvme0n1"))
+	assert.True(reader.IsWholeDisk("sda"))
+	assert.False(reader.IsWholeDisk("nvme0n1p1"))
+	assert.False(reader.IsWholeDisk("loop0"))
+
+	temps, err := reader.Temperatures()
+	assert.NoError(err)
+	assert.Equal([]sysmon.Temperature{
+		{Chip: "coretemp", Label: "Package id 0", Celsius: 45},
+		{Chip: "coretemp", Label: "Core 0", Celsius: 43.5},
+		{Chip: "nvme", Label: "temp1", Celsius: 38.85},
+	}, temps)
+
+	// the AC adapter isn't a battery
+	batteries, err := reader.Batteries()
+	assert.NoError(err)
+	assert.Equal([]sysmon.Battery{
+		{Name: "BAT0", Percent: 73},
+		{Name: "BAT1", Percent: 100},
+	}, batteries)
+}
+
+func TestReaderErrors(t *testing.T) {
+	assert := assert.New(t)
+	reader := sysmon.Reader{Root: t.TempDir()}
+
+	_, err := reader.CPU()
+	assert.ErrorIs(err, os.ErrNotExist)
+	_, err = reader.Memory()
+	assert.ErrorIs(err, os.ErrNotExist)
+	_, err = reader.Load()
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// no sensors isn't an error
+	temps, err := reader.Temperatures()
+	assert.NoError(err)
+	assert.Empty(temps)
+	batteries, err := reader.Batteries()
+	assert.NoError(err)
+	assert.Empty(batteries)
+
+	writeFile(t, reader.Root, "sys/class/power_supply/BAT0/type", "Battery\n")
+	writeFile(t, reader.Root, "sys/class/power_supply/BAT0/capacity", "full\n")
+	_, err = reader.Batteries()
+	assert.ErrorContains(err, "invalid battery capacity")
+
+	writeFile(t, reader.Root, "proc/loadavg", "0.1 nope 0.3 1/2 3\n")
+	_, err = reader.Load()
+	assert.ErrorContains(err, "invalid /proc/loadavg")
+
+	writeFile(t, reader.Root, "proc/meminfo", "MemTotal: 1024 kB\n")
+	_, err = reader.Memory()
+	assert.EqualError(err, "MemTotal or MemAvailable missing from /proc/meminfo")
+}
+
+func writeFile(t *testing.T, root, path, data string) {
+	t.Helper()
+	path = filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o770); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o660); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	root := t.TempDir()
+	assert.NoError(os.CopyFS(root, os.DirFS(fixtureRoot)))
+	sampler := sysmon.NewSampler(root)
+
+	start := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)
+	first, err := sampler.Sample(start)
+	assert.NoError(err)
+	assert.Equal(start, first.Time)
+	// usage since boot
+	assert.Equal([]float64{0.5, 0.7, 0.3}, first.CPU)
+	rate, ok := first.NetworkRate("eth0")
+	assert.True(ok)
+	assert.Zero(rate)
+	temp, ok := first.Temperature("")
+	assert.True(ok)
+	assert.Equal("coretemp/Package id 0", temp.Name())
+	temp, ok = first.Temperature("nvme")
+	assert.True(ok)
+	assert.Equal(38.85, temp.Celsius)
+	_, ok = first.Temperature("amdgpu")
+	assert.False(ok)
+	battery, ok := first.Battery("BAT1")
+	assert.True(ok)
+	assert.Equal(100.0, battery.Percent)
+
+	// metrics as single values
+	value, ok := first.Value(sysmon.MetricCPU, "")
+	assert.True(ok)
+	assert.Equal(50.0, value)
+	value, ok = first.Value(sysmon.MetricTemperature, "nvme")
+	assert.True(ok)
+	assert.Equal(38.85, value)
+	value, ok = first.Value(sysmon.MetricBattery, "")
+	assert.True(ok)
+	assert.Equal(73.0, value)
+	_, ok = first.Value(sysmon.MetricBattery, "BAT2")
+	assert.False(ok)
+	_, ok = sysmon.Sample{}.Value(sysmon.MetricCPU, "")
+	assert.False(ok)
+
+	writeFile(t, root, "proc/stat", `cpu  4100 0 1100 4200 1000 0 0 0 0 0
+cpu0 3100 0 600 1000 500 0 0 0 0 0
+cpu1 1000 0 500 3200 500 0 0 0 0 0
+`)
+	writeFile(t, root, "proc/net/dev", `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  900000    1000    0    0    0     0          0         0   900000    1000    0    0    0     0       0          0
+  eth0: 2004000    3000    0    0    0     0          0         0  1001000    2000    0    0    0     0       0          0
+ wlan0:  100200     200    0    0    0     0          0         0    50000     100    0    0    0     0       0          0
+`)
+	writeFile(t, root, "proc/diskstats", ` 259       0 nvme0n1 1000 0 20016 500 2000 0 40000 800 0 1000 1300 0 0 0 0 0 0
+ 259       1 nvme0n1p1 900 0 18016 450 1900 0 38000 750 0 900 1200 0 0 0 0 0 0
+   8       0 sda 100 0 2000 50 200 0 4008 80 0 100 130 0 0 0 0 0 0
+   7       0 loop0 10 0 1000 5 0 0 0 0 0 10 5 0 0 0 0 0 0
+`)
+
+	second, err := sampler.Sample(start.Add(2 * time.Second))
+	assert.NoError(err)
+	assert.Equal([]float64{0.5, 1, 0}, second.CPU)
+
+	rate, ok = second.NetworkRate("eth0")
+	assert.True(ok)
+	assert.Equal(sysmon.Rate{In: 2000, Out: 500}, rate)
+	// all interfaces except loopback
+	rate, ok = second.NetworkRate("")
+	assert.True(ok)
+	assert.Equal(sysmon.Rate{In: 2100, Out: 500}, rate)
+	_, ok = second.NetworkRate("eth1")
+	assert.False(ok)
+
+	rate, ok = second.DiskRate("nvme0n1p1")
+	assert.True(ok)
+	assert.Equal(sysmon.Rate{In: 16 * 512 / 2}, rate)
+	// whole disks only
+	rate, ok = second.DiskRate("")
+	assert.True(ok)
+	assert.Equal(sysmon.Rate{In: 16 * 512 / 2, Out: 8 * 512 / 2}, rate)
+
+	// missing statistics are nil
+	assert.NoError(os.Remove(filepath.Join(root, "proc", "meminfo")))
+	third, err := sampler.Sample(start.Add(3 * time.Second))
+	assert.ErrorIs(err, os.ErrNotExist)
+	assert.Nil(third.Memory)
+	assert.NotNil(third.Load)
+}
+
+func TestParseMetric(t *testing.T) {
+	assert := assert.New(t)
+	for _, metric := range []sysmon.Metric{sysmon.MetricCPU, sysmon.MetricTemperature, sysmon.MetricBattery} {
+		parsed, err := sysmon.ParseMetric(metric.String())
+		assert.NoError(err)
+		assert.Equal(metric, parsed)
+	}
+	_, err := sysmon.ParseMetric("fan")
+	assert.EqualError(err, `unknown metric: "fan"`)
+}
@@ -0,0 +1,302 @@
+// Package timers keeps named stopwatches and countdowns, like ability
+// cooldowns started by key presses, and draws the active ones as an overlay
+// for the LCD.
+package timers
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"slices"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/lcdtext"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Kind is whether a timer counts up or down.
+type Kind int
+
+const (
+	// Stopwatch counts up from zero until it's stopped.
+	Stopwatch Kind = iota
+	// Countdown counts down from its duration and expires at zero.
+	Countdown
+)
+
+var kindNames = map[Kind]string{
+	Stopwatch: "stopwatch",
+	Countdown: "countdown",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// ParseKind returns the kind of timer with the given name.
+func ParseKind(name string) (Kind, error) {
+	for kind, kindName := range kindNames {
+		if kindName == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown timer type: %q", name)
+}
+
+// Timer is a named stopwatch or countdown.
+type Timer struct {
+	Name string
+	Kind Kind
+
+	// Duration of a countdown.
+	Duration time.Duration
+}
+
+// state is a timer and how long it has been running.
+type state struct {
+	Timer
+
+	// cooldowns are removed when they expire
+	cooldown bool
+
+	running bool
+	// time the timer was last started, and the time it ran for before that
+	started time.Time
+	elapsed time.Duration
+}
+
+func (st *state) elapsedAt(now time.Time) time.Duration {
+	if !st.running {
+		return st.elapsed
+	}
+	return st.elapsed + now.Sub(st.started)
+}
+
+// Set is a group of timers, kept in the order they were added.
+type Set struct {
+	timers []*state
+}
+
+// NewSet returns a set of timers, all stopped at zero.
+func NewSet(timers []Timer) *Set {
+	s := &Set{}
+	for _, timer := range timers {
+		s.timers = append(s.timers, &state{Timer: timer})
+	}
+	return s
+}
+
+func (s *Set) find(name string) *state {
+	for _, st := range s.timers {
+		if st.Name == name {
+			return st
+		}
+	}
+	return nil
+}
+
+// Start starts or resumes a timer. It returns false if there is no timer with
+// the name.
+func (s *Set) Start(name string, now time.Time) bool {
+	st := s.find(name)
+	if st == nil {
+		return false
+	}
+	if !st.running {
+		st.running = true
+		st.started = now
+	}
+	return true
+}
+
+// Stop pauses a timer. It returns false if there is no timer with the name.
+func (s *Set) Stop(name string, now time.Time) bool {
+	st := s.find(name)
+	if st == nil {
+		return false
+	}
+	if st.running {
+		st.elapsed = st.elapsedAt(now)
+		st.running = false
+	}
+	return true
+}
+
+// Toggle stops a timer that is running and starts one that isn't. It returns
+// false if there is no timer with the name.
+func (s *Set) Toggle(name string, now time.Time) bool {
+	st := s.find(name)
+	if st == nil {
+		return false
+	}
+	if st.running {
+		return s.Stop(name, now)
+	}
+	return s.Start(name, now)
+}
+
+// Reset stops a timer and sets it back to zero. It returns false if there is
+// no timer with the name.
+func (s *Set) Reset(name string) bool {
+	st := s.find(name)
+	if st == nil {
+		return false
+	}
+	st.running = false
+	st.elapsed = 0
+	return true
+}
+
+// StartCooldown starts a countdown that is removed from the set when it
+// expires. A cooldown that is already running is restarted.
+func (s *Set) StartCooldown(name string, duration time.Duration, now time.Time) {
+	st := s.find(name)
+	if st == nil {
+		st = &state{Timer: Timer{Name: name, Kind: Countdown}, cooldown: true}
+		s.timers = append(s.timers, st)
+	}
+	st.Duration = duration
+	st.running = true
+	st.started = now
+	st.elapsed = 0
+}
+
+// Expire ends the countdowns that reached zero and returns their names.
+// Countdowns are reset and cooldowns are removed.
+func (s *Set) Expire(now time.Time) []string {
+	var expired []string
+	s.timers = slices.DeleteFunc(s.timers, func(st *state) bool {
+		if st.Kind != Countdown || !st.running || st.elapsedAt(now) < st.Duration {
+			return false
+		}
+		expired = append(expired, st.Name)
+		st.running = false
+		st.elapsed = 0
+		return st.cooldown
+	})
+	return expired
+}
+
+// Status is the state of a timer at a point in time.
+type Status struct {
+	Name    string
+	Kind    Kind
+	Running bool
+
+	// Time a stopwatch has been running, or the time left on a countdown.
+	Time time.Duration
+
+	// Fraction of a countdown left, from 0 to 1 (0 for stopwatches).
+	Left float64
+}
+
+// Active returns the timers that are running or stopped part of the way
+// through, in the order they were added.
+func (s *Set) Active(now time.Time) []Status {
+	var active []Status
+	for _, st := range s.timers {
+		elapsed := st.elapsedAt(now)
+		if !st.running && elapsed == 0 {
+			continue
+		}
+		status := Status{Name: st.Name, Kind: st.Kind, Running: st.running, Time: elapsed}
+		if st.Kind == Countdown {
+			status.Time = max(0, st.Duration-elapsed)
+			if st.Duration > 0 {
+				status.Left = float64(status.Time) / float64(st.Duration)
+			}
+		}
+		active = append(active, status)
+	}
+	return active
+}
+
+// FormatTime formats the time of a timer: minutes and seconds for countdowns,
+// rounded up, and with tenths of a second for stopwatches. Hours are added
+// for long times.
+func FormatTime(kind Kind, d time.Duration) string {
+	d = max(0, d)
+	var tenths string
+	if kind == Countdown {
+		d = (d + time.Second - 1).Truncate(time.Second)
+	} else {
+		tenths = fmt.Sprintf(".%d", d/(time.Second/10)%10)
+	}
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d%s", seconds/3600, seconds/60%60, seconds%60, tenths)
+	}
+	return fmt.Sprintf("%d:%02d%s", seconds/60, seconds%60, tenths)
+}
+
+// Height of the row of each timer drawn by [Render].
+const RowHeight = 8
+
+// Width of the name column of the rows drawn by [Render], in characters.
+const nameColumns = 8
+
+// Render draws timers as rows at the bottom of an image the size of the LCD,
+// which is transparent above them: the name, a bar with the time left on
+// countdowns, and the time. Only as many timers as fit are drawn, and
+// stopped timers are drawn inverted.
+func Render(active []Status) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, device.LCDWidth, device.LCDHeight))
+	rows := min(len(active), device.LCDHeight/RowHeight)
+	top := device.LCDHeight - rows*RowHeight
+	face := lcdtext.Builtin
+	for idx, status := range active[:rows] {
+		row := image.Rect(0, top+idx*RowHeight, device.LCDWidth, top+(idx+1)*RowHeight)
+		fg, bg := image.Black, image.White
+		if !status.Running {
+			fg, bg = image.White, image.Black
+		}
+		draw.Draw(img, row, bg, image.Point{}, draw.Src)
+
+		name := []rune(status.Name)
+		if len(name) > nameColumns {
+			name = name[:nameColumns]
+		}
+		drawText(img, fg, row.Min.X+1, row.Min.Y, string(name))
+
+		text := FormatTime(status.Kind, status.Time)
+		textX := row.Max.X - len(text)*face.Advance
+		drawText(img, fg, textX, row.Min.Y, text)
+
+		if status.Kind == Countdown {
+			bar := image.Rect(row.Min.X+1+nameColumns*face.Advance+2, row.Min.Y+1, textX-3, row.Max.Y-1)
+			drawBar(img, fg, bg, bar, status.Left)
+		}
+	}
+	return img
+}
+
+// drawText draws a line of text with the built-in font with its top left
+// corner at x, y.
+func drawText(img draw.Image, src image.Image, x, y int, text string) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  src,
+		Face: lcdtext.Builtin,
+		Dot:  fixed.P(x, y+lcdtext.Builtin.Ascent),
+	}
+	drawer.DrawString(text)
+}
+
+// drawBar draws an outlined horizontal bar, filled from the left by a value
+// from 0 to 1.
+func drawBar(img draw.Image, fg, bg *image.Uniform, area image.Rectangle, value float64) {
+	if area.Dx() < 3 || area.Dy() < 3 {
+		return
+	}
+	draw.Draw(img, area, fg, image.Point{}, draw.Src)
+	inner := area.Inset(1)
+	draw.Draw(img, inner, bg, image.Point{}, draw.Src)
+	filled := inner
+	filled.Max.X = inner.Min.X + int(min(1, max(0, value))*float64(inner.Dx())+0.5)
+	draw.Draw(img, filled, fg, image.Point{}, draw.Src)
+}
@@ -0,0 +1,141 @@
+package timers_test
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/achilleas-k/gg13/internal/device"
+	"github.com/achilleas-k/gg13/internal/timers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopwatch(t *testing.T) {
+	assert := assert.New(t)
+	set := timers.NewSet([]timers.Timer{{Name: "run", Kind: timers.Stopwatch}})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// stopped at zero isn't active
+	assert.Empty(set.Active(start))
+
+	assert.True(set.Start("run", start))
+	assert.Equal([]timers.Status{{Name: "run", Kind: timers.Stopwatch, Running: true, Time: 5 * time.Second}}, set.Active(start.Add(5*time.Second)))
+
+	// stopping pauses the time, and starting again resumes it
+	assert.True(set.Toggle("run", start.Add(5*time.Second)))
+	assert.Equal([]timers.Status{{Name: "run", Kind: timers.Stopwatch, Time: 5 * time.Second}}, set.Active(start.Add(time.Minute)))
+	assert.True(set.Toggle("run", start.Add(time.Minute)))
+	assert.Equal(7*time.Second, set.Active(start.Add(time.Minute + 2*time.Second))[0].Time)
+
+	// stopwatches don't expire
+	assert.Empty(set.Expire(start.Add(time.Hour)))
+
+	assert.True(set.Reset("run"))
+	assert.Empty(set.Active(start.Add(time.Hour)))
+
+	assert.False(set.Start("missing", start))
+	assert.False(set.Stop("missing", start))
+	assert.False(set.Toggle("missing", start))
+	assert.False(set.Reset("missing"))
+}
+
+func TestCountdown(t *testing.T) {
+	assert := assert.New(t)
+	set := timers.NewSet([]timers.Timer{{Name: "boss", Kind: timers.Countdown, Duration: 10 * time.Second}})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(set.Start("boss", start))
+	// starting again doesn't restart it
+	assert.True(set.Start("boss", start.Add(time.Second)))
+	assert.Equal([]timers.Status{{Name: "boss", Kind: timers.Countdown, Running: true, Time: 6 * time.Second, Left: 0.6}}, set.Active(start.Add(4*time.Second)))
+
+	assert.Empty(set.Expire(start.Add(9 * time.Second)))
+	assert.Equal([]string{"boss"}, set.Expire(start.Add(10*time.Second)))
+	// an expired countdown is reset, but kept to be started again
+	assert.Empty(set.Active(start.Add(10 * time.Second)))
+	assert.Empty(set.Expire(start.Add(time.Minute)))
+	assert.True(set.Start("boss", start.Add(time.Minute)))
+	assert.Equal(10*time.Second, set.Active(start.Add(time.Minute))[0].Time)
+}
+
+func TestCooldown(t *testing.T) {
+	assert := assert.New(t)
+	set := timers.NewSet([]timers.Timer{{Name: "run", Kind: timers.Stopwatch}})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	set.Start("run", start)
+	set.StartCooldown("G5", 30*time.Second, start)
+	set.StartCooldown("G6", time.Minute, start.Add(10*time.Second))
+	// pressing again restarts a cooldown
+	set.StartCooldown("G5", 30*time.Second, start.Add(10*time.Second))
+
+	active := set.Active(start.Add(20 * time.Second))
+	assert.Len(active, 3)
+	assert.Equal("run", active[0].Name)
+	assert.Equal(timers.Status{Name: "G5", Kind: timers.Countdown, Running: true, Time: 20 * time.Second, Left: 2.0 / 3}, active[1])
+	assert.Equal("G6", active[2].Name)
+
+	// cooldowns are removed when they expire
+	assert.Equal([]string{"G5"}, set.Expire(start.Add(40*time.Second)))
+	active = set.Active(start.Add(40 * time.Second))
+	assert.Len(active, 2)
+	assert.False(set.Start("G5", start))
+}
+
+func TestParseKind(t *testing.T) {
+	assert := assert.New(t)
+	for _, name := range []string{"stopwatch", "countdown"} {
+		kind, err := timers.ParseKind(name)
+		assert.NoError(err)
+		assert.Equal(name, kind.String())
+	}
+	_, err := timers.ParseKind("hourglass")
+	assert.EqualError(err, "unknown timer type: \"hourglass\"")
+}
+
+func TestFormatTime(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("0:00.0", timers.FormatTime(timers.Stopwatch, 0))
+	assert.Equal("1:05.4", timers.FormatTime(timers.Stopwatch, 65*time.Second+480*time.Millisecond))
+	assert.Equal("1:00:00.0", timers.FormatTime(timers.Stopwatch, time.Hour))
+	// countdowns round up, so they show zero only when they expire
+	assert.Equal("0:01", timers.FormatTime(timers.Countdown, time.Millisecond))
+	assert.Equal("0:30", timers.FormatTime(timers.Countdown, 30*time.Second))
+	assert.Equal("0:00", timers.FormatTime(timers.Countdown, -time.Second))
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	transparent := func(img *image.RGBA, y int) bool {
+		for x := range device.LCDWidth {
+			if img.RGBAAt(x, y).A != 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	img := timers.Render(nil)
+	assert.Equal(image.Rect(0, 0, device.LCDWidth, device.LCDHeight), img.Bounds())
+	assert.True(transparent(img, device.LCDHeight-1))
+
+	// rows are drawn from the bottom
+	img = timers.Render([]timers.Status{
+		{Name: "boss", Kind: timers.Countdown, Running: true, Time: time.Second, Left: 0.5},
+		{Name: "run", Kind: timers.Stopwatch, Time: time.Second},
+	})
+	top := device.LCDHeight - 2*timers.RowHeight
+	assert.True(transparent(img, top-1))
+	assert.False(transparent(img, top))
+	// running timers are on white and stopped ones on black
+	assert.Equal(uint8(0xff), img.RGBAAt(0, top).R)
+	assert.Equal(uint8(0), img.RGBAAt(0, device.LCDHeight-1).R)
+
+	// only as many rows as fit are drawn
+	many := make([]timers.Status, 10)
+	img = timers.Render(many)
+	top = device.LCDHeight - device.LCDHeight/timers.RowHeight*timers.RowHeight
+	assert.True(transparent(img, top-1))
+	assert.False(transparent(img, top))
+}
@@ -0,0 +1,256 @@
+// Package uinputdev provides a minimal interface to the Linux uinput module for
+// creating virtual input devices with capabilities that aren't covered by
+// [github.com/bendahl/uinput], such as high-resolution scroll wheels.
+package uinputdev
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Default path of the uinput device node.
+const DefaultPath = "/dev/uinput"
+
+// ioctl requests and constants from linux/uinput.h
+const (
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiSetRelBit  = 0x40045566
+	uiSetAbsBit  = 0x40045567
+
+	maxNameSize = 80
+	absSize     = 64
+
+	BusUSB     = 0x03
+	BusVirtual = 0x06
+)
+
+// Event types and codes from linux/input-event-codes.h
+const (
+	EvSyn = 0x00
+	EvKey = 0x01
+	EvRel = 0x02
+	EvAbs = 0x03
+
+	SynReport = 0x00
+
+	RelX                = 0x00
+	RelY                = 0x01
+	RelHWheel           = 0x06
+	RelWheel            = 0x08
+	RelWheelHiRes       = 0x0b
+	RelHWheelHiRes      = 0x0c
+	WheelHiResPerDetent = 120
+
+	AbsX     = 0x00
+	AbsY     = 0x01
+	AbsZ     = 0x02
+	AbsRX    = 0x03
+	AbsRY    = 0x04
+	AbsRZ    = 0x05
+	AbsHat0X = 0x10
+	AbsHat0Y = 0x11
+
+	BtnLeft   = 0x110
+	BtnRight  = 0x111
+	BtnMiddle = 0x112
+)
+
+// AbsInfo describes the range of an absolute axis.
+type AbsInfo struct {
+	Min  int32
+	Max  int32
+	Fuzz int32
+	Flat int32
+}
+
+// Spec describes the identity and capabilities of a virtual device.
+type Spec struct {
+	Name    string
+	Bus     uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+
+	Keys []uint16
+	Rels []uint16
+	Abs  map[uint16]AbsInfo
+}
+
+// Device is a virtual input device created through uinput.
+type Device struct {
+	file *os.File
+}
+
+type inputID struct {
+	Bustype uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+// uinput_user_dev from linux/uinput.h
+type userDev struct {
+	Name       [maxNameSize]byte
+	ID         inputID
+	EffectsMax uint32
+	Absmax     [absSize]int32
+	Absmin     [absSize]int32
+	Absfuzz    [absSize]int32
+	Absflat    [absSize]int32
+}
+
+// input_event from linux/input.h
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Create registers a new virtual device with the given [Spec] using the uinput
+// device node at path.
+func Create(path string, spec Spec) (*Device, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("device name may not be empty")
+	}
+	if len(spec.Name) > maxNameSize {
+		return nil, fmt.Errorf("device name %q is too long (maximum of %d characters allowed)", spec.Name, maxNameSize)
+	}
+
+	file, err := os.OpenFile(path, syscall.O_WRONLY|syscall.O_NONBLOCK, 0o660)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uinput device %q: %w", path, err)
+	}
+
+	if err := register(file, spec); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to register capabilities for %q: %w", spec.Name, err)
+	}
+
+	dev := userDev{
+		ID: inputID{
+			Bustype: spec.Bus,
+			Vendor:  spec.Vendor,
+			Product: spec.Product,
+			Version: spec.Version,
+		},
+	}
+	copy(dev.Name[:], spec.Name)
+	for code, info := range spec.Abs {
+		dev.Absmin[code] = info.Min
+		dev.Absmax[code] = info.Max
+		dev.Absfuzz[code] = info.Fuzz
+		dev.Absflat[code] = info.Flat
+	}
+
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, dev); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to encode device description: %w", err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write device description: %w", err)
+	}
+	if err := ioctl(file, uiDevCreate, 0); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to create device %q: %w", spec.Name, err)
+	}
+
+	// give udev and any listeners some time to pick up the new device before
+	// events start arriving
+	time.Sleep(200 * time.Millisecond)
+
+	return &Device{file: file}, nil
+}
+
+func register(file *os.File, spec Spec) error {
+	if len(spec.Keys) > 0 {
+		if err := ioctl(file, uiSetEvBit, EvKey); err != nil {
+			return err
+		}
+		for _, code := range spec.Keys {
+			if err := ioctl(file, uiSetKeyBit, uintptr(code)); err != nil {
+				return fmt.Errorf("key %#x: %w", code, err)
+			}
+		}
+	}
+
+	if len(spec.Rels) > 0 {
+		if err := ioctl(file, uiSetEvBit, EvRel); err != nil {
+			return err
+		}
+		for _, code := range spec.Rels {
+			if err := ioctl(file, uiSetRelBit, uintptr(code)); err != nil {
+				return fmt.Errorf("relative axis %#x: %w", code, err)
+			}
+		}
+	}
+
+	if len(spec.Abs) > 0 {
+		if err := ioctl(file, uiSetEvBit, EvAbs); err != nil {
+			return err
+		}
+		for code := range spec.Abs {
+			if code >= absSize {
+				return fmt.Errorf("absolute axis %#x out of range", code)
+			}
+			if err := ioctl(file, uiSetAbsBit, uintptr(code)); err != nil {
+				return fmt.Errorf("absolute axis %#x: %w", code, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Emit writes a single event to the device. Events are not delivered to
+// listeners until [Device.Sync] is called.
+func (d *Device) Emit(evType, code uint16, value int32) error {
+	if d == nil || d.file == nil {
+		return fmt.Errorf("event emitted on closed device")
+	}
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, inputEvent{Type: evType, Code: code, Value: value}); err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if _, err := d.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// Sync writes a synchronisation report, delivering all previously emitted
+// events as one atomic update.
+func (d *Device) Sync() error {
+	return d.Emit(EvSyn, SynReport, 0)
+}
+
+// Close destroys the virtual device.
+func (d *Device) Close() error {
+	if d == nil || d.file == nil {
+		return nil
+	}
+	defer func() {
+		d.file = nil
+	}()
+	if err := ioctl(d.file, uiDevDestroy, 0); err != nil {
+		_ = d.file.Close()
+		return fmt.Errorf("failed to destroy device: %w", err)
+	}
+	return d.file.Close()
+}
+
+func ioctl(file *os.File, cmd, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), cmd, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
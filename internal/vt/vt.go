@@ -0,0 +1,243 @@
+// Package vt keeps the text of a small terminal screen written to by a
+// program, with a subset of the VT100 control sequences: newlines, carriage
+// returns, backspaces, tabs, clearing the screen and lines, and moving the
+// cursor. Other escape sequences, like colours, are dropped.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Width of tab stops, in columns.
+const tabWidth = 8
+
+// parser states
+const (
+	stateText = iota
+	stateEscape
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// Screen is a grid of characters that output is written to. Lines are
+// wrapped at the last column, and the screen scrolls up when a line is added
+// below the last row. Newlines also return the cursor to the first column,
+// as a terminal does for the output of programs.
+type Screen struct {
+	cols, rows int
+	cells      [][]rune
+
+	// position of the cursor; x is cols after writing to the last column,
+	// wrapping only when the next character is written
+	x, y int
+
+	state  int
+	params []byte
+	// bytes of a character split between writes
+	partial []byte
+}
+
+// New returns an empty screen of the given size.
+func New(cols, rows int) *Screen {
+	s := &Screen{cols: max(cols, 1), rows: max(rows, 1)}
+	s.Clear()
+	return s
+}
+
+// Clear empties the screen and moves the cursor to the top left.
+func (s *Screen) Clear() {
+	s.cells = make([][]rune, s.rows)
+	for y := range s.cells {
+		s.cells[y] = s.blankLine()
+	}
+	s.x, s.y = 0, 0
+}
+
+func (s *Screen) blankLine() []rune {
+	return []rune(strings.Repeat(" ", s.cols))
+}
+
+// Lines returns the text of each row of the screen, without trailing spaces.
+func (s *Screen) Lines() []string {
+	lines := make([]string, s.rows)
+	for y, row := range s.cells {
+		lines[y] = strings.TrimRight(string(row), " ")
+	}
+	return lines
+}
+
+// Write adds output to the screen. It never fails.
+func (s *Screen) Write(p []byte) (int, error) {
+	data := p
+	if len(s.partial) > 0 {
+		data = append(s.partial, p...)
+		s.partial = nil
+	}
+	for len(data) > 0 {
+		if s.state == stateText && data[0] >= utf8.RuneSelf {
+			if !utf8.FullRune(data) {
+				s.partial = append([]byte(nil), data...)
+				break
+			}
+			r, size := utf8.DecodeRune(data)
+			s.put(r)
+			data = data[size:]
+			continue
+		}
+		s.handleByte(data[0])
+		data = data[1:]
+	}
+	return len(p), nil
+}
+
+func (s *Screen) handleByte(b byte) {
+	switch s.state {
+	case stateEscape:
+		s.state = stateText
+		switch b {
+		case '[':
+			s.state = stateCSI
+			s.params = s.params[:0]
+		case ']':
+			s.state = stateOSC
+		case 'c':
+			s.Clear()
+		}
+	case stateCSI:
+		if b >= 0x40 && b <= 0x7e {
+			s.state = stateText
+			s.csi(b)
+			return
+		}
+		s.params = append(s.params, b)
+	case stateOSC:
+		// operating system commands, like window titles, end with a bell or
+		// a string terminator
+		switch b {
+		case '\a':
+			s.state = stateText
+		case 0x1b:
+			s.state = stateOSCEscape
+		}
+	case stateOSCEscape:
+		s.state = stateText
+		if b != '\\' {
+			s.state = stateOSC
+		}
+	default:
+		switch b {
+		case 0x1b:
+			s.state = stateEscape
+		case '\n':
+			s.x = 0
+			s.lineFeed()
+		case '\r':
+			s.x = 0
+		case '\b':
+			s.x = max(0, min(s.x, s.cols-1)-1)
+		case '\t':
+			s.x = min(s.cols-1, (s.x/tabWidth+1)*tabWidth)
+		case '\f':
+			s.Clear()
+		default:
+			if b >= ' ' && b != 0x7f {
+				s.put(rune(b))
+			}
+		}
+	}
+}
+
+// put writes a character at the cursor and moves the cursor right.
+func (s *Screen) put(r rune) {
+	if s.x >= s.cols {
+		s.x = 0
+		s.lineFeed()
+	}
+	s.cells[s.y][s.x] = r
+	s.x++
+}
+
+// lineFeed moves the cursor down a row, scrolling the screen up at the last
+// row.
+func (s *Screen) lineFeed() {
+	if s.y < s.rows-1 {
+		s.y++
+		return
+	}
+	copy(s.cells, s.cells[1:])
+	s.cells[s.rows-1] = s.blankLine()
+}
+
+// csiParams returns the numeric parameters of a control sequence. Missing
+// parameters are 0.
+func (s *Screen) csiParams() []int {
+	var params []int
+	for _, field := range strings.Split(string(s.params), ";") {
+		n, _ := strconv.Atoi(field)
+		params = append(params, n)
+	}
+	return params
+}
+
+// csi runs a control sequence with the given final byte.
+func (s *Screen) csi(final byte) {
+	params := s.csiParams()
+	param := func(idx, def int) int {
+		if idx < len(params) && params[idx] > 0 {
+			return params[idx]
+		}
+		return def
+	}
+	x := min(s.x, s.cols-1)
+
+	switch final {
+	case 'H', 'f':
+		s.y = min(param(0, 1), s.rows) - 1
+		s.x = min(param(1, 1), s.cols) - 1
+	case 'A':
+		s.y = max(0, s.y-param(0, 1))
+	case 'B':
+		s.y = min(s.rows-1, s.y+param(0, 1))
+	case 'C':
+		s.x = min(s.cols-1, x+param(0, 1))
+	case 'D':
+		s.x = max(0, x-param(0, 1))
+	case 'J':
+		switch params[0] {
+		case 0:
+			s.clearLine(s.y, x, s.cols)
+			for y := s.y + 1; y < s.rows; y++ {
+				s.cells[y] = s.blankLine()
+			}
+		case 1:
+			for y := 0; y < s.y; y++ {
+				s.cells[y] = s.blankLine()
+			}
+			s.clearLine(s.y, 0, x+1)
+		default:
+			// the cursor doesn't move, the same as a terminal
+			x, y := s.x, s.y
+			s.Clear()
+			s.x, s.y = x, y
+		}
+	case 'K':
+		switch params[0] {
+		case 0:
+			s.clearLine(s.y, x, s.cols)
+		case 1:
+			s.clearLine(s.y, 0, x+1)
+		default:
+			s.clearLine(s.y, 0, s.cols)
+		}
+	}
+}
+
+// clearLine blanks the columns from start up to end of a row.
+func (s *Screen) clearLine(y, start, end int) {
+	for x := start; x < end; x++ {
+		s.cells[y][x] = ' '
+	}
+}
@@ -0,0 +1,113 @@
+package vt_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/achilleas-k/gg13/internal/vt"
+	"github.com/stretchr/testify/assert"
+)
+
+func write(s *vt.Screen, text string) {
+	_, _ = s.Write([]byte(text))
+}
+
+func TestNewlines(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(10, 3)
+	assert.Equal([]string{"", "", ""}, s.Lines())
+
+	write(s, "one\ntwo\n")
+	assert.Equal([]string{"one", "two", ""}, s.Lines())
+
+	// the screen scrolls when a line is added below the last row
+	write(s, "three\nfour")
+	assert.Equal([]string{"two", "three", "four"}, s.Lines())
+}
+
+func TestWrap(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(4, 3)
+
+	// the cursor stays at the end of a full line until more is written
+	write(s, "abcd")
+	assert.Equal([]string{"abcd", "", ""}, s.Lines())
+	write(s, "\n")
+	assert.Equal([]string{"abcd", "", ""}, s.Lines())
+	write(s, "abcdefghij")
+	assert.Equal([]string{"abcd", "efgh", "ij"}, s.Lines())
+}
+
+func TestCarriageReturn(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(10, 2)
+
+	// progress output overwrites the same line
+	for pct := 0; pct <= 100; pct += 50 {
+		write(s, fmt.Sprintf("\r%3d%%", pct))
+	}
+	assert.Equal([]string{"100%", ""}, s.Lines())
+
+	write(s, "\rab\bc\tx")
+	assert.Equal([]string{"ac0%    x", ""}, s.Lines())
+}
+
+func TestClear(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(10, 3)
+
+	// what clear(1) writes
+	write(s, "one\ntwo\nthree")
+	write(s, "\x1b[H\x1b[2J\x1b[3J")
+	assert.Equal([]string{"", "", ""}, s.Lines())
+	write(s, "top")
+	assert.Equal([]string{"top", "", ""}, s.Lines())
+
+	write(s, "\fnew")
+	assert.Equal([]string{"new", "", ""}, s.Lines())
+	write(s, "\x1bcreset")
+	assert.Equal([]string{"reset", "", ""}, s.Lines())
+}
+
+func TestCursor(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(10, 3)
+
+	write(s, "aaaaa\nbbbbb\nccccc")
+	write(s, "\x1b[2;3HX")
+	assert.Equal([]string{"aaaaa", "bbXbb", "ccccc"}, s.Lines())
+
+	// clear the rest of the line, then the rest of the screen
+	write(s, "\x1b[K")
+	assert.Equal([]string{"aaaaa", "bbX", "ccccc"}, s.Lines())
+	write(s, "\x1b[1;2H\x1b[J")
+	assert.Equal([]string{"a", "", ""}, s.Lines())
+
+	write(s, "\x1b[3;1Hxyz\x1b[2DQ\x1b[AW")
+	assert.Equal([]string{"a", "  W", "xQz"}, s.Lines())
+}
+
+func TestDroppedSequences(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(20, 2)
+
+	// colours and window titles
+	write(s, "\x1b[1;31mred\x1b[0m \x1b]0;title\aok \x1b]2;title\x1b\\done")
+	assert.Equal([]string{"red ok done", ""}, s.Lines())
+}
+
+func TestUTF8(t *testing.T) {
+	assert := assert.New(t)
+	s := vt.New(5, 1)
+
+	// a character split between writes
+	check := []byte("✓ok")
+	_, _ = s.Write(check[:1])
+	_, _ = s.Write(check[1:])
+	assert.Equal([]string{"✓ok"}, s.Lines())
+
+	// escape sequences split between writes
+	write(s, "\x1b[")
+	write(s, "2J")
+	assert.Equal([]string{""}, s.Lines())
+}